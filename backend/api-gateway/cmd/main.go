@@ -7,12 +7,18 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/fathima-sithara/api-gateway/internal/admin"
 	"github.com/fathima-sithara/api-gateway/internal/config"
+	"github.com/fathima-sithara/api-gateway/internal/graphql"
 	"github.com/fathima-sithara/api-gateway/internal/middleware"
+	"github.com/fathima-sithara/api-gateway/internal/openapi"
 	"github.com/fathima-sithara/api-gateway/internal/proxy"
 	"github.com/fathima-sithara/api-gateway/internal/router"
+	"github.com/fathima-sithara/platform/observability"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
@@ -27,14 +33,63 @@ func main() {
 		logger.Fatal("failed to load config", zap.Error(err))
 	}
 
+	// tracing
+	shutdownTracing, err := observability.InitTracing(context.Background(), "api-gateway")
+	if err != nil {
+		logger.Fatal("failed to init tracing", zap.Error(err))
+	}
+
 	// load JWT middleware
-	jwtMw, err := middleware.NewJWTMiddleware(cfg.JWTPublicKeyPath, logger)
+	signer := middleware.NewIdentitySigner(cfg.IdentitySecret)
+	jwtMw, err := middleware.NewJWTMiddleware(cfg.JWTPublicKeyPath, signer, cfg.Security.AccessCookie, logger)
 	if err != nil {
 		logger.Fatal("failed to init jwt middleware", zap.Error(err))
 	}
 
+	// redis (optional; shared state across gateway replicas)
+	var rdb *redis.Client
+	if cfg.Redis.Addr != "" {
+		rdb = redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		observability.InstrumentRedis(rdb)
+		pingCtx, pingCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := rdb.Ping(pingCtx).Err(); err != nil {
+			logger.Warn("redis ping failed", zap.Error(err))
+		}
+		pingCancel()
+	}
+
 	// rate limiter
-	rl := middleware.NewIPRateLimiter(cfg.RateLimitPerMin, logger)
+	rl := middleware.NewRateLimiter(cfg, rdb, logger)
+
+	// response cache (opt-in per route via CACHE_POLICIES_JSON)
+	cache := middleware.NewResponseCache(cfg, rdb, logger)
+
+	// purges for cached responses that services change from their event consumers
+	var nc *nats.Conn
+	if cfg.NATSURL != "" {
+		nc, err = nats.Connect(cfg.NATSURL, nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1))
+		if err != nil {
+			logger.Fatal("nats connect failed", zap.Error(err))
+		}
+		if _, err := cache.SubscribePurges(nc); err != nil {
+			logger.Fatal("cache purge subscription failed", zap.Error(err))
+		}
+	} else if len(cfg.CachePolicies) > 0 {
+		logger.Warn("NATS_URL not set; cached responses changed by service events stay stale until their ttl")
+	}
+
+	// stored responses for retried writes carrying an Idempotency-Key
+	idem := middleware.NewIdempotency(cfg, rdb, logger)
+
+	// request validation against the embedded OpenAPI specs
+	spec, err := openapi.Load()
+	if err != nil {
+		logger.Fatal("failed to load openapi specs", zap.Error(err))
+	}
 
 	// proxy (services map)
 	prox, err := proxy.NewProxyFromEnv(cfg)
@@ -42,13 +97,33 @@ func main() {
 		logger.Fatal("discovery init failed", zap.Error(err))
 	}
 
+	// active health checks; only ready instances receive traffic
+	hcCtx, stopHealthChecks := context.WithCancel(context.Background())
+	prox.StartHealthChecks(hcCtx, cfg.HealthCheck.Interval, cfg.HealthCheck.Timeout, cfg.HealthCheck.Path)
+
+	// GraphQL aggregation over the upstream services
+	gql, err := graphql.New(prox, logger)
+	if err != nil {
+		logger.Fatal("failed to build graphql schema", zap.Error(err))
+	}
+
 	// fiber app
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: true,
+		BodyLimit:             middleware.MaxBodyLimit(cfg.Limits),
+		ReadBufferSize:        cfg.Limits.MaxHeaderBytes,
+		ReadTimeout:           cfg.Limits.ReadTimeout,
+		WriteTimeout:          cfg.Limits.WriteTimeout,
+		IdleTimeout:           cfg.Limits.IdleTimeout,
+		ErrorHandler:          middleware.ErrorHandler,
 	})
 
+	// live per-route counters for the admin API
+	stats := admin.NewStats()
+	app.Use(stats.Middleware())
+
 	// register routes
-	router.RegisterRoutes(app, prox, jwtMw, rl, logger)
+	router.RegisterRoutes(app, prox, jwtMw, rl, cache, idem, gql, spec, cfg.Limits, cfg.Security, logger)
 
 	// start server
 	addr := ":" + cfg.Port
@@ -59,6 +134,19 @@ func main() {
 		}
 	}()
 
+	// admin listener (separate port, token protected)
+	var adminApp *fiber.App
+	if cfg.AdminPort != "" {
+		adminApp = admin.NewServer(cfg.AdminToken, app, prox, stats, logger)
+		adminAddr := ":" + cfg.AdminPort
+		go func() {
+			logger.Info("starting admin listener", zap.String("addr", adminAddr))
+			if err := adminApp.Listen(adminAddr); err != nil {
+				logger.Fatal("admin server failed", zap.Error(err))
+			}
+		}()
+	}
+
 	// graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -66,7 +154,20 @@ func main() {
 	logger.Info("shutdown requested")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	stopHealthChecks()
 	_ = app.Shutdown()
+	if adminApp != nil {
+		_ = adminApp.Shutdown()
+	}
 	_ = prox.Close(ctx)
+	if nc != nil {
+		_ = nc.Drain()
+	}
+	if rdb != nil {
+		_ = rdb.Close()
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Warn("tracing shutdown failed", zap.Error(err))
+	}
 	logger.Info("gateway stopped")
 }
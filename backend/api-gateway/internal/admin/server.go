@@ -0,0 +1,90 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"sort"
+	"strings"
+
+	"github.com/fathima-sithara/api-gateway/internal/proxy"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// route is one entry of the effective route table.
+type route struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// NewServer builds the admin app. It is meant for a separate, non-public port and
+// every endpoint requires "Authorization: Bearer <token>".
+func NewServer(token string, public *fiber.App, p *proxy.Proxy, stats *Stats, logger *zap.Logger) *fiber.App {
+	app := fiber.New(fiber.Config{
+		DisableStartupMessage: true,
+	})
+	app.Use(requireToken(token))
+
+	app.Get("/admin/routes", func(c *fiber.Ctx) error {
+		var routes []route
+		for _, r := range public.GetRoutes(true) {
+			// fiber registers every route under HEAD as well; list GET only
+			if r.Method == fiber.MethodHead {
+				continue
+			}
+			routes = append(routes, route{Method: r.Method, Path: r.Path})
+		}
+		sort.Slice(routes, func(i, j int) bool {
+			if routes[i].Path != routes[j].Path {
+				return routes[i].Path < routes[j].Path
+			}
+			return routes[i].Method < routes[j].Method
+		})
+		return c.JSON(fiber.Map{"routes": routes, "upstreams": p.Mounts()})
+	})
+
+	app.Get("/admin/upstreams", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"upstreams": p.Upstreams()})
+	})
+
+	app.Get("/admin/stats", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"in_flight": stats.InFlight(), "routes": stats.Snapshot()})
+	})
+
+	// runtime control: drain stops new requests and disables the upstream once
+	// in-flight requests finish, disable cuts it off at once, enable restores it
+	states := map[string]string{
+		"drain":   proxy.StateDraining,
+		"disable": proxy.StateDisabled,
+		"enable":  proxy.StateActive,
+	}
+	app.Post("/admin/upstreams/:name/:action", func(c *fiber.Ctx) error {
+		state, ok := states[c.Params("action")]
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "unknown action"})
+		}
+		name := c.Params("name")
+		if err := p.SetState(name, state); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		logger.Info("admin changed upstream state", zap.String("upstream", name), zap.String("action", c.Params("action")), zap.String("ip", c.IP()))
+		for _, u := range p.Upstreams() {
+			if u.Name == name {
+				return c.JSON(u)
+			}
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	return app
+}
+
+func requireToken(token string) fiber.Handler {
+	want := []byte(token)
+	return func(c *fiber.Ctx) error {
+		got, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), want) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,152 @@
+package admin
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sampleSize is how many recent latencies are kept per route for percentiles.
+const sampleSize = 1024
+
+// Stats keeps live per-route traffic counters for the admin API. Unlike the
+// Prometheus metrics it answers percentiles directly, over a window of the most
+// recent requests of each route.
+type Stats struct {
+	inflight atomic.Int64
+
+	mu     sync.Mutex
+	routes map[string]*routeStats
+}
+
+type routeStats struct {
+	method  string
+	route   string
+	count   uint64
+	errors  uint64
+	samples [sampleSize]time.Duration
+}
+
+// RouteStats is the admin view of one route.
+type RouteStats struct {
+	Method string  `json:"method"`
+	Route  string  `json:"route"`
+	Count  uint64  `json:"count"`
+	Errors uint64  `json:"errors"`
+	P50Ms  float64 `json:"p50_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+}
+
+func NewStats() *Stats {
+	return &Stats{routes: map[string]*routeStats{}}
+}
+
+// Middleware records every request against its route template. 5xx answers count
+// as errors.
+func (s *Stats) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		s.inflight.Add(1)
+		err := c.Next()
+		s.inflight.Add(-1)
+
+		route := c.Route().Path
+		if unmatched(err) {
+			route = "unmatched"
+		}
+		s.record(c.Method(), route, statusCode(c, err), time.Since(start))
+		return err
+	}
+}
+
+func (s *Stats) record(method, route string, status int, d time.Duration) {
+	key := method + " " + route
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.routes[key]
+	if !ok {
+		r = &routeStats{method: method, route: route}
+		s.routes[key] = r
+	}
+	r.samples[r.count%sampleSize] = d
+	r.count++
+	if status >= fiber.StatusInternalServerError {
+		r.errors++
+	}
+}
+
+// InFlight is the number of requests the gateway is serving right now.
+func (s *Stats) InFlight() int64 {
+	return s.inflight.Load()
+}
+
+// Snapshot returns the counters of every route seen so far, busiest first.
+func (s *Stats) Snapshot() []RouteStats {
+	s.mu.Lock()
+	out := make([]RouteStats, 0, len(s.routes))
+	for _, r := range s.routes {
+		n := r.count
+		if n > sampleSize {
+			n = sampleSize
+		}
+		window := append([]time.Duration(nil), r.samples[:n]...)
+		sort.Slice(window, func(i, j int) bool { return window[i] < window[j] })
+		out = append(out, RouteStats{
+			Method: r.method,
+			Route:  r.route,
+			Count:  r.count,
+			Errors: r.errors,
+			P50Ms:  percentile(window, 0.50),
+			P99Ms:  percentile(window, 0.99),
+		})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Method+out[i].Route < out[j].Method+out[j].Route
+	})
+	return out
+}
+
+// percentile reads the q-th percentile, in milliseconds, from sorted samples.
+func percentile(sorted []time.Duration, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(q*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return float64(sorted[i].Microseconds()) / 1000
+}
+
+// unmatched reports whether err is fiber's own 404 for a path no handler matched.
+// c.Route() then names the last middleware, and for those fiber reports the
+// request method rather than "USE".
+func unmatched(err error) bool {
+	var fe *fiber.Error
+	return errors.As(err, &fe) && fe.Code == fiber.StatusNotFound && strings.HasPrefix(fe.Message, "Cannot ")
+}
+
+// statusCode predicts the status the error handler will write for err.
+func statusCode(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		return fe.Code
+	}
+	return fiber.StatusInternalServerError
+}
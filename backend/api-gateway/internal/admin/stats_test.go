@@ -0,0 +1,80 @@
+package admin
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestStatsMiddleware(t *testing.T) {
+	s := NewStats()
+	app := fiber.New()
+	app.Use(s.Middleware())
+	app.Get("/api/v1/chat/*", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	app.Get("/boom", func(c *fiber.Ctx) error { return errors.New("boom") })
+	app.Get("/gone", func(c *fiber.Ctx) error { return fiber.ErrNotFound })
+
+	for _, path := range []string{"/api/v1/chat/chats", "/api/v1/chat/chats/c1", "/boom", "/gone", "/nope", "/other/nope"} {
+		if _, err := app.Test(httptest.NewRequest("GET", path, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := map[string]RouteStats{}
+	for _, r := range s.Snapshot() {
+		got[r.Method+" "+r.Route] = r
+	}
+	tests := []struct {
+		key            string
+		count, errored uint64
+	}{
+		{"GET /api/v1/chat/*", 2, 0},
+		{"GET /boom", 1, 1},
+		{"GET /gone", 1, 0},
+		{"GET unmatched", 2, 0},
+	}
+	for _, tt := range tests {
+		r, ok := got[tt.key]
+		if !ok || r.Count != tt.count || r.Errors != tt.errored {
+			t.Errorf("%s = %+v (present %v), want count %d errors %d", tt.key, r, ok, tt.count, tt.errored)
+		}
+	}
+	if len(got) != len(tests) {
+		t.Errorf("routes = %v, want only %d series", got, len(tests))
+	}
+	if s.InFlight() != 0 {
+		t.Errorf("InFlight = %d after all requests finished", s.InFlight())
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	ms := func(n ...int) []time.Duration {
+		out := make([]time.Duration, len(n))
+		for i, v := range n {
+			out[i] = time.Duration(v) * time.Millisecond
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		sorted []time.Duration
+		q      float64
+		want   float64
+	}{
+		{"empty", nil, 0.5, 0},
+		{"one sample", ms(7), 0.99, 7},
+		{"median of four", ms(1, 2, 3, 4), 0.5, 2},
+		{"p99 of a hundred", ms(make([]int, 99)...), 0.99, 0},
+		{"p99 picks the tail", append(ms(make([]int, 98)...), ms(50, 90)...), 0.99, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.sorted, tt.q); got != tt.want {
+				t.Fatalf("percentile = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,8 +3,11 @@ package config
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type CircuitBreakerConfig struct {
@@ -13,12 +16,100 @@ type CircuitBreakerConfig struct {
 	TimeoutSec  int
 }
 
+// HealthCheckConfig controls active probing of upstream instances.
+type HealthCheckConfig struct {
+	Interval time.Duration
+	Timeout  time.Duration
+	Path     string
+}
+
+// LimitsConfig protects the gateway from large, slow or excessive requests.
+type LimitsConfig struct {
+	// MaxBodyBytes applies to routes without an entry in BodyLimits
+	MaxBodyBytes int
+	// per-route body limits keyed by path prefix, parsed from BODY_LIMITS_JSON
+	BodyLimits     map[string]int
+	MaxHeaderBytes int
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+	IdleTimeout    time.Duration
+	// UpstreamTimeout bounds a single proxied call
+	UpstreamTimeout time.Duration
+	// MaxConcurrent requests are served at once; up to MaxQueued more wait at most
+	// QueueTimeout for a slot. Zero MaxConcurrent disables the limiter.
+	MaxConcurrent int
+	MaxQueued     int
+	QueueTimeout  time.Duration
+}
+
+// SecurityConfig is the browser-facing policy: CORS, response hardening headers and
+// CSRF protection for cookie authenticated requests.
+type SecurityConfig struct {
+	AllowedOrigins   []string
+	AllowCredentials bool
+	PreflightMaxAge  time.Duration
+	// HSTSMaxAge of zero omits Strict-Transport-Security
+	HSTSMaxAge   time.Duration
+	FrameOptions string
+	// CookieSecure marks gateway issued cookies Secure; off only for plain http dev setups
+	CookieSecure bool
+	CSRFCookie   string
+	CSRFHeader   string
+	// AccessCookie carries the access token for cookie sessions
+	AccessCookie string
+	// AuthCookies are the cookies that authenticate a request; unsafe requests
+	// carrying any of them without an Authorization header need a CSRF token
+	AuthCookies []string
+}
+
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+}
+
+// RatePolicy limits a client to Limit requests per Period, allowing bursts of up to Burst.
+type RatePolicy struct {
+	Limit  int           `json:"limit"`
+	Period time.Duration `json:"-"`
+	Burst  int           `json:"burst"`
+	// PeriodStr is the JSON form of Period, e.g. "1m" or "10s".
+	PeriodStr string `json:"period"`
+}
+
+// CachePolicy opts a route into response caching for up to TTL. A shorter upstream
+// Cache-Control max-age takes precedence.
+type CachePolicy struct {
+	TTL time.Duration `json:"-"`
+	// TTLStr is the JSON form of TTL, e.g. "30s".
+	TTLStr string `json:"ttl"`
+}
+
 type Config struct {
 	Port             string
 	JWTPublicKeyPath string
-	RateLimitPerMin  int
-	CircuitBreaker   CircuitBreakerConfig
-	// services mapping JSON string -> parsed to map[string]string
+	// admin listener; disabled when AdminPort is empty
+	AdminPort  string
+	AdminToken string
+	// shared HMAC secret used to sign identity headers for downstream services
+	IdentitySecret  string
+	RateLimitPerMin int
+	RateLimitBurst  int
+	// per-route policies keyed by path prefix, parsed from RATE_LIMIT_POLICIES_JSON
+	RatePolicies map[string]RatePolicy
+	// cacheable GET routes keyed by path prefix, parsed from CACHE_POLICIES_JSON
+	CachePolicies map[string]CachePolicy
+	// responses to requests carrying an Idempotency-Key are replayed for this long
+	IdempotencyTTL time.Duration
+	CircuitBreaker CircuitBreakerConfig
+	HealthCheck    HealthCheckConfig
+	Limits         LimitsConfig
+	Security       SecurityConfig
+	Redis          RedisConfig
+	// event bus carrying cache purges from service event consumers; optional
+	NATSURL string
+	// services mapping JSON string -> parsed to map[string]string; a value may list
+	// several comma separated instance URLs
 	ServicesJSON string
 	Services     map[string]string
 	ConsulAddr   string // optional (not implemented)
@@ -33,12 +124,39 @@ func LoadFromEnv() (*Config, error) {
 	if jwtPath == "" {
 		return nil, errors.New("JWT_PUBLIC_KEY_PATH is required")
 	}
+	identitySecret := os.Getenv("IDENTITY_HMAC_SECRET")
+	if identitySecret == "" {
+		return nil, errors.New("IDENTITY_HMAC_SECRET is required")
+	}
+	adminPort := os.Getenv("ADMIN_PORT")
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	if adminPort != "" && adminToken == "" {
+		return nil, errors.New("ADMIN_TOKEN is required when ADMIN_PORT is set")
+	}
 	rl := 60
 	if s := os.Getenv("RATE_LIMIT_PER_MIN"); s != "" {
 		if v, err := strconv.Atoi(s); err == nil {
 			rl = v
 		}
 	}
+	if rl <= 0 {
+		return nil, errors.New("RATE_LIMIT_PER_MIN must be positive")
+	}
+	burst := 5
+	if s := os.Getenv("RATE_LIMIT_BURST"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil {
+			burst = v
+		}
+	}
+	if burst < 0 {
+		return nil, errors.New("RATE_LIMIT_BURST cannot be negative")
+	}
+	redisDB := 0
+	if s := os.Getenv("REDIS_DB"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil {
+			redisDB = v
+		}
+	}
 	maxFail := uint32(5)
 	if s := os.Getenv("CB_MAX_FAILURES"); s != "" {
 		if v, err := strconv.Atoi(s); err == nil {
@@ -57,16 +175,116 @@ func LoadFromEnv() (*Config, error) {
 			timeout = v
 		}
 	}
+	hcInterval := 10 * time.Second
+	if s := os.Getenv("HEALTH_CHECK_INTERVAL"); s != "" {
+		if v, err := time.ParseDuration(s); err == nil && v > 0 {
+			hcInterval = v
+		}
+	}
+	hcTimeout := 2 * time.Second
+	if s := os.Getenv("HEALTH_CHECK_TIMEOUT"); s != "" {
+		if v, err := time.ParseDuration(s); err == nil && v > 0 {
+			hcTimeout = v
+		}
+	}
+	hcPath := os.Getenv("HEALTH_CHECK_PATH")
+	if hcPath == "" {
+		hcPath = "/readyz"
+	}
+	limits := LimitsConfig{
+		MaxBodyBytes:    envInt("MAX_BODY_BYTES", 4<<20),
+		MaxHeaderBytes:  envInt("MAX_HEADER_BYTES", 8<<10),
+		ReadTimeout:     envDuration("READ_TIMEOUT", 10*time.Second),
+		WriteTimeout:    envDuration("WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:     envDuration("IDLE_TIMEOUT", 60*time.Second),
+		UpstreamTimeout: envDuration("UPSTREAM_TIMEOUT", 30*time.Second),
+		MaxConcurrent:   envInt("MAX_CONCURRENT_REQUESTS", 1000),
+		MaxQueued:       envInt("MAX_QUEUED_REQUESTS", 200),
+		QueueTimeout:    envDuration("QUEUE_TIMEOUT", 2*time.Second),
+		BodyLimits:      map[string]int{},
+	}
+	if s := os.Getenv("BODY_LIMITS_JSON"); s != "" {
+		if err := json.Unmarshal([]byte(s), &limits.BodyLimits); err != nil {
+			return nil, err
+		}
+		for prefix, n := range limits.BodyLimits {
+			if n <= 0 {
+				return nil, fmt.Errorf("body limit %s: must be positive", prefix)
+			}
+		}
+	}
+	// browser policy defaults depend on the environment: development allows the local
+	// frontends over plain http, everything else starts closed
+	env := os.Getenv("APP_ENV")
+	security := SecurityConfig{
+		AllowCredentials: os.Getenv("CORS_ALLOW_CREDENTIALS") != "false",
+		PreflightMaxAge:  envDuration("CORS_MAX_AGE", 10*time.Minute),
+		HSTSMaxAge:       365 * 24 * time.Hour,
+		FrameOptions:     "DENY",
+		CookieSecure:     true,
+		CSRFCookie:       "csrf_token",
+		CSRFHeader:       "X-CSRF-Token",
+		AccessCookie:     "access_token",
+		AuthCookies:      []string{"access_token", "refresh_token"},
+	}
+	if env == "development" {
+		security.AllowedOrigins = []string{"http://localhost:3000", "http://localhost:5173"}
+		security.HSTSMaxAge = 0
+		security.CookieSecure = false
+	}
+	if s := os.Getenv("CORS_ALLOWED_ORIGINS"); s != "" {
+		security.AllowedOrigins = splitList(s)
+	}
+	if s := os.Getenv("HSTS_MAX_AGE"); s != "" {
+		if v, err := time.ParseDuration(s); err == nil && v >= 0 {
+			security.HSTSMaxAge = v
+		}
+	}
+	if s := os.Getenv("FRAME_OPTIONS"); s != "" {
+		security.FrameOptions = s
+	}
+	if s := os.Getenv("COOKIE_SECURE"); s != "" {
+		security.CookieSecure = s == "true"
+	}
+	if s := os.Getenv("ACCESS_COOKIE_NAME"); s != "" {
+		security.AccessCookie = s
+	}
+	if s := os.Getenv("CSRF_AUTH_COOKIES"); s != "" {
+		security.AuthCookies = splitList(s)
+	}
+	for _, o := range security.AllowedOrigins {
+		if o == "*" && security.AllowCredentials {
+			return nil, errors.New("CORS_ALLOWED_ORIGINS cannot be * while credentials are allowed")
+		}
+	}
 
 	cfg := &Config{
 		Port:             port,
 		JWTPublicKeyPath: jwtPath,
+		IdentitySecret:   identitySecret,
+		AdminPort:        adminPort,
+		AdminToken:       adminToken,
 		RateLimitPerMin:  rl,
+		RateLimitBurst:   burst,
+		IdempotencyTTL:   envDuration("IDEMPOTENCY_TTL", 24*time.Hour),
 		CircuitBreaker: CircuitBreakerConfig{
 			MaxFailures: maxFail,
 			IntervalSec: interval,
 			TimeoutSec:  timeout,
 		},
+		HealthCheck: HealthCheckConfig{
+			Interval: hcInterval,
+			Timeout:  hcTimeout,
+			Path:     hcPath,
+		},
+		Limits:   limits,
+		Security: security,
+		Redis: RedisConfig{
+			Addr:     os.Getenv("REDIS_ADDR"),
+			Password: os.Getenv("REDIS_PASSWORD"),
+			DB:       redisDB,
+		},
+		NATSURL:      os.Getenv("NATS_URL"),
 		ServicesJSON: os.Getenv("SERVICES_JSON"),
 		ConsulAddr:   os.Getenv("CONSUL_ADDR"),
 	}
@@ -82,5 +300,72 @@ func LoadFromEnv() (*Config, error) {
 		cfg.Services = map[string]string{}
 	}
 
+	// parse per-route rate limit policies if provided
+	cfg.RatePolicies = map[string]RatePolicy{}
+	if s := os.Getenv("RATE_LIMIT_POLICIES_JSON"); s != "" {
+		var m map[string]RatePolicy
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			return nil, err
+		}
+		for prefix, p := range m {
+			d, err := time.ParseDuration(p.PeriodStr)
+			if err != nil {
+				return nil, fmt.Errorf("rate policy %s: invalid period %q", prefix, p.PeriodStr)
+			}
+			if p.Limit <= 0 || d <= 0 {
+				return nil, fmt.Errorf("rate policy %s: limit and period must be positive", prefix)
+			}
+			if p.Burst <= 0 {
+				p.Burst = 1
+			}
+			p.Period = d
+			cfg.RatePolicies[prefix] = p
+		}
+	}
+
+	// parse per-route cache policies if provided; routes without one are never cached
+	cfg.CachePolicies = map[string]CachePolicy{}
+	if s := os.Getenv("CACHE_POLICIES_JSON"); s != "" {
+		var m map[string]CachePolicy
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			return nil, err
+		}
+		for prefix, p := range m {
+			d, err := time.ParseDuration(p.TTLStr)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("cache policy %s: invalid ttl %q", prefix, p.TTLStr)
+			}
+			p.TTL = d
+			cfg.CachePolicies[prefix] = p
+		}
+	}
+
 	return cfg, nil
 }
+
+// envInt reads a non-negative integer, falling back to def when unset or invalid.
+func envInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v >= 0 {
+		return v
+	}
+	return def
+}
+
+// envDuration reads a positive duration such as "30s", falling back to def.
+func envDuration(key string, def time.Duration) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+// splitList parses a comma separated env value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
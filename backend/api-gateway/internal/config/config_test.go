@@ -0,0 +1,38 @@
+package config
+
+import "testing"
+
+func TestLoadFromEnvRateLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		perMin    string
+		burst     string
+		wantLimit int
+		wantBurst int
+		wantErr   bool
+	}{
+		{name: "defaults", wantLimit: 60, wantBurst: 5},
+		{name: "explicit", perMin: "120", burst: "0", wantLimit: 120, wantBurst: 0},
+		{name: "zero limit", perMin: "0", wantErr: true},
+		{name: "negative limit", perMin: "-1", wantErr: true},
+		{name: "negative burst", burst: "-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_PUBLIC_KEY_PATH", "/keys/jwt.pub")
+			t.Setenv("IDENTITY_HMAC_SECRET", "secret")
+			t.Setenv("RATE_LIMIT_PER_MIN", tt.perMin)
+			t.Setenv("RATE_LIMIT_BURST", tt.burst)
+			cfg, err := LoadFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadFromEnv error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.RateLimitPerMin != tt.wantLimit || cfg.RateLimitBurst != tt.wantBurst {
+				t.Fatalf("rate limit = %d burst %d, want %d burst %d", cfg.RateLimitPerMin, cfg.RateLimitBurst, tt.wantLimit, tt.wantBurst)
+			}
+		})
+	}
+}
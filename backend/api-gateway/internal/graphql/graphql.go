@@ -0,0 +1,172 @@
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fathima-sithara/api-gateway/internal/middleware"
+	"github.com/fathima-sithara/api-gateway/internal/proxy"
+	"github.com/gofiber/fiber/v2"
+	gql "github.com/graph-gophers/graphql-go"
+	"go.uber.org/zap"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+const (
+	// batchWait is how long a loader collects keys before fetching them
+	batchWait = 2 * time.Millisecond
+	maxBatch  = 100
+	// fanout bounds the resolvers of one query running at once
+	fanout = 8
+)
+
+// forwardedHeaders carry the caller's identity and correlation ids to the
+// services, exactly as they would reach them through the proxy.
+var forwardedHeaders = []string{
+	fiber.HeaderAuthorization,
+	"X-User-ID", "X-User-Roles", "X-User-Session-ID", "X-User-Timestamp", "X-User-Signature",
+	"X-Request-ID", "traceparent", "X-API-Version",
+}
+
+// Server answers GraphQL queries by fanning out to the services behind the gateway.
+type Server struct {
+	schema *gql.Schema
+	proxy  *proxy.Proxy
+	log    *zap.Logger
+}
+
+func New(p *proxy.Proxy, logger *zap.Logger) (*Server, error) {
+	schema, err := gql.ParseSchema(schemaSDL, &resolver{},
+		gql.MaxDepth(8),
+		gql.MaxParallelism(fanout),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{schema: schema, proxy: p, log: logger}, nil
+}
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler serves POST /graphql. It must run after JWT authentication, whose signed
+// identity headers are reused for the upstream calls.
+func (s *Server) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req request
+		if err := c.BodyParser(&req); err != nil || req.Query == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": []fiber.Map{{"message": "query is required"}}})
+		}
+
+		header := http.Header{}
+		for _, h := range forwardedHeaders {
+			if v := c.Get(h); v != "" {
+				header.Set(h, v)
+			}
+		}
+		ctx := c.UserContext()
+		f := newFetcher(ctx, s.proxy, header, s.log.With(middleware.LogFields(c)...))
+
+		resp := s.schema.Exec(withFetcher(ctx, f), req.Query, req.OperationName, req.Variables)
+		return c.JSON(resp)
+	}
+}
+
+type ctxKey struct{}
+
+func withFetcher(ctx context.Context, f *fetcher) context.Context {
+	return context.WithValue(ctx, ctxKey{}, f)
+}
+
+func fetcherFrom(ctx context.Context) *fetcher {
+	return ctx.Value(ctxKey{}).(*fetcher)
+}
+
+// fetcher makes the upstream calls of one GraphQL request. Per-entity lookups go
+// through loaders so a user who is a member of ten chats is fetched once, and the
+// users, presence or last messages a query needs take one call per batch.
+type fetcher struct {
+	proxy  *proxy.Proxy
+	header http.Header
+	log    *zap.Logger
+
+	lastMessages *Loader[string, *message]
+	users        *Loader[string, *user]
+	presence     *Loader[string, *bool]
+}
+
+func newFetcher(ctx context.Context, p *proxy.Proxy, header http.Header, logger *zap.Logger) *fetcher {
+	f := &fetcher{proxy: p, header: header, log: logger}
+	f.lastMessages = NewLoader(ctx, func(ctx context.Context, chatIDs []string) (map[string]*message, error) {
+		var env struct {
+			Data map[string]*message `json:"data"`
+		}
+		_, err := f.get(ctx, "message", "/v1/last-messages?chat_ids="+listParam(chatIDs), &env)
+		return env.Data, f.logged("last message", err)
+	}, batchWait, maxBatch)
+	f.users = NewLoader(ctx, func(ctx context.Context, userIDs []string) (map[string]*user, error) {
+		var env struct {
+			Data map[string]*user `json:"data"`
+		}
+		_, err := f.get(ctx, "user", "/api/v1/users?ids="+listParam(userIDs), &env)
+		return env.Data, f.logged("user", err)
+	}, batchWait, maxBatch)
+	f.presence = NewLoader(ctx, func(ctx context.Context, userIDs []string) (map[string]*bool, error) {
+		var p struct {
+			Online map[string]bool `json:"online"`
+		}
+		if _, err := f.get(ctx, "websocket", "/presence?user_ids="+listParam(userIDs), &p); err != nil {
+			return nil, f.logged("presence", err)
+		}
+		out := make(map[string]*bool, len(p.Online))
+		for id, online := range p.Online {
+			online := online
+			out[id] = &online
+		}
+		return out, nil
+	}, batchWait, maxBatch)
+	return f
+}
+
+// listParam encodes keys as the comma-separated list the batch endpoints take.
+func listParam(keys []string) string {
+	return url.QueryEscape(strings.Join(keys, ","))
+}
+
+// get fetches path from service into out. A 404 reports ok=false without an error.
+func (f *fetcher) get(ctx context.Context, service, path string, out interface{}) (bool, error) {
+	status, body, err := f.proxy.Fetch(ctx, service, path, f.header)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case status == http.StatusNotFound:
+		return false, nil
+	case status >= http.StatusBadRequest:
+		return false, fmt.Errorf("%s %s: status %d", service, path, status)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return false, fmt.Errorf("%s %s: %w", service, path, err)
+	}
+	return true, nil
+}
+
+// logged logs a failed batch lookup before the error reaches every field of
+// the batch.
+func (f *fetcher) logged(what string, err error) error {
+	if err != nil {
+		f.log.Warn("graphql "+what+" lookup failed", zap.Error(err))
+	}
+	return err
+}
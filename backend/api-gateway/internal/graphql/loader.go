@@ -0,0 +1,91 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Loader coalesces the Load calls made within a short window into one batch
+// fetch and remembers the results, so each key is fetched at most once. A loader
+// lives for a single GraphQL request, and its fetches run under that request's
+// context rather than the context of whichever Load happened to start a batch.
+type Loader[K comparable, V any] struct {
+	ctx      context.Context
+	fetch    func(ctx context.Context, keys []K) (map[K]V, error)
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	cache   map[K]*loadResult[V]
+	pending *loadBatch[K, V]
+}
+
+type loadResult[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+type loadBatch[K comparable, V any] struct {
+	keys    []K
+	results []*loadResult[V]
+}
+
+// NewLoader returns a loader for fetch that fetches under the request context
+// ctx. Keys missing from the map fetch returns load as the zero value.
+func NewLoader[K comparable, V any](ctx context.Context, fetch func(ctx context.Context, keys []K) (map[K]V, error), wait time.Duration, maxBatch int) *Loader[K, V] {
+	return &Loader[K, V]{ctx: ctx, fetch: fetch, wait: wait, maxBatch: maxBatch, cache: map[K]*loadResult[V]{}}
+}
+
+// Load returns the value for key, waiting at most until ctx is done.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	r, ok := l.cache[key]
+	if !ok {
+		r = &loadResult[V]{done: make(chan struct{})}
+		l.cache[key] = r
+		if l.pending == nil {
+			b := &loadBatch[K, V]{}
+			l.pending = b
+			time.AfterFunc(l.wait, func() { l.dispatch(b) })
+		}
+		b := l.pending
+		b.keys = append(b.keys, key)
+		b.results = append(b.results, r)
+		if len(b.keys) >= l.maxBatch {
+			l.pending = nil
+			go l.run(b)
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return r.val, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// dispatch runs b when its wait expires, unless it already filled up and ran.
+func (l *Loader[K, V]) dispatch(b *loadBatch[K, V]) {
+	l.mu.Lock()
+	if l.pending != b {
+		l.mu.Unlock()
+		return
+	}
+	l.pending = nil
+	l.mu.Unlock()
+	l.run(b)
+}
+
+func (l *Loader[K, V]) run(b *loadBatch[K, V]) {
+	vals, err := l.fetch(l.ctx, b.keys)
+	for i, key := range b.keys {
+		r := b.results[i]
+		r.val, r.err = vals[key], err
+		close(r.done)
+	}
+}
@@ -0,0 +1,120 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type ctxTag struct{}
+
+func TestLoader(t *testing.T) {
+	errDown := errors.New("upstream down")
+	tests := []struct {
+		name     string
+		keys     []string
+		maxBatch int
+		fail     bool
+		// wantSizes are the sizes of the fetched batches, largest first
+		wantSizes []int
+		wantErr   error
+	}{
+		{name: "one batch for concurrent loads", keys: []string{"a", "b", "c"}, maxBatch: 100, wantSizes: []int{3}},
+		{name: "repeated keys are fetched once", keys: []string{"a", "b", "a", "b", "a"}, maxBatch: 100, wantSizes: []int{2}},
+		{name: "full batches go at once", keys: []string{"a", "b", "c", "d", "e"}, maxBatch: 2, wantSizes: []int{2, 2, 1}},
+		{name: "missing keys load as zero", keys: []string{"a", "missing"}, maxBatch: 100, wantSizes: []int{2}},
+		{name: "errors reach every caller", keys: []string{"a", "b"}, maxBatch: 100, fail: true, wantSizes: []int{2}, wantErr: errDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqCtx := context.WithValue(context.Background(), ctxTag{}, "request")
+			var mu sync.Mutex
+			var sizes []int
+			fetched := map[string]int{}
+			fetch := func(ctx context.Context, keys []string) (map[string]string, error) {
+				if ctx.Value(ctxTag{}) != "request" {
+					t.Error("fetch did not run under the request context")
+				}
+				mu.Lock()
+				sizes = append(sizes, len(keys))
+				for _, k := range keys {
+					fetched[k]++
+				}
+				mu.Unlock()
+				if tt.fail {
+					return nil, errDown
+				}
+				out := map[string]string{}
+				for _, k := range keys {
+					if k != "missing" {
+						out[k] = "v" + k
+					}
+				}
+				return out, nil
+			}
+			// a long wait makes only the batch size or the timer decide, not scheduling
+			l := NewLoader(reqCtx, fetch, 20*time.Millisecond, tt.maxBatch)
+
+			var wg sync.WaitGroup
+			for _, k := range tt.keys {
+				wg.Add(1)
+				go func(k string) {
+					defer wg.Done()
+					// each resolver has its own context, as graphql-go hands out
+					ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+					defer cancel()
+					v, err := l.Load(ctx, k)
+					want := "v" + k
+					if k == "missing" || tt.wantErr != nil {
+						want = ""
+					}
+					if !errors.Is(err, tt.wantErr) || v != want {
+						t.Errorf("Load(%q) = (%q, %v), want (%q, %v)", k, v, err, want, tt.wantErr)
+					}
+				}(k)
+			}
+			wg.Wait()
+
+			sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+			if !reflect.DeepEqual(sizes, tt.wantSizes) {
+				t.Fatalf("batch sizes = %v, want %v", sizes, tt.wantSizes)
+			}
+			for _, k := range tt.keys {
+				if fetched[k] != 1 {
+					t.Fatalf("key %q fetched %d times, want 1", k, fetched[k])
+				}
+			}
+		})
+	}
+}
+
+func TestLoaderCachesAndOutlivesCallers(t *testing.T) {
+	calls := 0
+	release := make(chan struct{})
+	l := NewLoader(context.Background(), func(ctx context.Context, keys []string) (map[string]int, error) {
+		calls++
+		<-release
+		return map[string]int{"a": 1}, ctx.Err()
+	}, time.Millisecond, 10)
+
+	// the first caller gives up; the batch it started still completes for others
+	gone, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Load(gone, "a"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled Load err = %v, want context.Canceled", err)
+	}
+	close(release)
+	for i := 0; i < 2; i++ {
+		v, err := l.Load(context.Background(), "a")
+		if err != nil || v != 1 {
+			t.Fatalf("Load = (%d, %v), want (1, nil)", v, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("fetch ran %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,163 @@
+package graphql
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	gql "github.com/graph-gophers/graphql-go"
+)
+
+// Upstream payloads, trimmed to the fields the schema exposes.
+
+type chat struct {
+	ID                string    `json:"id"`
+	Name              string    `json:"name"`
+	IsGroup           bool      `json:"is_group"`
+	Members           []string  `json:"members"`
+	LastMessage       *message  `json:"last_message"`
+	UnreadCount       int32     `json:"unread_count"`
+	LastReadMessageID string    `json:"last_read_message_id"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type message struct {
+	ID        string    `json:"id"`
+	ChatID    string    `json:"chat_id"`
+	SenderID  string    `json:"sender_id"`
+	Content   string    `json:"content"`
+	MsgType   string    `json:"msg_type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type user struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+}
+
+type resolver struct{}
+
+func (r *resolver) Chats(ctx context.Context) ([]*chatResolver, error) {
+	var env struct {
+		Data []*chat `json:"data"`
+	}
+	if _, err := fetcherFrom(ctx).get(ctx, "chat", "/v1/chats", &env); err != nil {
+		return nil, err
+	}
+	out := make([]*chatResolver, 0, len(env.Data))
+	for _, c := range env.Data {
+		out = append(out, &chatResolver{c})
+	}
+	return out, nil
+}
+
+func (r *resolver) Chat(ctx context.Context, args struct{ ID gql.ID }) (*chatResolver, error) {
+	var env struct {
+		Data *chat `json:"data"`
+	}
+	ok, err := fetcherFrom(ctx).get(ctx, "chat", "/v1/chats/"+url.PathEscape(string(args.ID)), &env)
+	if err != nil || !ok || env.Data == nil {
+		return nil, err
+	}
+	return &chatResolver{env.Data}, nil
+}
+
+func (r *resolver) Me(ctx context.Context) (*userResolver, error) {
+	var u user
+	ok, err := fetcherFrom(ctx).get(ctx, "user", "/api/v1/users/me", &u)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &userResolver{&u}, nil
+}
+
+type chatResolver struct{ c *chat }
+
+func (r *chatResolver) ID() gql.ID        { return gql.ID(r.c.ID) }
+func (r *chatResolver) Name() string      { return r.c.Name }
+func (r *chatResolver) IsGroup() bool     { return r.c.IsGroup }
+func (r *chatResolver) CreatedAt() string { return r.c.CreatedAt.Format(time.RFC3339) }
+func (r *chatResolver) UpdatedAt() string { return r.c.UpdatedAt.Format(time.RFC3339) }
+
+func (r *chatResolver) UnreadCount() int32 { return r.c.UnreadCount }
+
+func (r *chatResolver) LastReadMessageID() *gql.ID {
+	if r.c.LastReadMessageID == "" {
+		return nil
+	}
+	id := gql.ID(r.c.LastReadMessageID)
+	return &id
+}
+
+// LastMessage uses the copy chat-service keeps on the chat, and only asks
+// message-service when it has none.
+func (r *chatResolver) LastMessage(ctx context.Context) (*messageResolver, error) {
+	if r.c.LastMessage != nil {
+		m := *r.c.LastMessage
+		m.ChatID = r.c.ID
+		return &messageResolver{&m}, nil
+	}
+	m, err := fetcherFrom(ctx).lastMessages.Load(ctx, r.c.ID)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	return &messageResolver{m}, nil
+}
+
+func (r *chatResolver) Members() []*memberResolver {
+	out := make([]*memberResolver, 0, len(r.c.Members))
+	for _, id := range r.c.Members {
+		out = append(out, &memberResolver{id})
+	}
+	return out
+}
+
+type memberResolver struct{ userID string }
+
+func (r *memberResolver) UserID() gql.ID { return gql.ID(r.userID) }
+
+func (r *memberResolver) Profile(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, r.userID)
+}
+
+func (r *memberResolver) Online(ctx context.Context) (*bool, error) {
+	return fetcherFrom(ctx).presence.Load(ctx, r.userID)
+}
+
+type userResolver struct{ u *user }
+
+func (r *userResolver) ID() gql.ID        { return gql.ID(r.u.ID) }
+func (r *userResolver) Username() *string { return optional(r.u.Username) }
+func (r *userResolver) Email() *string    { return optional(r.u.Email) }
+func (r *userResolver) Phone() *string    { return optional(r.u.Phone) }
+
+type messageResolver struct{ m *message }
+
+func (r *messageResolver) ID() gql.ID        { return gql.ID(r.m.ID) }
+func (r *messageResolver) ChatID() gql.ID    { return gql.ID(r.m.ChatID) }
+func (r *messageResolver) SenderID() gql.ID  { return gql.ID(r.m.SenderID) }
+func (r *messageResolver) Content() string   { return r.m.Content }
+func (r *messageResolver) Type() string      { return r.m.MsgType }
+func (r *messageResolver) CreatedAt() string { return r.m.CreatedAt.Format(time.RFC3339) }
+
+func (r *messageResolver) Sender(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, r.m.SenderID)
+}
+
+func loadUser(ctx context.Context, id string) (*userResolver, error) {
+	u, err := fetcherFrom(ctx).users.Load(ctx, id)
+	if err != nil || u == nil {
+		return nil, err
+	}
+	return &userResolver{u}, nil
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
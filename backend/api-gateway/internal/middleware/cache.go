@@ -0,0 +1,391 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fathima-sithara/api-gateway/internal/config"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Headers exchanged with upstream services. Surrogate-Key tags a cacheable response
+// (space separated, e.g. "chat:42 user:7"); X-Cache-Purge on any response drops every
+// entry carrying one of the listed tags. Neither is passed on to clients.
+const (
+	HeaderSurrogateKey = "Surrogate-Key"
+	HeaderCachePurge   = "X-Cache-Purge"
+)
+
+// SubjectCachePurge carries purges from service event consumers, for changes that
+// never pass through the gateway, such as a message sent over a chat WebSocket
+// moving the chat up its members' lists. The payload is {"tags": [...]}.
+const SubjectCachePurge = "cache.purge"
+
+// maxCacheBody keeps large listings out of the cache.
+const maxCacheBody = 1 << 20
+
+// ResponseCache caches successful GET responses for routes with a cache policy.
+// Entries are private to the authenticated user: the key is user id plus the full
+// request URI, so it must run after JWTMiddleware.
+type ResponseCache struct {
+	store  cacheStore
+	shared bool // store is Redis, seen by every replica
+	routes []cacheRoute
+	log    *zap.Logger
+}
+
+type cacheRoute struct {
+	prefix string
+	ttl    time.Duration
+}
+
+type cachedResponse struct {
+	Status       int    `json:"status"`
+	ContentType  string `json:"content_type"`
+	CacheControl string `json:"cache_control,omitempty"`
+	ETag         string `json:"etag"`
+	Body         []byte `json:"body"`
+}
+
+type cacheStore interface {
+	// get returns nil without error on a miss.
+	get(ctx context.Context, key string) (*cachedResponse, error)
+	set(ctx context.Context, key string, r *cachedResponse, ttl time.Duration, tags []string) error
+	purge(ctx context.Context, tags []string) error
+}
+
+func NewResponseCache(cfg *config.Config, rdb *redis.Client, logger *zap.Logger) *ResponseCache {
+	var routes []cacheRoute
+	var maxTTL time.Duration
+	for prefix, p := range cfg.CachePolicies {
+		routes = append(routes, cacheRoute{prefix: prefix, ttl: p.TTL})
+		if p.TTL > maxTTL {
+			maxTTL = p.TTL
+		}
+	}
+	// longest prefix wins
+	sort.Slice(routes, func(i, j int) bool { return len(routes[i].prefix) > len(routes[j].prefix) })
+
+	var st cacheStore
+	if rdb != nil {
+		st = &redisCache{rdb: rdb, tagTTL: maxTTL}
+	} else {
+		if len(routes) > 0 {
+			logger.Warn("response cache using in-process store; purges do not reach other replicas")
+		}
+		st = newMemoryCache()
+	}
+
+	return &ResponseCache{store: st, shared: rdb != nil, routes: routes, log: logger}
+}
+
+// SubscribePurges applies the purges published on SubjectCachePurge. A shared
+// store needs each purge once, so replicas then share a queue group; in-process
+// stores need it on every replica.
+func (rc *ResponseCache) SubscribePurges(nc *nats.Conn) (*nats.Subscription, error) {
+	handle := func(m *nats.Msg) {
+		if err := rc.purgeEvent(m.Data); err != nil {
+			rc.log.Error("cache purge event failed", zap.Error(err))
+		}
+	}
+	if rc.shared {
+		return nc.QueueSubscribe(SubjectCachePurge, "api-gateway", handle)
+	}
+	return nc.Subscribe(SubjectCachePurge, handle)
+}
+
+func (rc *ResponseCache) purgeEvent(data []byte) error {
+	var ev struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return err
+	}
+	if len(ev.Tags) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return rc.store.purge(ctx, ev.Tags)
+}
+
+func (rc *ResponseCache) ttlFor(path string) (time.Duration, bool) {
+	for _, r := range rc.routes {
+		if strings.HasPrefix(path, r.prefix) {
+			return r.ttl, true
+		}
+	}
+	return 0, false
+}
+
+func (rc *ResponseCache) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ttl, ok := rc.ttlFor(c.Path())
+		uid, _ := c.Locals("user_id").(string)
+		if !ok || uid == "" || c.Method() != fiber.MethodGet {
+			err := c.Next()
+			rc.applyPurge(c)
+			return err
+		}
+
+		// the proxy rewrites the request URI, so build the key first
+		key := "cache:" + uid + ":" + c.OriginalURL()
+
+		if !strings.Contains(strings.ToLower(c.Get(fiber.HeaderCacheControl)), "no-cache") {
+			r, err := rc.store.get(c.UserContext(), key)
+			if err != nil {
+				rc.log.Error("cache get failed", append(LogFields(c), zap.Error(err))...)
+			} else if r != nil {
+				c.Set("X-Cache", "HIT")
+				return writeCached(c, r)
+			}
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+		rc.applyPurge(c)
+
+		res := &c.Response().Header
+		tags := strings.Fields(string(res.Peek(HeaderSurrogateKey)))
+		res.Del(HeaderSurrogateKey)
+		c.Set("X-Cache", "MISS")
+
+		if c.Response().StatusCode() != fiber.StatusOK || len(res.Peek(fiber.HeaderContentEncoding)) > 0 {
+			return nil
+		}
+		body := c.Response().Body()
+		etag := string(res.Peek(fiber.HeaderETag))
+		if etag == "" {
+			sum := sha256.Sum256(body)
+			etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+			c.Set(fiber.HeaderETag, etag)
+		}
+
+		cc := string(res.Peek(fiber.HeaderCacheControl))
+		if ttl, ok = cacheTTL(cc, ttl); ok && len(body) <= maxCacheBody {
+			entry := &cachedResponse{
+				Status:       fiber.StatusOK,
+				ContentType:  string(res.ContentType()),
+				CacheControl: cc,
+				ETag:         etag,
+				Body:         append([]byte(nil), body...),
+			}
+			if err := rc.store.set(c.UserContext(), key, entry, ttl, tags); err != nil {
+				rc.log.Error("cache set failed", append(LogFields(c), zap.Error(err))...)
+			}
+		}
+
+		if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+			c.Status(fiber.StatusNotModified)
+			c.Response().ResetBody()
+		}
+		return nil
+	}
+}
+
+// applyPurge drops entries for the tags an upstream listed in X-Cache-Purge.
+func (rc *ResponseCache) applyPurge(c *fiber.Ctx) {
+	res := &c.Response().Header
+	tags := strings.Fields(string(res.Peek(HeaderCachePurge)))
+	res.Del(HeaderCachePurge)
+	if len(tags) == 0 {
+		return
+	}
+	if err := rc.store.purge(c.UserContext(), tags); err != nil {
+		rc.log.Error("cache purge failed", append(LogFields(c), zap.Error(err), zap.Strings("tags", tags))...)
+	}
+}
+
+func writeCached(c *fiber.Ctx, r *cachedResponse) error {
+	c.Set(fiber.HeaderETag, r.ETag)
+	if r.CacheControl != "" {
+		c.Set(fiber.HeaderCacheControl, r.CacheControl)
+	}
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), r.ETag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	c.Set(fiber.HeaderContentType, r.ContentType)
+	return c.Status(r.Status).Send(r.Body)
+}
+
+// cacheTTL applies the upstream Cache-Control to the route's TTL: no-store, no-cache
+// and max-age=0 disable caching, and a shorter s-maxage or max-age wins.
+func cacheTTL(cacheControl string, ttl time.Duration) (time.Duration, bool) {
+	var maxAge, sMaxAge = -1, -1
+	for _, d := range strings.Split(strings.ToLower(cacheControl), ",") {
+		d = strings.TrimSpace(d)
+		switch {
+		case d == "no-store" || d == "no-cache":
+			return 0, false
+		case strings.HasPrefix(d, "s-maxage="):
+			sMaxAge, _ = strconv.Atoi(strings.TrimPrefix(d, "s-maxage="))
+		case strings.HasPrefix(d, "max-age="):
+			maxAge, _ = strconv.Atoi(strings.TrimPrefix(d, "max-age="))
+		}
+	}
+	if sMaxAge >= 0 {
+		maxAge = sMaxAge
+	}
+	if maxAge == 0 {
+		return 0, false
+	}
+	if maxAge > 0 && time.Duration(maxAge)*time.Second < ttl {
+		ttl = time.Duration(maxAge) * time.Second
+	}
+	return ttl, true
+}
+
+// etagMatches implements the weak comparison If-None-Match requires.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// redisCache shares entries between gateway replicas. Each tag is a set of the
+// entry keys carrying it.
+type redisCache struct {
+	rdb    *redis.Client
+	tagTTL time.Duration
+}
+
+func (s *redisCache) get(ctx context.Context, key string) (*cachedResponse, error) {
+	b, err := s.rdb.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r cachedResponse
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *redisCache) set(ctx context.Context, key string, r *cachedResponse, ttl time.Duration, tags []string) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	pipe := s.rdb.TxPipeline()
+	pipe.Set(ctx, key, b, ttl)
+	for _, t := range tags {
+		pipe.SAdd(ctx, "cache:tag:"+t, key)
+		// outlive every entry the tag may point at
+		pipe.Expire(ctx, "cache:tag:"+t, s.tagTTL)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *redisCache) purge(ctx context.Context, tags []string) error {
+	for _, t := range tags {
+		tagKey := "cache:tag:" + t
+		keys, err := s.rdb.SMembers(ctx, tagKey).Result()
+		if err != nil {
+			return err
+		}
+		if err := s.rdb.Del(ctx, append(keys, tagKey)...).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// memoryCache is the single-replica fallback used when no Redis is configured.
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	tags    map[string]map[string]struct{}
+}
+
+type memoryEntry struct {
+	resp    *cachedResponse
+	expires time.Time
+}
+
+func newMemoryCache() *memoryCache {
+	s := &memoryCache{entries: map[string]memoryEntry{}, tags: map[string]map[string]struct{}{}}
+	go s.cleanup()
+	return s
+}
+
+func (s *memoryCache) get(_ context.Context, key string) (*cachedResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		return nil, nil
+	}
+	return e.resp, nil
+}
+
+func (s *memoryCache) set(_ context.Context, key string, r *cachedResponse, ttl time.Duration, tags []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryEntry{resp: r, expires: time.Now().Add(ttl)}
+	for _, t := range tags {
+		if s.tags[t] == nil {
+			s.tags[t] = map[string]struct{}{}
+		}
+		s.tags[t][key] = struct{}{}
+	}
+	return nil
+}
+
+func (s *memoryCache) purge(_ context.Context, tags []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range tags {
+		for key := range s.tags[t] {
+			delete(s.entries, key)
+		}
+		delete(s.tags, t)
+	}
+	return nil
+}
+
+func (s *memoryCache) cleanup() {
+	for {
+		time.Sleep(time.Minute)
+		now := time.Now()
+		s.mu.Lock()
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		for t, keys := range s.tags {
+			for k := range keys {
+				if _, ok := s.entries[k]; !ok {
+					delete(keys, k)
+				}
+			}
+			if len(keys) == 0 {
+				delete(s.tags, t)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
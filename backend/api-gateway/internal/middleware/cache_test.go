@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestCacheTTL(t *testing.T) {
+	const route = 30 * time.Second
+	tests := []struct {
+		name         string
+		cacheControl string
+		want         time.Duration
+		wantOK       bool
+	}{
+		{"no header keeps the route ttl", "", route, true},
+		{"no-store", "no-store", 0, false},
+		{"no-cache among others", "public, No-Cache", 0, false},
+		{"max-age=0", "max-age=0", 0, false},
+		{"shorter max-age wins", "max-age=10", 10 * time.Second, true},
+		{"longer max-age is capped", "max-age=3600", route, true},
+		{"s-maxage beats max-age", "max-age=5, s-maxage=20", 20 * time.Second, true},
+		{"s-maxage=0 disables", "max-age=20, s-maxage=0", 0, false},
+		{"unparsable max-age disables", "max-age=soon", 0, false},
+		{"unrelated directives", "public, must-revalidate", route, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := cacheTTL(tt.cacheControl, route)
+			if got != tt.want || ok != tt.wantOK {
+				t.Fatalf("cacheTTL(%q) = (%v, %v), want (%v, %v)", tt.cacheControl, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		etag        string
+		want        bool
+	}{
+		{"exact", `"abc"`, `"abc"`, true},
+		{"different", `"abc"`, `"abd"`, false},
+		{"weak request tag", `W/"abc"`, `"abc"`, true},
+		{"weak stored tag", `"abc"`, `W/"abc"`, true},
+		{"one of a list", `"x", "abc" , "y"`, `"abc"`, true},
+		{"wildcard", `*`, `"abc"`, true},
+		{"no If-None-Match", ``, `"abc"`, false},
+		{"no etag", `*`, ``, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagMatches(tt.ifNoneMatch, tt.etag); got != tt.want {
+				t.Fatalf("etagMatches(%q, %q) = %v, want %v", tt.ifNoneMatch, tt.etag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPurgeEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   string
+		wantErr bool
+		// wantKept are the entries still cached afterwards
+		wantKept []string
+	}{
+		{name: "chat tag", event: `{"tags":["chat:c1"]}`, wantKept: []string{"u2-list"}},
+		{name: "member list tag", event: `{"tags":["chats:user:u2"]}`, wantKept: []string{"u1-list", "u1-chat"}},
+		{name: "several tags", event: `{"tags":["chats:user:u1","chats:user:u2"]}`, wantKept: []string{"u1-chat"}},
+		{name: "unknown tag", event: `{"tags":["chat:other"]}`, wantKept: []string{"u1-list", "u1-chat", "u2-list"}},
+		{name: "no tags", event: `{}`, wantKept: []string{"u1-list", "u1-chat", "u2-list"}},
+		{name: "not json", event: `chat:c1`, wantErr: true, wantKept: []string{"u1-list", "u1-chat", "u2-list"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			st := newMemoryCache()
+			entries := map[string][]string{
+				"u1-list": {"chats:user:u1", "chat:c1"},
+				"u1-chat": {"chat:c1"},
+				"u2-list": {"chats:user:u2", "chat:c2"},
+			}
+			for key, tags := range entries {
+				if err := st.set(ctx, key, &cachedResponse{Status: 200}, time.Minute, tags); err != nil {
+					t.Fatal(err)
+				}
+			}
+			rc := &ResponseCache{store: st, log: zap.NewNop()}
+
+			if err := rc.purgeEvent([]byte(tt.event)); (err != nil) != tt.wantErr {
+				t.Fatalf("purgeEvent error = %v, want error %v", err, tt.wantErr)
+			}
+			kept := map[string]bool{}
+			for _, k := range tt.wantKept {
+				kept[k] = true
+			}
+			for key := range entries {
+				r, _ := st.get(ctx, key)
+				if (r != nil) != kept[key] {
+					t.Fatalf("entry %q cached = %v, want %v", key, r != nil, kept[key])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fathima-sithara/api-gateway/internal/config"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Idempotency-Key names the logical operation a client is retrying; replayed
+// responses carry Idempotent-Replayed: true.
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+const maxIdempotencyKey = 255
+
+// Idempotency makes unsafe requests carrying an Idempotency-Key safe to retry. The
+// first response for a key is stored and replayed for repeats; the same key with a
+// different method, URI or body is a 409, as is a repeat that arrives while the
+// first attempt is still running. Keys are private to the authenticated user, so it
+// must run after JWTMiddleware.
+type Idempotency struct {
+	store idemStore
+	ttl   time.Duration
+	// lockTTL bounds how long an unfinished attempt holds its key
+	lockTTL time.Duration
+	log     *zap.Logger
+}
+
+type idemRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Done        bool   `json:"done"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Location    string `json:"location,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+type idemStore interface {
+	// reserve stores rec under key unless the key is taken, in which case it
+	// returns the existing record and leaves it alone.
+	reserve(ctx context.Context, key string, rec *idemRecord, ttl time.Duration) (*idemRecord, error)
+	save(ctx context.Context, key string, rec *idemRecord, ttl time.Duration) error
+	release(ctx context.Context, key string) error
+}
+
+func NewIdempotency(cfg *config.Config, rdb *redis.Client, logger *zap.Logger) *Idempotency {
+	var st idemStore
+	if rdb != nil {
+		st = &redisIdemStore{rdb: rdb}
+	} else {
+		logger.Warn("idempotency keys using in-process store; retries reaching another replica run again")
+		st = newMemoryIdemStore()
+	}
+	return &Idempotency{
+		store:   st,
+		ttl:     cfg.IdempotencyTTL,
+		lockTTL: cfg.Limits.UpstreamTimeout + 10*time.Second,
+		log:     logger,
+	}
+}
+
+func (idm *Idempotency) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(HeaderIdempotencyKey)
+		uid, _ := c.Locals("user_id").(string)
+		if key == "" || uid == "" || isSafeMethod(c.Method()) {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKey {
+			return WriteProblem(c, Problem{
+				Title:  "Bad Request",
+				Status: fiber.StatusBadRequest,
+				Detail: "Idempotency-Key must be at most " + strconv.Itoa(maxIdempotencyKey) + " characters",
+			})
+		}
+
+		storeKey := "idem:" + uid + ":" + key
+		fp := requestFingerprint(c)
+		prev, err := idm.store.reserve(c.UserContext(), storeKey, &idemRecord{Fingerprint: fp}, idm.lockTTL)
+		if err != nil {
+			// fail open like the rate limiter: a retry may run twice, but nothing breaks
+			idm.log.Error("idempotency store error", append(LogFields(c), zap.Error(err))...)
+			return c.Next()
+		}
+		if prev != nil {
+			switch {
+			case prev.Fingerprint != fp:
+				return WriteProblem(c, Problem{
+					Type:   "/problems/idempotency-key-reuse",
+					Title:  "Idempotency-Key reused",
+					Status: fiber.StatusConflict,
+					Detail: "this key was already used for a different request",
+				})
+			case !prev.Done:
+				c.Set(fiber.HeaderRetryAfter, "1")
+				return WriteProblem(c, Problem{
+					Type:   "/problems/idempotency-in-progress",
+					Title:  "Request in progress",
+					Status: fiber.StatusConflict,
+					Detail: "a request with this key is still being processed",
+				})
+			}
+			return replay(c, prev)
+		}
+
+		if err := c.Next(); err != nil {
+			idm.release(c, storeKey)
+			return err
+		}
+
+		// an overloaded or failing upstream may not have applied the request, so
+		// let the retry make a fresh attempt instead of replaying the failure
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError || status == fiber.StatusTooManyRequests {
+			idm.release(c, storeKey)
+			return nil
+		}
+
+		res := &c.Response().Header
+		rec := &idemRecord{
+			Fingerprint: fp,
+			Done:        true,
+			Status:      status,
+			ContentType: string(res.ContentType()),
+			Location:    string(res.Peek(fiber.HeaderLocation)),
+			Body:        append([]byte(nil), c.Response().Body()...),
+		}
+		if err := idm.store.save(c.UserContext(), storeKey, rec, idm.ttl); err != nil {
+			idm.log.Error("idempotency save failed", append(LogFields(c), zap.Error(err))...)
+		}
+		return nil
+	}
+}
+
+func (idm *Idempotency) release(c *fiber.Ctx, key string) {
+	if err := idm.store.release(c.UserContext(), key); err != nil {
+		idm.log.Error("idempotency release failed", append(LogFields(c), zap.Error(err))...)
+	}
+}
+
+func replay(c *fiber.Ctx, r *idemRecord) error {
+	c.Set(HeaderIdempotentReplayed, "true")
+	if r.Location != "" {
+		c.Set(fiber.HeaderLocation, r.Location)
+	}
+	c.Set(fiber.HeaderContentType, r.ContentType)
+	return c.Status(r.Status).Send(r.Body)
+}
+
+// requestFingerprint identifies what a key was first used for.
+func requestFingerprint(c *fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method() + " " + c.OriginalURL() + "\n"))
+	h.Write(c.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// redisIdemStore shares keys between gateway replicas. reserve relies on SET NX GET,
+// which needs Redis 7.
+type redisIdemStore struct {
+	rdb *redis.Client
+}
+
+func (s *redisIdemStore) reserve(ctx context.Context, key string, rec *idemRecord, ttl time.Duration) (*idemRecord, error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	old, err := s.rdb.SetArgs(ctx, key, b, redis.SetArgs{Mode: "NX", TTL: ttl, Get: true}).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var prev idemRecord
+	if err := json.Unmarshal(old, &prev); err != nil {
+		return nil, err
+	}
+	return &prev, nil
+}
+
+func (s *redisIdemStore) save(ctx context.Context, key string, rec *idemRecord, ttl time.Duration) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, key, b, ttl).Err()
+}
+
+func (s *redisIdemStore) release(ctx context.Context, key string) error {
+	return s.rdb.Del(ctx, key).Err()
+}
+
+// memoryIdemStore is the single-replica fallback used when no Redis is configured.
+type memoryIdemStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryIdemEntry
+}
+
+type memoryIdemEntry struct {
+	rec     *idemRecord
+	expires time.Time
+}
+
+func newMemoryIdemStore() *memoryIdemStore {
+	s := &memoryIdemStore{entries: map[string]memoryIdemEntry{}}
+	go s.cleanup()
+	return s
+}
+
+func (s *memoryIdemStore) reserve(_ context.Context, key string, rec *idemRecord, ttl time.Duration) (*idemRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok && time.Now().Before(e.expires) {
+		return e.rec, nil
+	}
+	s.entries[key] = memoryIdemEntry{rec: rec, expires: time.Now().Add(ttl)}
+	return nil, nil
+}
+
+func (s *memoryIdemStore) save(_ context.Context, key string, rec *idemRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryIdemEntry{rec: rec, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *memoryIdemStore) release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *memoryIdemStore) cleanup() {
+	for {
+		time.Sleep(time.Minute)
+		now := time.Now()
+		s.mu.Lock()
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequestFingerprint(t *testing.T) {
+	app := fiber.New()
+	app.All("/*", func(c *fiber.Ctx) error { return c.SendString(requestFingerprint(c)) })
+	fingerprint := func(method, target, body string) string {
+		resp, err := app.Test(httptest.NewRequest(method, target, strings.NewReader(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	type req struct{ method, target, body string }
+	tests := []struct {
+		name string
+		a, b req
+		same bool
+	}{
+		{"identical", req{"POST", "/chats", `{"name":"x"}`}, req{"POST", "/chats", `{"name":"x"}`}, true},
+		{"other body", req{"POST", "/chats", `{"name":"x"}`}, req{"POST", "/chats", `{"name":"y"}`}, false},
+		{"other method", req{"POST", "/chats", `{}`}, req{"PUT", "/chats", `{}`}, false},
+		{"other path", req{"POST", "/chats", `{}`}, req{"POST", "/chat", `{}`}, false},
+		{"other query", req{"POST", "/chats?a=1", `{}`}, req{"POST", "/chats?a=2", `{}`}, false},
+		{"path and body do not blur", req{"POST", "/a", "b"}, req{"POST", "/ab", ""}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := fingerprint(tt.a.method, tt.a.target, tt.a.body)
+			b := fingerprint(tt.b.method, tt.b.target, tt.b.body)
+			if len(a) != 64 {
+				t.Fatalf("fingerprint %q is not a hex sha256", a)
+			}
+			if (a == b) != tt.same {
+				t.Fatalf("fingerprints equal = %v, want %v", a == b, tt.same)
+			}
+		})
+	}
+}
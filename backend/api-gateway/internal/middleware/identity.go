@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"strings"
+	"time"
+
+	"github.com/fathima-sithara/platform/identity"
+	"github.com/gofiber/fiber/v2"
+)
+
+// IdentitySigner injects the signed identity headers of the identity package
+// for downstream services, which trust them only when X-User-Signature verifies
+// against the shared IDENTITY_HMAC_SECRET.
+type IdentitySigner struct {
+	secret string
+}
+
+func NewIdentitySigner(secret string) *IdentitySigner {
+	return &IdentitySigner{secret: secret}
+}
+
+// Apply sets the signed identity headers on the request that will be proxied upstream.
+func (s *IdentitySigner) Apply(c *fiber.Ctx, userID string, roles []string, sessionID string) {
+	h := &c.Request().Header
+	id := identity.Identity{UserID: userID, Roles: roles, SessionID: sessionID}
+	for k, v := range identity.Sign(s.secret, id, time.Now()) {
+		h.Set(k, v)
+	}
+}
+
+// StripIdentityHeaders removes any client supplied X-User-* headers so callers
+// cannot impersonate another user by setting them directly.
+func StripIdentityHeaders() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var spoofed []string
+		c.Request().Header.VisitAll(func(k, _ []byte) {
+			if len(k) >= 7 && strings.EqualFold(string(k[:7]), "X-User-") {
+				spoofed = append(spoofed, string(k))
+			}
+		})
+		for _, k := range spoofed {
+			c.Request().Header.Del(k)
+		}
+		return c.Next()
+	}
+}
@@ -13,10 +13,13 @@ import (
 
 type JWTMiddleware struct {
 	pubKey *rsa.PublicKey
+	signer *IdentitySigner
+	// cookie holding the access token for browser sessions; empty disables it
+	cookie string
 	log    *zap.Logger
 }
 
-func NewJWTMiddleware(pubKeyPath string, logger *zap.Logger) (*JWTMiddleware, error) {
+func NewJWTMiddleware(pubKeyPath string, signer *IdentitySigner, accessCookie string, logger *zap.Logger) (*JWTMiddleware, error) {
 	data, err := ioutil.ReadFile(pubKeyPath)
 	if err != nil {
 		return nil, err
@@ -27,6 +30,8 @@ func NewJWTMiddleware(pubKeyPath string, logger *zap.Logger) (*JWTMiddleware, er
 	}
 	return &JWTMiddleware{
 		pubKey: pub,
+		signer: signer,
+		cookie: accessCookie,
 		log:    logger,
 	}, nil
 }
@@ -34,13 +39,19 @@ func NewJWTMiddleware(pubKeyPath string, logger *zap.Logger) (*JWTMiddleware, er
 func (j *JWTMiddleware) Handler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		auth := c.Get("Authorization")
-		if auth == "" {
+		var tokenStr string
+		switch {
+		case auth != "":
+			if !strings.HasPrefix(auth, "Bearer ") {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid authorization header"})
+			}
+			tokenStr = strings.TrimPrefix(auth, "Bearer ")
+		case j.cookie != "" && c.Cookies(j.cookie) != "":
+			// cookie session; the CSRF middleware has already checked unsafe methods
+			tokenStr = c.Cookies(j.cookie)
+		default:
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "missing authorization"})
 		}
-		if !strings.HasPrefix(auth, "Bearer ") {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid authorization header"})
-		}
-		tokenStr := strings.TrimPrefix(auth, "Bearer ")
 
 		claims := jwt.MapClaims{}
 		token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
@@ -50,7 +61,7 @@ func (j *JWTMiddleware) Handler() fiber.Handler {
 			return j.pubKey, nil
 		})
 		if err != nil || !token.Valid {
-			j.log.Debug("jwt invalid", zap.Error(err))
+			j.log.Debug("jwt invalid", append(LogFields(c), zap.Error(err))...)
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or expired token"})
 		}
 
@@ -64,7 +75,32 @@ func (j *JWTMiddleware) Handler() fiber.Handler {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "missing user id in token"})
 		}
 
+		roles := claimRoles(claims)
+		sid, _ := claims["sid"].(string)
+		if sid == "" {
+			sid, _ = claims["jti"].(string)
+		}
+
 		c.Locals("user_id", uid)
+		c.Locals("roles", roles)
+		c.Locals("session_id", sid)
+		j.signer.Apply(c, uid, roles, sid)
 		return c.Next()
 	}
 }
+
+// claimRoles accepts either a "roles" array or a single "role" string claim.
+func claimRoles(claims jwt.MapClaims) []string {
+	var roles []string
+	if arr, ok := claims["roles"].([]interface{}); ok {
+		for _, r := range arr {
+			if s, ok := r.(string); ok && s != "" {
+				roles = append(roles, s)
+			}
+		}
+	}
+	if r, ok := claims["role"].(string); ok && r != "" {
+		roles = append(roles, r)
+	}
+	return roles
+}
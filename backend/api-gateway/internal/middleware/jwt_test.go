@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+func TestJWTMiddleware(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPath := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sign := func(claims jwt.MapClaims) string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	valid := sign(jwt.MapClaims{"sub": "u1", "role": "admin", "sid": "s1", "exp": time.Now().Add(time.Hour).Unix()})
+	expired := sign(jwt.MapClaims{"sub": "u1", "exp": time.Now().Add(-time.Hour).Unix()})
+
+	j, err := NewJWTMiddleware(pubPath, NewIdentitySigner("secret"), "access_token", zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Use(j.Handler())
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(c.Locals("user_id").(string) + " " + c.Locals("session_id").(string))
+	})
+
+	tests := []struct {
+		name       string
+		auth       string
+		cookie     string
+		wantStatus int
+	}{
+		{name: "bearer token", auth: "Bearer " + valid, wantStatus: 200},
+		{name: "access cookie", cookie: valid, wantStatus: 200},
+		{name: "header wins over cookie", auth: "Bearer " + expired, cookie: valid, wantStatus: 401},
+		{name: "malformed header", auth: "Token " + valid, cookie: valid, wantStatus: 401},
+		{name: "expired cookie", cookie: expired, wantStatus: 401},
+		{name: "no credentials", wantStatus: 401},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "access_token", Value: tt.cookie})
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == 200 {
+				body, _ := io.ReadAll(resp.Body)
+				if string(body) != "u1 s1" {
+					t.Fatalf("identity = %q, want %q", body, "u1 s1")
+				}
+			}
+		})
+	}
+}
+
+func TestJWTMiddlewareCookieDisabled(t *testing.T) {
+	j := &JWTMiddleware{signer: NewIdentitySigner("secret"), log: zap.NewNop()}
+	app := fiber.New()
+	app.Use(j.Handler())
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: "anything"})
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401 when cookie sessions are off", resp.StatusCode)
+	}
+}
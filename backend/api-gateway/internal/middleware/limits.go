@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fathima-sithara/api-gateway/internal/config"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type routeLimit struct {
+	prefix string
+	bytes  int
+}
+
+// BodyLimit rejects bodies larger than the limit of the longest matching prefix in
+// cfg.BodyLimits, or cfg.MaxBodyBytes for other routes. The server-wide limit
+// (MaxBodyLimit) must be at least as large, since fasthttp enforces it while
+// reading and before any handler runs.
+func BodyLimit(cfg config.LimitsConfig) fiber.Handler {
+	var limits []routeLimit
+	for prefix, n := range cfg.BodyLimits {
+		limits = append(limits, routeLimit{prefix: prefix, bytes: n})
+	}
+	// longest prefix wins
+	sort.Slice(limits, func(i, j int) bool { return len(limits[i].prefix) > len(limits[j].prefix) })
+
+	return func(c *fiber.Ctx) error {
+		max := cfg.MaxBodyBytes
+		for _, l := range limits {
+			if strings.HasPrefix(c.Path(), l.prefix) {
+				max = l.bytes
+				break
+			}
+		}
+		if n := c.Request().Header.ContentLength(); n > max || len(c.Body()) > max {
+			return WriteProblem(c, Problem{
+				Title:  "Request body too large",
+				Status: fiber.StatusRequestEntityTooLarge,
+				Detail: "this endpoint accepts at most " + strconv.Itoa(max) + " bytes",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// MaxBodyLimit is the largest body any route accepts, for fiber.Config.BodyLimit.
+func MaxBodyLimit(cfg config.LimitsConfig) int {
+	max := cfg.MaxBodyBytes
+	for _, n := range cfg.BodyLimits {
+		if n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+// ConcurrencyLimiter caps the requests served at once. Requests over the cap wait in
+// a bounded queue for up to cfg.QueueTimeout; when the queue is full or the wait
+// runs out the client gets a 503 with Retry-After instead of piling up.
+func ConcurrencyLimiter(cfg config.LimitsConfig, logger *zap.Logger) fiber.Handler {
+	if cfg.MaxConcurrent <= 0 {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	slots := make(chan struct{}, cfg.MaxConcurrent)
+	var queued atomic.Int64
+
+	overloaded := func(c *fiber.Ctx, reason string) error {
+		logger.Warn("gateway overloaded", append(LogFields(c), zap.String("reason", reason), zap.String("path", c.Path()))...)
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return WriteProblem(c, Problem{
+			Title:  "Service overloaded",
+			Status: fiber.StatusServiceUnavailable,
+			Detail: "the gateway is handling too many requests, retry shortly",
+		})
+	}
+
+	return func(c *fiber.Ctx) error {
+		select {
+		case slots <- struct{}{}:
+		default:
+			if queued.Add(1) > int64(cfg.MaxQueued) {
+				queued.Add(-1)
+				return overloaded(c, "queue full")
+			}
+			timer := time.NewTimer(cfg.QueueTimeout)
+			select {
+			case slots <- struct{}{}:
+				timer.Stop()
+				queued.Add(-1)
+			case <-timer.C:
+				queued.Add(-1)
+				return overloaded(c, "queue timeout")
+			}
+		}
+		defer func() { <-slots }()
+		return c.Next()
+	}
+}
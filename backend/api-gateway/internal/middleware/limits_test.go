@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fathima-sithara/api-gateway/internal/config"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+func TestBodyLimit(t *testing.T) {
+	cfg := config.LimitsConfig{
+		MaxBodyBytes: 10,
+		BodyLimits: map[string]int{
+			"/api/v1/media":        100,
+			"/api/v1/media/avatar": 20,
+		},
+	}
+	// a roomy server limit so the middleware, not fasthttp, answers every case
+	app := fiber.New(fiber.Config{BodyLimit: 1 << 20})
+	app.Use(BodyLimit(cfg))
+	app.Post("/*", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	tests := []struct {
+		name       string
+		path       string
+		size       int
+		wantStatus int
+	}{
+		{name: "default limit", path: "/api/v1/chat", size: 10, wantStatus: 200},
+		{name: "over the default limit", path: "/api/v1/chat", size: 11, wantStatus: 413},
+		{name: "route limit", path: "/api/v1/media/upload", size: 100, wantStatus: 200},
+		{name: "over the route limit", path: "/api/v1/media/upload", size: 101, wantStatus: 413},
+		{name: "longest prefix wins", path: "/api/v1/media/avatar", size: 21, wantStatus: 413},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(strings.Repeat("x", tt.size)))
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+
+	if got := MaxBodyLimit(cfg); got != 100 {
+		t.Fatalf("MaxBodyLimit = %d, want 100", got)
+	}
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxQueued int
+		timeout   time.Duration
+		// releaseAfter frees the busy slot while the second request waits
+		releaseAfter time.Duration
+		wantStatus   int
+	}{
+		{name: "queued request gets the freed slot", maxQueued: 1, timeout: 2 * time.Second, releaseAfter: 20 * time.Millisecond, wantStatus: 200},
+		{name: "queue wait runs out", maxQueued: 1, timeout: 20 * time.Millisecond, releaseAfter: time.Second, wantStatus: 503},
+		{name: "no room in the queue", maxQueued: 0, timeout: 2 * time.Second, releaseAfter: time.Second, wantStatus: 503},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.LimitsConfig{MaxConcurrent: 1, MaxQueued: tt.maxQueued, QueueTimeout: tt.timeout}
+			entered := make(chan struct{}, 2)
+			release := make(chan struct{})
+			app := fiber.New()
+			app.Use(ConcurrencyLimiter(cfg, zap.NewNop()))
+			app.Get("/", func(c *fiber.Ctx) error {
+				entered <- struct{}{}
+				<-release
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			busy := make(chan int, 1)
+			go func() {
+				resp, err := app.Test(httptest.NewRequest("GET", "/", nil), -1)
+				if err != nil {
+					t.Error(err)
+					busy <- 0
+					return
+				}
+				busy <- resp.StatusCode
+			}()
+			<-entered
+
+			time.AfterFunc(tt.releaseAfter, func() { close(release) })
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil), -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == 503 && resp.Header.Get(fiber.HeaderRetryAfter) != "1" {
+				t.Fatalf("Retry-After = %q, want 1", resp.Header.Get(fiber.HeaderRetryAfter))
+			}
+			if got := <-busy; got != 200 {
+				t.Fatalf("first request status = %d, want 200", got)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimiterDisabled(t *testing.T) {
+	app := fiber.New()
+	app.Use(ConcurrencyLimiter(config.LimitsConfig{}, zap.NewNop()))
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("Test = (%v, %v), want 200", resp, err)
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// Problem is an RFC 9457 problem details body.
+type Problem struct {
+	Type      string         `json:"type"`
+	Title     string         `json:"title"`
+	Status    int            `json:"status"`
+	Detail    string         `json:"detail,omitempty"`
+	Instance  string         `json:"instance,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	Errors    []ProblemField `json:"errors,omitempty"`
+}
+
+// ProblemField points at one invalid part of the request.
+type ProblemField struct {
+	// In is "path", "query", "header" or "body".
+	In string `json:"in"`
+	// Field is the parameter name or a JSON pointer into the body.
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// WriteProblem sends p as application/problem+json, filling in the request path and id.
+func WriteProblem(c *fiber.Ctx, p Problem) error {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Instance == "" {
+		p.Instance = c.Path()
+	}
+	if p.RequestID == "" {
+		p.RequestID, _ = c.Locals("request_id").(string)
+	}
+	return c.Status(p.Status).JSON(p, "application/problem+json")
+}
+
+// ErrorHandler renders errors that escape the handler chain, including the 408,
+// 413 and 431 answers fiber produces for slow or oversized requests, as problems.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		status = fe.Code
+	}
+	p := Problem{Title: utils.StatusMessage(status), Status: status}
+	if fe != nil && fe.Message != p.Title {
+		p.Detail = fe.Message
+	}
+	return WriteProblem(c, p)
+}
@@ -1,77 +1,233 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
+	"math"
 	"net"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/fathima-sithara/api-gateway/internal/config"
 	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
-	"golang.org/x/time/rate"
 )
 
-type IPRateLimiter struct {
-	visitors sync.Map
-	rps      rate.Limit
-	burst    int
+// RateLimiter enforces GCRA (generic cell rate algorithm) limits per client and route.
+// Clients are keyed by user id once authenticated and by IP otherwise. State lives in
+// Redis when a client is configured so every gateway replica shares the same buckets.
+type RateLimiter struct {
+	store    limitStore
+	def      config.RatePolicy
+	policies []routePolicy
 	log      *zap.Logger
 }
 
-type visitor struct {
-	limiter  *rate.Limiter
-	lastSeen time.Time
+type routePolicy struct {
+	prefix string
+	policy config.RatePolicy
 }
 
-func NewIPRateLimiter(perMinute int, logger *zap.Logger) *IPRateLimiter {
-	rps := rate.Limit(float64(perMinute) / 60.0)
-	l := &IPRateLimiter{
-		visitors: sync.Map{},
-		rps:      rps,
-		burst:    5,
-		log:      logger,
-	}
-	go l.cleanupVisitors()
-	return l
+// limitResult is the outcome of a single GCRA check.
+type limitResult struct {
+	allowed    bool
+	remaining  int
+	resetAfter time.Duration
+	retryAfter time.Duration
+}
+
+type limitStore interface {
+	take(ctx context.Context, key string, p config.RatePolicy) (limitResult, error)
 }
 
-func (l *IPRateLimiter) getLimiter(ip string) *rate.Limiter {
-	v, ok := l.visitors.Load(ip)
-	if ok {
-		vi := v.(*visitor)
-		vi.lastSeen = time.Now()
-		return vi.limiter
+func NewRateLimiter(cfg *config.Config, rdb *redis.Client, logger *zap.Logger) *RateLimiter {
+	def := config.RatePolicy{Limit: cfg.RateLimitPerMin, Period: time.Minute, Burst: cfg.RateLimitBurst}
+	if def.Burst <= 0 {
+		def.Burst = 1
+	}
+
+	var policies []routePolicy
+	for prefix, p := range cfg.RatePolicies {
+		policies = append(policies, routePolicy{prefix: prefix, policy: p})
+	}
+	// longest prefix wins
+	sort.Slice(policies, func(i, j int) bool { return len(policies[i].prefix) > len(policies[j].prefix) })
+
+	var st limitStore
+	if rdb != nil {
+		st = &redisStore{rdb: rdb}
+	} else {
+		logger.Warn("rate limiter using in-process store; limits are not shared between replicas")
+		st = newMemoryStore()
 	}
-	lim := rate.NewLimiter(l.rps, l.burst)
-	l.visitors.Store(ip, &visitor{limiter: lim, lastSeen: time.Now()})
-	return lim
+
+	return &RateLimiter{store: st, def: def, policies: policies, log: logger}
 }
 
-func (l *IPRateLimiter) cleanupVisitors() {
-	for {
-		time.Sleep(time.Minute)
-		cutoff := time.Now().Add(-5 * time.Minute)
-		l.visitors.Range(func(k, v interface{}) bool {
-			vi := v.(*visitor)
-			if vi.lastSeen.Before(cutoff) {
-				l.visitors.Delete(k)
-			}
-			return true
-		})
+func (l *RateLimiter) policyFor(path string) (string, config.RatePolicy) {
+	for _, rp := range l.policies {
+		if strings.HasPrefix(path, rp.prefix) {
+			return rp.prefix, rp.policy
+		}
 	}
+	return "default", l.def
 }
 
-func (l *IPRateLimiter) Handler() fiber.Handler {
+func (l *RateLimiter) Handler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ip := getIP(c)
-		limiter := l.getLimiter(ip)
-		if !limiter.Allow() {
-			l.log.Warn("rate limit exceeded", zap.String("ip", ip), zap.String("path", c.Path()))
+		name, p := l.policyFor(c.Path())
+
+		subject := "ip:" + getIP(c)
+		if uid, ok := c.Locals("user_id").(string); ok && uid != "" {
+			subject = "user:" + uid
+		}
+		key := "rl:" + name + ":" + subject
+
+		res, err := l.store.take(c.UserContext(), key, p)
+		if err != nil {
+			// fail open: an unavailable limiter store should not take the gateway down
+			l.log.Error("rate limiter store error", append(LogFields(c), zap.Error(err), zap.String("key", key))...)
+			return c.Next()
+		}
+
+		c.Set("RateLimit-Limit", strconv.Itoa(p.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(res.remaining))
+		c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.resetAfter)))
+		c.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", p.Limit, ceilSeconds(p.Period), p.Burst))
+
+		if !res.allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(res.retryAfter)))
+			l.log.Warn("rate limit exceeded", append(LogFields(c), zap.String("subject", subject), zap.String("policy", name), zap.String("path", c.Path()))...)
 			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "rate limit exceeded"})
 		}
 		return c.Next()
 	}
 }
 
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// gcra computes the new theoretical arrival time (tat) for a request at now.
+// All values are in microseconds.
+func gcra(now, tat, interval, tolerance int64) (newTat int64, res limitResult) {
+	if tat < now {
+		tat = now
+	}
+	newTat = tat + interval
+	allowAt := newTat - tolerance
+	if now < allowAt {
+		return tat, limitResult{
+			allowed:    false,
+			remaining:  0,
+			resetAfter: time.Duration(tat-now) * time.Microsecond,
+			retryAfter: time.Duration(allowAt-now) * time.Microsecond,
+		}
+	}
+	return newTat, limitResult{
+		allowed:    true,
+		remaining:  int((now - allowAt) / interval),
+		resetAfter: time.Duration(newTat-now) * time.Microsecond,
+	}
+}
+
+func gcraParams(p config.RatePolicy) (interval, tolerance int64) {
+	limit := int64(p.Limit)
+	if limit < 1 {
+		// LoadFromEnv rejects such policies; never divide by zero if one slips through
+		limit = 1
+	}
+	interval = p.Period.Microseconds() / limit
+	if interval <= 0 {
+		interval = 1
+	}
+	return interval, interval * int64(p.Burst)
+}
+
+// gcraScript runs the same algorithm as gcra atomically inside Redis, using the
+// Redis clock so replicas with skewed clocks still agree.
+var gcraScript = redis.NewScript(`
+local key = KEYS[1]
+local interval = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local tat = tonumber(redis.call("GET", key) or now)
+if tat < now then tat = now end
+local new_tat = tat + interval
+local allow_at = new_tat - tolerance
+if now < allow_at then
+  return {0, 0, tat - now, allow_at - now}
+end
+redis.call("SET", key, new_tat, "PX", math.ceil((new_tat - now) / 1000))
+return {1, math.floor((now - allow_at) / interval), new_tat - now, 0}
+`)
+
+type redisStore struct {
+	rdb *redis.Client
+}
+
+func (s *redisStore) take(ctx context.Context, key string, p config.RatePolicy) (limitResult, error) {
+	interval, tolerance := gcraParams(p)
+	vals, err := gcraScript.Run(ctx, s.rdb, []string{key}, interval, tolerance).Int64Slice()
+	if err != nil {
+		return limitResult{}, err
+	}
+	if len(vals) != 4 {
+		return limitResult{}, fmt.Errorf("unexpected gcra reply: %v", vals)
+	}
+	return limitResult{
+		allowed:    vals[0] == 1,
+		remaining:  int(vals[1]),
+		resetAfter: time.Duration(vals[2]) * time.Microsecond,
+		retryAfter: time.Duration(vals[3]) * time.Microsecond,
+	}, nil
+}
+
+// memoryStore is the single-replica fallback used when no Redis is configured.
+type memoryStore struct {
+	mu  sync.Mutex
+	tat map[string]int64
+}
+
+func newMemoryStore() *memoryStore {
+	s := &memoryStore{tat: map[string]int64{}}
+	go s.cleanup()
+	return s
+}
+
+func (s *memoryStore) take(_ context.Context, key string, p config.RatePolicy) (limitResult, error) {
+	interval, tolerance := gcraParams(p)
+	now := time.Now().UnixMicro()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	newTat, res := gcra(now, s.tat[key], interval, tolerance)
+	s.tat[key] = newTat
+	return res, nil
+}
+
+func (s *memoryStore) cleanup() {
+	for {
+		time.Sleep(time.Minute)
+		now := time.Now().UnixMicro()
+		s.mu.Lock()
+		for k, tat := range s.tat {
+			if tat < now {
+				delete(s.tat, k)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
 func getIP(c *fiber.Ctx) string {
 	ip := c.IP()
 	if ip == "" {
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fathima-sithara/api-gateway/internal/config"
+)
+
+func TestGCRAParams(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        config.RatePolicy
+		wantInterval  int64
+		wantTolerance int64
+	}{
+		{"ten a second with burst", config.RatePolicy{Limit: 10, Period: time.Second, Burst: 2}, 100_000, 200_000},
+		{"one a second without burst", config.RatePolicy{Limit: 60, Period: time.Minute}, 1_000_000, 0},
+		{"interval rounds up to a microsecond", config.RatePolicy{Limit: 10, Period: time.Microsecond, Burst: 3}, 1, 3},
+		{"zero limit admits one a period", config.RatePolicy{Limit: 0, Period: time.Minute, Burst: 1}, 60_000_000, 60_000_000},
+		{"negative limit admits one a period", config.RatePolicy{Limit: -3, Period: time.Second}, 1_000_000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interval, tolerance := gcraParams(tt.policy)
+			if interval != tt.wantInterval || tolerance != tt.wantTolerance {
+				t.Fatalf("gcraParams = (%d, %d), want (%d, %d)", interval, tolerance, tt.wantInterval, tt.wantTolerance)
+			}
+		})
+	}
+}
+
+func TestGCRA(t *testing.T) {
+	const interval, tolerance = 100_000, 200_000 // ten a second, burst of two
+
+	type step struct {
+		now        int64
+		allowed    bool
+		remaining  int
+		resetAfter time.Duration
+		retryAfter time.Duration
+	}
+	tests := []struct {
+		name  string
+		tat   int64
+		steps []step
+	}{
+		{
+			name: "burst then denied",
+			steps: []step{
+				{now: 0, allowed: true, remaining: 1, resetAfter: 100 * time.Millisecond},
+				{now: 0, allowed: true, remaining: 0, resetAfter: 200 * time.Millisecond},
+				{now: 0, allowed: false, resetAfter: 200 * time.Millisecond, retryAfter: 100 * time.Millisecond},
+			},
+		},
+		{
+			name: "one interval later frees one request",
+			tat:  300_000,
+			steps: []step{
+				{now: 100_000, allowed: false, resetAfter: 200 * time.Millisecond, retryAfter: 100 * time.Millisecond},
+				{now: 200_000, allowed: true, remaining: 0, resetAfter: 200 * time.Millisecond},
+			},
+		},
+		{
+			name: "a stale tat starts from now",
+			tat:  0,
+			steps: []step{
+				{now: 5_000_000, allowed: true, remaining: 1, resetAfter: 100 * time.Millisecond},
+			},
+		},
+		{
+			name: "steady rate is never denied",
+			steps: []step{
+				{now: 0, allowed: true, remaining: 1, resetAfter: 100 * time.Millisecond},
+				{now: 100_000, allowed: true, remaining: 1, resetAfter: 100 * time.Millisecond},
+				{now: 200_000, allowed: true, remaining: 1, resetAfter: 100 * time.Millisecond},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tat := tt.tat
+			for i, s := range tt.steps {
+				var res limitResult
+				tat, res = gcra(s.now, tat, interval, tolerance)
+				if res.allowed != s.allowed || res.remaining != s.remaining || res.resetAfter != s.resetAfter || res.retryAfter != s.retryAfter {
+					t.Fatalf("step %d: got %+v, want %+v", i, res, s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fathima-sithara/api-gateway/internal/config"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+// CORS answers preflights and sets CORS headers for the configured origins. It is
+// the only CORS policy in the system; services behind the gateway do not add their own.
+func CORS(cfg config.SecurityConfig) fiber.Handler {
+	cc := cors.Config{
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:     strings.Join([]string{fiber.HeaderOrigin, fiber.HeaderContentType, fiber.HeaderAccept, fiber.HeaderAuthorization, fiber.HeaderIfNoneMatch, "X-Request-ID", "traceparent", "X-Auth-Mode", HeaderIdempotencyKey, cfg.CSRFHeader}, ","),
+		ExposeHeaders:    strings.Join([]string{"X-Request-ID", fiber.HeaderETag, fiber.HeaderRetryAfter, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "X-Cache", "X-API-Version", HeaderIdempotentReplayed, cfg.CSRFHeader}, ","),
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           int(cfg.PreflightMaxAge / time.Second),
+	}
+	if len(cfg.AllowedOrigins) > 0 {
+		cc.AllowOrigins = strings.Join(cfg.AllowedOrigins, ",")
+	} else {
+		// no browser origin is trusted
+		cc.AllowOriginsFunc = func(string) bool { return false }
+	}
+	return cors.New(cc)
+}
+
+// SecurityHeaders hardens every response. The gateway only serves JSON, so the
+// content security policy forbids everything.
+func SecurityHeaders(cfg config.SecurityConfig) fiber.Handler {
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge/time.Second)) + "; includeSubDomains"
+	}
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+		c.Set(fiber.HeaderXFrameOptions, cfg.FrameOptions)
+		c.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+		c.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; frame-ancestors 'none'")
+		if hsts != "" {
+			c.Set(fiber.HeaderStrictTransportSecurity, hsts)
+		}
+		return c.Next()
+	}
+}
+
+// CSRF implements double-submit protection for cookie authenticated clients. Every
+// response makes sure the client holds a token cookie and echoes the token in the
+// CSRF header, since a frontend on another origin cannot read the cookie itself.
+// Unsafe requests that authenticate with a cookie instead of an Authorization
+// header must send the same token back in that header.
+func CSRF(cfg config.SecurityConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token := c.Cookies(cfg.CSRFCookie)
+
+		if !isSafeMethod(c.Method()) && c.Get(fiber.HeaderAuthorization) == "" && hasAuthCookie(c, cfg.AuthCookies) {
+			sent := c.Get(cfg.CSRFHeader)
+			if token == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+				return WriteProblem(c, Problem{
+					Type:   "/problems/csrf",
+					Title:  "CSRF token missing or invalid",
+					Status: fiber.StatusForbidden,
+					Detail: "send the " + cfg.CSRFCookie + " cookie value in the " + cfg.CSRFHeader + " header",
+				})
+			}
+		}
+
+		if token == "" {
+			token = newCSRFToken()
+			c.Cookie(&fiber.Cookie{
+				Name:     cfg.CSRFCookie,
+				Value:    token,
+				Path:     "/",
+				Secure:   cfg.CookieSecure,
+				SameSite: fiber.CookieSameSiteLaxMode,
+			})
+		}
+		c.Set(cfg.CSRFHeader, token)
+		return c.Next()
+	}
+}
+
+func isSafeMethod(m string) bool {
+	return m == fiber.MethodGet || m == fiber.MethodHead || m == fiber.MethodOptions
+}
+
+func hasAuthCookie(c *fiber.Ctx, names []string) bool {
+	for _, n := range names {
+		if c.Cookies(n) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func newCSRFToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
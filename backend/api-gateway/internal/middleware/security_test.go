@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fathima-sithara/api-gateway/internal/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCSRF(t *testing.T) {
+	cfg := config.SecurityConfig{
+		CSRFCookie:  "csrf_token",
+		CSRFHeader:  "X-CSRF-Token",
+		AuthCookies: []string{"access_token"},
+	}
+	app := fiber.New()
+	app.Use(CSRF(cfg))
+	app.All("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	tests := []struct {
+		name       string
+		method     string
+		cookies    map[string]string
+		headers    map[string]string
+		wantStatus int
+		// wantToken is the token echoed back; "new" means a freshly issued one
+		wantToken string
+	}{
+		{name: "safe request gets a token", method: "GET", wantStatus: 200, wantToken: "new"},
+		{name: "existing token is echoed", method: "GET", cookies: map[string]string{"csrf_token": "t1"}, wantStatus: 200, wantToken: "t1"},
+		{name: "anonymous write", method: "POST", wantStatus: 200, wantToken: "new"},
+		{name: "bearer write needs no token", method: "POST", cookies: map[string]string{"access_token": "a"}, headers: map[string]string{"Authorization": "Bearer a"}, wantStatus: 200, wantToken: "new"},
+		{name: "cookie write without header", method: "POST", cookies: map[string]string{"access_token": "a", "csrf_token": "t1"}, wantStatus: 403},
+		{name: "cookie write with wrong header", method: "DELETE", cookies: map[string]string{"access_token": "a", "csrf_token": "t1"}, headers: map[string]string{"X-CSRF-Token": "t2"}, wantStatus: 403},
+		{name: "cookie write without token cookie", method: "PUT", cookies: map[string]string{"access_token": "a"}, headers: map[string]string{"X-CSRF-Token": ""}, wantStatus: 403},
+		{name: "cookie write with matching header", method: "PATCH", cookies: map[string]string{"access_token": "a", "csrf_token": "t1"}, headers: map[string]string{"X-CSRF-Token": "t1"}, wantStatus: 200, wantToken: "t1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			var cookies []string
+			for k, v := range tt.cookies {
+				cookies = append(cookies, k+"="+v)
+			}
+			if len(cookies) > 0 {
+				req.Header.Set("Cookie", strings.Join(cookies, "; "))
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != 200 {
+				return
+			}
+			got := resp.Header.Get("X-CSRF-Token")
+			switch tt.wantToken {
+			case "new":
+				if got == "" || !strings.Contains(resp.Header.Get("Set-Cookie"), "csrf_token="+got) {
+					t.Fatalf("want a new token set as cookie and header, got header %q, Set-Cookie %q", got, resp.Header.Get("Set-Cookie"))
+				}
+			default:
+				if got != tt.wantToken || resp.Header.Get("Set-Cookie") != "" {
+					t.Fatalf("header = %q, Set-Cookie = %q; want %q and no new cookie", got, resp.Header.Get("Set-Cookie"), tt.wantToken)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/fathima-sithara/platform/reqctx"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Correlation headers forwarded to every upstream service.
+const (
+	HeaderRequestID   = "X-Request-ID"
+	HeaderTraceparent = "traceparent"
+)
+
+// RequestContext accepts or generates X-Request-ID and a W3C traceparent. The gateway
+// is a hop of its own, so an incoming trace is continued with a new span id.
+func RequestContext() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		rid := c.Get(HeaderRequestID)
+		if rid == "" || len(rid) > 128 {
+			rid = uuid.NewString()
+		}
+
+		traceID, flags := randomHex(16), "01"
+		if parts, ok := parseTraceparent(c.Get(HeaderTraceparent)); ok {
+			traceID, flags = parts[1], parts[3]
+		}
+		tp := "00-" + traceID + "-" + randomHex(8) + "-" + flags
+
+		c.Request().Header.Set(HeaderRequestID, rid)
+		c.Request().Header.Set(HeaderTraceparent, tp)
+		c.Set(HeaderRequestID, rid)
+		c.Locals("request_id", rid)
+		c.Locals("trace_id", traceID)
+		c.Locals("traceparent", tp)
+		c.SetUserContext(reqctx.NewContext(c.UserContext(), reqctx.Info{RequestID: rid, Traceparent: tp}))
+		return c.Next()
+	}
+}
+
+// ForwardSpan points the traceparent sent upstream at the gateway's server span,
+// so upstream services see it as their parent. It must run after
+// observability.Middleware, which moves the reqctx traceparent to that span.
+func ForwardSpan() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if tp := reqctx.FromContext(c.UserContext()).Traceparent; tp != "" {
+			c.Request().Header.Set(HeaderTraceparent, tp)
+			c.Locals("traceparent", tp)
+		}
+		return c.Next()
+	}
+}
+
+// AccessLog writes one zap line per request, tagged with the correlation ids.
+func AccessLog(logger *zap.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+		fields := append(LogFields(c),
+			zap.String("method", c.Method()),
+			zap.String("path", c.Path()),
+			zap.Int("status", c.Response().StatusCode()),
+			zap.Duration("latency", time.Since(start)),
+		)
+		if err != nil {
+			logger.Error("request failed", append(fields, zap.Error(err))...)
+			return err
+		}
+		logger.Info("request", fields...)
+		return nil
+	}
+}
+
+// LogFields returns the correlation fields to attach to any log line about c.
+func LogFields(c *fiber.Ctx) []zap.Field {
+	rid, _ := c.Locals("request_id").(string)
+	tid, _ := c.Locals("trace_id").(string)
+	return []zap.Field{zap.String("request_id", rid), zap.String("trace_id", tid)}
+}
+
+// parseTraceparent validates the version-00 layout: 00-<32 hex>-<16 hex>-<2 hex>.
+func parseTraceparent(tp string) ([]string, bool) {
+	parts := strings.Split(tp, "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return nil, false
+	}
+	for _, p := range parts[1:] {
+		if _, err := hex.DecodeString(p); err != nil {
+			return nil, false
+		}
+	}
+	if parts[1] == strings.Repeat("0", 32) || parts[2] == strings.Repeat("0", 16) {
+		return nil, false
+	}
+	return parts, true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRequestContext(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const incoming = "00-" + traceID + "-00f067aa0ba902b7-00"
+	tests := []struct {
+		name        string
+		requestID   string
+		traceparent string
+		wantTraceID string // "" means a fresh trace
+		wantFlags   string
+	}{
+		{name: "continues the caller's trace", requestID: "req-1", traceparent: incoming, wantTraceID: traceID, wantFlags: "00"},
+		{name: "starts a sampled trace", wantFlags: "01"},
+		{name: "ignores a malformed trace", requestID: "req-1", traceparent: "00-" + traceID + "-short-01", wantFlags: "01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var upRID, upTP, localTID string
+			app := fiber.New()
+			app.Use(RequestContext())
+			app.Get("/", func(c *fiber.Ctx) error {
+				// what the proxy forwards upstream
+				upRID, upTP = c.Get(HeaderRequestID), c.Get(HeaderTraceparent)
+				localTID, _ = c.Locals("trace_id").(string)
+				return nil
+			})
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.requestID != "" {
+				req.Header.Set(HeaderRequestID, tt.requestID)
+			}
+			if tt.traceparent != "" {
+				req.Header.Set(HeaderTraceparent, tt.traceparent)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.requestID != "" && upRID != tt.requestID {
+				t.Fatalf("forwarded request id = %q, want %q", upRID, tt.requestID)
+			}
+			if upRID == "" || resp.Header.Get(HeaderRequestID) != upRID {
+				t.Fatalf("echoed request id = %q, forwarded %q", resp.Header.Get(HeaderRequestID), upRID)
+			}
+			parts, ok := parseTraceparent(upTP)
+			if !ok {
+				t.Fatalf("forwarded traceparent %q is invalid", upTP)
+			}
+			if tt.wantTraceID != "" && parts[1] != tt.wantTraceID {
+				t.Fatalf("trace id = %q, want %q", parts[1], tt.wantTraceID)
+			}
+			if tt.wantTraceID == "" && strings.Contains(tt.traceparent, parts[1]) {
+				t.Fatalf("trace id %q reused from a rejected traceparent", parts[1])
+			}
+			if upTP == tt.traceparent {
+				t.Fatal("gateway forwarded the caller's span id instead of its own")
+			}
+			if parts[3] != tt.wantFlags || localTID != parts[1] {
+				t.Fatalf("flags = %q, trace_id local = %q; want %q, %q", parts[3], localTID, tt.wantFlags, parts[1])
+			}
+		})
+	}
+}
+
+func TestForwardSpan(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	defer otel.SetTracerProvider(prev)
+
+	var incoming, upstream string
+	app := fiber.New()
+	app.Use(RequestContext())
+	app.Use(func(c *fiber.Ctx) error {
+		// fiber reuses the header buffer, which ForwardSpan overwrites
+		incoming = strings.Clone(c.Get(HeaderTraceparent))
+		return c.Next()
+	})
+	app.Use(observability.Middleware())
+	app.Use(ForwardSpan())
+	app.Get("/", func(c *fiber.Ctx) error {
+		upstream = c.Get(HeaderTraceparent)
+		return nil
+	})
+	if _, err := app.Test(httptest.NewRequest("GET", "/", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want the gateway's server span", len(spans))
+	}
+	sc := spans[0].SpanContext()
+	want := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-" + sc.TraceFlags().String()
+	if upstream != want {
+		t.Fatalf("upstream traceparent = %q, want the server span %q", upstream, want)
+	}
+	if parent := spans[0].Parent(); parent.SpanID().String() != strings.Split(incoming, "-")[2] {
+		t.Fatalf("server span parent = %s, want RequestContext's span from %q", parent.SpanID(), incoming)
+	}
+}
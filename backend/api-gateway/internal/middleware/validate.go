@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fathima-sithara/api-gateway/internal/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// ValidateRequest rejects requests that do not match the OpenAPI spec before they
+// are proxied, so services never see missing or malformed fields.
+func ValidateRequest(spec *openapi.Spec) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var r http.Request
+		if err := fasthttpadaptor.ConvertRequest(c.Context(), &r, false); err != nil {
+			return WriteProblem(c, Problem{Title: "Bad Request", Status: fiber.StatusBadRequest, Detail: err.Error()})
+		}
+		r.Body = io.NopCloser(bytes.NewReader(c.Body()))
+
+		err := spec.Validate(c.UserContext(), &r)
+		switch {
+		case err == nil:
+			return c.Next()
+		case errors.Is(err, routers.ErrPathNotFound):
+			return WriteProblem(c, Problem{Title: "Not Found", Status: fiber.StatusNotFound, Detail: "no such operation"})
+		case errors.Is(err, routers.ErrMethodNotAllowed):
+			return WriteProblem(c, Problem{Title: "Method Not Allowed", Status: fiber.StatusMethodNotAllowed})
+		}
+
+		return WriteProblem(c, Problem{
+			Type:   "/problems/validation",
+			Title:  "Request validation failed",
+			Status: fiber.StatusBadRequest,
+			Errors: validationFields(err),
+		})
+	}
+}
+
+// validationFields flattens kin-openapi errors into one entry per invalid field.
+func validationFields(err error) []ProblemField {
+	var multi openapi3.MultiError
+	if !errors.As(err, &multi) {
+		multi = openapi3.MultiError{err}
+	}
+
+	var out []ProblemField
+	for _, e := range multi {
+		var re *openapi3filter.RequestError
+		if !errors.As(e, &re) {
+			out = append(out, ProblemField{In: "body", Message: e.Error()})
+			continue
+		}
+
+		f := ProblemField{In: "body", Message: re.Reason}
+		if re.Parameter != nil {
+			f.In, f.Field = re.Parameter.In, re.Parameter.Name
+		}
+
+		// schema errors may be nested in their own MultiError
+		var schemaErrs openapi3.MultiError
+		if errors.As(re.Err, &schemaErrs) {
+			for _, se := range schemaErrs {
+				out = append(out, schemaField(f, se))
+			}
+			continue
+		}
+		out = append(out, schemaField(f, re.Err))
+	}
+	return out
+}
+
+func schemaField(f ProblemField, err error) ProblemField {
+	var se *openapi3.SchemaError
+	if errors.As(err, &se) {
+		if ptr := se.JSONPointer(); len(ptr) > 0 && f.In == "body" {
+			f.Field = "/" + strings.Join(ptr, "/")
+		}
+		f.Message = se.Reason
+	} else if f.Message == "" && err != nil {
+		f.Message = err.Error()
+	}
+	return f
+}
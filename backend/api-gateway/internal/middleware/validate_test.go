@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fathima-sithara/api-gateway/internal/openapi"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestValidateRequestNotifications(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.All("/api/v1/notifications/*", ValidateRequest(spec), func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"own list", "GET", "/api/v1/notifications", 200},
+		{"another user's list", "GET", "/api/v1/notifications/victim", 404},
+		{"sending is internal", "POST", "/api/v1/notifications", 405},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"user_id":"victim","title":"t","message":"m"}`))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
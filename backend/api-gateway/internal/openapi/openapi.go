@@ -0,0 +1,165 @@
+// Package openapi embeds the public API contract of every upstream service and
+// validates gateway requests against it.
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+//go:embed specs/*.yaml
+var specFS embed.FS
+
+// Versions are the public API versions, each served under /api/<version>. v2 exposes
+// the same operations as v1 until an operation needs a breaking change; upstreams
+// can tell them apart by the X-API-Version header.
+var Versions = []string{"v1", "v2"}
+
+// Spec is the merged contract of all services.
+type Spec struct {
+	doc    *openapi3.T
+	router routers.Router
+	json   []byte
+}
+
+// Load parses and merges the embedded per-service specs. Paths in the specs carry
+// no version prefix; every version in Versions is declared as a server instead.
+func Load() (*Spec, error) {
+	merged := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info:    &openapi3.Info{Title: "Chat App API", Version: Versions[len(Versions)-1]},
+		Paths:   openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas:         openapi3.Schemas{},
+			Parameters:      openapi3.ParametersMap{},
+			RequestBodies:   openapi3.RequestBodies{},
+			Responses:       openapi3.ResponseBodies{},
+			SecuritySchemes: openapi3.SecuritySchemes{},
+		},
+	}
+	for _, v := range Versions {
+		merged.Servers = append(merged.Servers, &openapi3.Server{URL: "/api/" + v})
+	}
+
+	files, err := fs.Glob(specFS, "specs/*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	loader := openapi3.NewLoader()
+	for _, name := range files {
+		b, err := specFS.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := loader.LoadFromData(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if err := doc.Validate(loader.Context); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if err := merge(merged, doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if err := merged.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("merged spec: %w", err)
+	}
+
+	router, err := gorillamux.NewRouter(merged)
+	if err != nil {
+		return nil, err
+	}
+	js, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	return &Spec{doc: merged, router: router, json: js}, nil
+}
+
+// JSON returns the merged spec.
+func (s *Spec) JSON() []byte { return s.json }
+
+// Validate checks r against the operation it routes to. It returns
+// routers.ErrPathNotFound or routers.ErrMethodNotAllowed when no operation matches,
+// and an openapi3.MultiError of *openapi3filter.RequestError otherwise.
+func (s *Spec) Validate(ctx context.Context, r *http.Request) error {
+	route, params, err := s.router.FindRoute(r)
+	if err != nil {
+		return err
+	}
+	return openapi3filter.ValidateRequest(ctx, &openapi3filter.RequestValidationInput{
+		Request:    r,
+		PathParams: params,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			// the JWT middleware has already authenticated the caller
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			MultiError:         true,
+		},
+	})
+}
+
+// merge copies the paths and components of doc into dst. A document-level security
+// requirement is pushed down to its operations since dst has none of its own.
+func merge(dst, doc *openapi3.T) error {
+	for path, item := range doc.Paths.Map() {
+		if dst.Paths.Value(path) != nil {
+			return fmt.Errorf("path %s defined twice", path)
+		}
+		if doc.Security != nil {
+			for _, op := range item.Operations() {
+				if op.Security == nil {
+					sec := doc.Security
+					op.Security = &sec
+				}
+			}
+		}
+		dst.Paths.Set(path, item)
+	}
+
+	if doc.Components == nil {
+		return nil
+	}
+	c := dst.Components
+	if err := mergeComponents(c.Schemas, doc.Components.Schemas, "schema"); err != nil {
+		return err
+	}
+	if err := mergeComponents(c.Parameters, doc.Components.Parameters, "parameter"); err != nil {
+		return err
+	}
+	if err := mergeComponents(c.RequestBodies, doc.Components.RequestBodies, "request body"); err != nil {
+		return err
+	}
+	if err := mergeComponents(c.Responses, doc.Components.Responses, "response"); err != nil {
+		return err
+	}
+	return mergeComponents(c.SecuritySchemes, doc.Components.SecuritySchemes, "security scheme")
+}
+
+// mergeComponents adds src to dst. Services may share a component name only when
+// they define it identically, since $refs in the merged spec resolve by name.
+func mergeComponents[M ~map[string]V, V any](dst, src M, kind string) error {
+	for name, v := range src {
+		if cur, ok := dst[name]; ok {
+			a, _ := json.Marshal(cur)
+			b, _ := json.Marshal(v)
+			if !bytes.Equal(a, b) {
+				return fmt.Errorf("%s %q conflicts with another service's definition", kind, name)
+			}
+			continue
+		}
+		dst[name] = v
+	}
+	return nil
+}
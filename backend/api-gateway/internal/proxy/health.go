@@ -0,0 +1,163 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// instance is one address of an upstream service. Instances start out ready so
+// traffic flows before the first probe completes.
+type instance struct {
+	url   string
+	ready atomic.Bool
+
+	mu        sync.Mutex
+	lastErr   string
+	checkedAt time.Time
+}
+
+// InstanceStatus is the admin view of one upstream instance.
+type InstanceStatus struct {
+	URL       string     `json:"url"`
+	Ready     bool       `json:"ready"`
+	LastError string     `json:"last_error,omitempty"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
+
+func newInstance(url string) *instance {
+	i := &instance{url: url}
+	i.ready.Store(true)
+	return i
+}
+
+// setReady records a probe result and reports whether readiness changed.
+func (i *instance) setReady(ready bool, errMsg string) bool {
+	i.mu.Lock()
+	i.lastErr, i.checkedAt = errMsg, time.Now()
+	i.mu.Unlock()
+	return i.ready.Swap(ready) != ready
+}
+
+// pick returns the next ready instance in round-robin order, or nil if none is ready.
+func (u *upstream) pick() *instance {
+	n := len(u.instances)
+	start := u.next.Add(1)
+	for k := 0; k < n; k++ {
+		inst := u.instances[(start+uint64(k))%uint64(n)]
+		if inst.ready.Load() {
+			return inst
+		}
+	}
+	return nil
+}
+
+func (u *upstream) readyCount() int {
+	n := 0
+	for _, inst := range u.instances {
+		if inst.ready.Load() {
+			n++
+		}
+	}
+	return n
+}
+
+func (u *upstream) instanceStatus() []InstanceStatus {
+	out := make([]InstanceStatus, 0, len(u.instances))
+	for _, inst := range u.instances {
+		st := InstanceStatus{URL: inst.url, Ready: inst.ready.Load()}
+		inst.mu.Lock()
+		st.LastError = inst.lastErr
+		if !inst.checkedAt.IsZero() {
+			at := inst.checkedAt
+			st.CheckedAt = &at
+		}
+		inst.mu.Unlock()
+		out = append(out, st)
+	}
+	return out
+}
+
+// StartHealthChecks probes path on every upstream instance each interval until ctx
+// is cancelled. Only instances answering 2xx receive traffic.
+func (p *Proxy) StartHealthChecks(ctx context.Context, interval, timeout time.Duration, path string) {
+	client := &http.Client{Timeout: timeout}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			p.probeAll(ctx, client, path)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (p *Proxy) probeAll(ctx context.Context, client *http.Client, path string) {
+	p.mu.Lock()
+	ups := make([]*upstream, 0, len(p.upstreams))
+	for _, u := range p.upstreams {
+		ups = append(ups, u)
+	}
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, u := range ups {
+		for _, inst := range u.instances {
+			wg.Add(1)
+			go func(u *upstream, inst *instance) {
+				defer wg.Done()
+				err := probe(ctx, client, inst.url+path)
+				msg := ""
+				if err != nil {
+					msg = err.Error()
+				}
+				if inst.setReady(err == nil, msg) {
+					p.log.Info("upstream instance readiness changed", zap.String("upstream", u.name), zap.String("instance", inst.url), zap.Bool("ready", err == nil), zap.String("error", msg))
+				}
+			}(u, inst)
+		}
+	}
+	wg.Wait()
+}
+
+func probe(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Readiness summarises how many instances of each upstream are ready. The gateway
+// is ready when every upstream that is not disabled by an operator has at least one.
+func (p *Proxy) Readiness() (map[string]string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make(map[string]string, len(p.upstreams))
+	ready := true
+	for name, u := range p.upstreams {
+		n := u.readyCount()
+		out[name] = fmt.Sprintf("%d/%d ready", n, len(u.instances))
+		if n == 0 && u.currentState() == StateActive {
+			ready = false
+		}
+	}
+	return out, ready
+}
@@ -4,19 +4,80 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/fathima-sithara/api-gateway/internal/config"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/proxy"
+	"github.com/sony/gobreaker"
+	"github.com/valyala/fasthttp"
 	"go.uber.org/zap"
 )
 
+// Upstream states, changed at runtime through the admin API. A draining upstream
+// refuses new requests and becomes disabled once its in-flight requests finish.
+const (
+	StateActive   = "active"
+	StateDraining = "draining"
+	StateDisabled = "disabled"
+)
+
+// errUpstreamStatus marks a 5xx answer so the breaker counts it as a failure even
+// though the response itself is passed through to the client.
+var errUpstreamStatus = errors.New("upstream returned 5xx")
+
 // Proxy is a small wrapper that holds service mapping
 type Proxy struct {
 	services map[string]string
 	log      *zap.Logger
 	cfg      config.CircuitBreakerConfig
+	timeout  time.Duration
+	client   *http.Client
+
+	mu        sync.Mutex
+	upstreams map[string]*upstream
+	mounts    []Mount
+}
+
+// Mount records one public path prefix served by an upstream.
+type Mount struct {
+	Service        string `json:"service"`
+	PathPrefix     string `json:"path_prefix"`
+	UpstreamPrefix string `json:"upstream_prefix"`
+}
+
+type upstream struct {
+	name      string
+	instances []*instance
+	next      atomic.Uint64
+	breaker   *gobreaker.CircuitBreaker
+	inflight  atomic.Int64
+
+	mu        sync.Mutex
+	state     string
+	lastErr   string
+	lastErrAt time.Time
+}
+
+// UpstreamStatus is the admin view of one upstream.
+type UpstreamStatus struct {
+	Name                string           `json:"name"`
+	Instances           []InstanceStatus `json:"instances"`
+	State               string           `json:"state"`
+	Circuit             string           `json:"circuit"`
+	InFlight            int64            `json:"in_flight"`
+	Requests            uint32           `json:"requests"`
+	TotalFailures       uint32           `json:"total_failures"`
+	ConsecutiveFailures uint32           `json:"consecutive_failures"`
+	LastError           string           `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time       `json:"last_error_at,omitempty"`
 }
 
 func NewProxyFromEnv(cfg *config.Config) (*Proxy, error) {
@@ -25,56 +86,334 @@ func NewProxyFromEnv(cfg *config.Config) (*Proxy, error) {
 	}
 
 	p := &Proxy{
-		services: cfg.Services,
-		log:      zap.NewExample(),
-		cfg:      cfg.CircuitBreaker,
+		services:  cfg.Services,
+		log:       zap.NewExample(),
+		cfg:       cfg.CircuitBreaker,
+		timeout:   cfg.Limits.UpstreamTimeout,
+		client:    &http.Client{Timeout: cfg.Limits.UpstreamTimeout},
+		upstreams: map[string]*upstream{},
+	}
+
+	for name, targets := range cfg.Services {
+		var instances []*instance
+		for _, t := range strings.Split(targets, ",") {
+			t = strings.TrimRight(strings.TrimSpace(t), "/")
+			if t == "" {
+				continue
+			}
+			if _, err := url.Parse(t); err != nil {
+				return nil, fmt.Errorf("service %s: %w", name, err)
+			}
+			instances = append(instances, newInstance(t))
+		}
+		if len(instances) > 0 {
+			p.upstreams[name] = p.newUpstream(name, instances)
+		}
 	}
 
 	return p, nil
 }
 
-// Lookup returns target base URL for a service name
+// Lookup returns the base URL of a ready instance of a service
 func (p *Proxy) Lookup(service string) (string, error) {
-	if t, ok := p.services[service]; ok && t != "" {
-		return t, nil
+	p.mu.Lock()
+	u, ok := p.upstreams[service]
+	p.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("service not found: %s", service)
 	}
-	return "", fmt.Errorf("service not found: %s", service)
+	inst := u.pick()
+	if inst == nil {
+		return "", fmt.Errorf("no ready instance: %s", service)
+	}
+	return inst.url, nil
 }
 
-// Forward returns a fiber.Handler that proxies to serviceName and strips pathPrefix
-func (p *Proxy) Forward(serviceName string, pathPrefix string) (fiber.Handler, error) {
-	target, err := p.Lookup(serviceName)
-	if err != nil {
-		return nil, err
-	}
+// newUpstream creates the shared state for a service, so every route to the same
+// service trips the same breaker.
+func (p *Proxy) newUpstream(name string, instances []*instance) *upstream {
+	u := &upstream{name: name, instances: instances, state: StateActive}
+	u.breaker = gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:     name,
+		Interval: time.Duration(p.cfg.IntervalSec) * time.Second,
+		Timeout:  time.Duration(p.cfg.TimeoutSec) * time.Second,
+		ReadyToTrip: func(c gobreaker.Counts) bool {
+			return c.ConsecutiveFailures >= p.cfg.MaxFailures
+		},
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			p.log.Warn("circuit state changed", zap.String("upstream", name), zap.String("from", from.String()), zap.String("to", to.String()))
+		},
+	})
+	return u
+}
 
-	_, err = url.Parse(target)
-	if err != nil {
-		return nil, err
+// Forward returns a fiber.Handler that proxies to serviceName, replacing the public
+// pathPrefix with the service's own upstreamPrefix (e.g. /api/v1/chat -> /v1).
+func (p *Proxy) Forward(serviceName, pathPrefix, upstreamPrefix string) (fiber.Handler, error) {
+	p.mu.Lock()
+	u, ok := p.upstreams[serviceName]
+	if ok {
+		p.mounts = append(p.mounts, Mount{Service: serviceName, PathPrefix: pathPrefix, UpstreamPrefix: upstreamPrefix})
+	}
+	p.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("service not found: %s", serviceName)
 	}
 
 	handler := func(c *fiber.Ctx) error {
-		if pathPrefix != "" {
-			orig := c.OriginalURL()
+		if st := u.currentState(); st != StateActive {
+			c.Set(fiber.HeaderRetryAfter, "30")
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "upstream " + st})
+		}
+		inst := u.pick()
+		if inst == nil {
+			c.Set(fiber.HeaderRetryAfter, "5")
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "no ready upstream"})
+		}
+		u.inflight.Add(1)
+		defer u.done()
 
-			newPath := orig
+		newPath := c.OriginalURL()
+		if pathPrefix != "" {
+			orig := newPath
 			if len(orig) >= len(pathPrefix) && orig[:len(pathPrefix)] == pathPrefix {
-				newPath = orig[len(pathPrefix):]
+				newPath = upstreamPrefix + orig[len(pathPrefix):]
 				if newPath == "" {
 					newPath = "/"
 				}
 			}
+		}
 
-			c.Request().SetRequestURI(newPath)
+		// make sure correlation ids reach the upstream even if the route skipped RequestContext
+		if rid, ok := c.Locals("request_id").(string); ok && len(c.Request().Header.Peek("X-Request-ID")) == 0 {
+			c.Request().Header.Set("X-Request-ID", rid)
+		}
+		if tp, ok := c.Locals("traceparent").(string); ok && len(c.Request().Header.Peek("traceparent")) == 0 {
+			c.Request().Header.Set("traceparent", tp)
 		}
 
-		return proxy.Forward(target)(c)
+		_, err := u.breaker.Execute(func() (interface{}, error) {
+			// proxy.Forward(target) would replace the whole URI, so pass the rewritten path along
+			if err := p.do(c, inst.url+newPath); err != nil {
+				return nil, err
+			}
+			if c.Response().StatusCode() >= fiber.StatusInternalServerError {
+				return nil, errUpstreamStatus
+			}
+			return nil, nil
+		})
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, gobreaker.ErrOpenState), errors.Is(err, gobreaker.ErrTooManyRequests):
+			c.Set(fiber.HeaderRetryAfter, fmt.Sprint(p.cfg.TimeoutSec))
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "upstream unavailable"})
+		case errors.Is(err, fasthttp.ErrTimeout):
+			u.recordError(err.Error())
+			return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{"error": "upstream timed out"})
+		case errors.Is(err, errUpstreamStatus):
+			u.recordError(fmt.Sprintf("status %d", c.Response().StatusCode()))
+			return nil
+		default:
+			// unreachable instance: stop routing to it until the next probe succeeds
+			inst.setReady(false, err.Error())
+			u.recordError(err.Error())
+			p.log.Error("proxy error", zap.String("upstream", serviceName), zap.Error(err))
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "bad gateway"})
+		}
 	}
 
 	return handler, nil
 }
 
+// do proxies to addr, bounded by the configured upstream timeout. fasthttp resets
+// the response before reading the upstream answer, so headers and cookies that
+// gateway middleware set earlier (request id, rate limits, CORS, CSRF) are saved
+// and restored wherever the upstream did not set its own, e.g. auth-service
+// rotating the CSRF cookie on login.
+func (p *Proxy) do(c *fiber.Ctx, addr string) error {
+	var kept fasthttp.ResponseHeader
+	c.Response().Header.CopyTo(&kept)
+
+	var err error
+	if p.timeout > 0 {
+		err = proxy.DoTimeout(c, addr, p.timeout)
+	} else {
+		err = proxy.Do(c, addr)
+	}
+
+	kept.VisitAll(func(k, v []byte) {
+		switch string(k) {
+		case fiber.HeaderContentType, fiber.HeaderContentLength, fiber.HeaderContentEncoding,
+			fiber.HeaderTransferEncoding, fiber.HeaderServer, fiber.HeaderDate, fiber.HeaderConnection, fiber.HeaderSetCookie:
+			return
+		}
+		if len(c.Response().Header.PeekBytes(k)) == 0 {
+			c.Response().Header.SetBytesKV(k, v)
+		}
+	})
+	kept.VisitAllCookie(func(k, v []byte) {
+		if len(c.Response().Header.PeekCookie(string(k))) > 0 {
+			return
+		}
+		ck := fasthttp.AcquireCookie()
+		if ck.ParseBytes(v) == nil {
+			c.Response().Header.SetCookie(ck)
+		}
+		fasthttp.ReleaseCookie(ck)
+	})
+	return err
+}
+
+// maxFetchBody caps the upstream body Fetch will read.
+const maxFetchBody = 4 << 20
+
+// Fetch performs an internal GET against a service for gateway-side aggregation. It
+// shares instance selection, the circuit breaker and drain state with proxied
+// traffic. Non-5xx answers are returned as is; 5xx answers return an error too.
+func (p *Proxy) Fetch(ctx context.Context, service, path string, header http.Header) (int, []byte, error) {
+	p.mu.Lock()
+	u, ok := p.upstreams[service]
+	p.mu.Unlock()
+	if !ok {
+		return 0, nil, fmt.Errorf("service not found: %s", service)
+	}
+	if st := u.currentState(); st != StateActive {
+		return 0, nil, fmt.Errorf("upstream %s %s", service, st)
+	}
+	inst := u.pick()
+	if inst == nil {
+		return 0, nil, fmt.Errorf("no ready instance: %s", service)
+	}
+	u.inflight.Add(1)
+	defer u.done()
+
+	var status int
+	var body []byte
+	_, err := u.breaker.Execute(func() (interface{}, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, inst.url+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header = header.Clone()
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		status = resp.StatusCode
+		if body, err = io.ReadAll(io.LimitReader(resp.Body, maxFetchBody)); err != nil {
+			return nil, err
+		}
+		if status >= http.StatusInternalServerError {
+			return nil, errUpstreamStatus
+		}
+		return nil, nil
+	})
+	switch {
+	case err == nil:
+		return status, body, nil
+	case errors.Is(err, errUpstreamStatus):
+		u.recordError(fmt.Sprintf("status %d", status))
+		return status, body, fmt.Errorf("%s returned %d", service, status)
+	default:
+		if !errors.Is(err, gobreaker.ErrOpenState) && !errors.Is(err, gobreaker.ErrTooManyRequests) {
+			u.recordError(err.Error())
+		}
+		return 0, nil, fmt.Errorf("%s: %w", service, err)
+	}
+}
+
+// SetState activates, drains or disables an upstream.
+func (p *Proxy) SetState(name, state string) error {
+	switch state {
+	case StateActive, StateDraining, StateDisabled:
+	default:
+		return fmt.Errorf("invalid state: %s", state)
+	}
+	p.mu.Lock()
+	u, ok := p.upstreams[name]
+	p.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("service not found: %s", name)
+	}
+
+	u.mu.Lock()
+	u.state = state
+	if state == StateDraining && u.inflight.Load() == 0 {
+		u.state = StateDisabled
+	}
+	u.mu.Unlock()
+	p.log.Info("upstream state changed", zap.String("upstream", name), zap.String("state", state))
+	return nil
+}
+
+// Upstreams reports the state of every routed upstream, sorted by name.
+func (p *Proxy) Upstreams() []UpstreamStatus {
+	p.mu.Lock()
+	ups := make([]*upstream, 0, len(p.upstreams))
+	for _, u := range p.upstreams {
+		ups = append(ups, u)
+	}
+	p.mu.Unlock()
+	sort.Slice(ups, func(i, j int) bool { return ups[i].name < ups[j].name })
+
+	out := make([]UpstreamStatus, 0, len(ups))
+	for _, u := range ups {
+		counts := u.breaker.Counts()
+		st := UpstreamStatus{
+			Name:                u.name,
+			Instances:           u.instanceStatus(),
+			Circuit:             u.breaker.State().String(),
+			InFlight:            u.inflight.Load(),
+			Requests:            counts.Requests,
+			TotalFailures:       counts.TotalFailures,
+			ConsecutiveFailures: counts.ConsecutiveFailures,
+		}
+		u.mu.Lock()
+		st.State, st.LastError = u.state, u.lastErr
+		if !u.lastErrAt.IsZero() {
+			at := u.lastErrAt
+			st.LastErrorAt = &at
+		}
+		u.mu.Unlock()
+		out = append(out, st)
+	}
+	return out
+}
+
+// Mounts lists the public prefixes routed to each upstream.
+func (p *Proxy) Mounts() []Mount {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Mount(nil), p.mounts...)
+}
+
 func (p *Proxy) Close(ctx context.Context) error {
 	_ = ctx
 	return nil
 }
+
+func (u *upstream) currentState() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.state
+}
+
+// done finishes an in-flight request, completing a drain when it was the last one.
+func (u *upstream) done() {
+	if u.inflight.Add(-1) > 0 {
+		return
+	}
+	u.mu.Lock()
+	if u.state == StateDraining {
+		u.state = StateDisabled
+	}
+	u.mu.Unlock()
+}
+
+func (u *upstream) recordError(msg string) {
+	u.mu.Lock()
+	u.lastErr, u.lastErrAt = msg, time.Now()
+	u.mu.Unlock()
+}
@@ -3,40 +3,108 @@ package router
 import (
 	"net/http"
 
+	"github.com/fathima-sithara/api-gateway/internal/config"
+	"github.com/fathima-sithara/api-gateway/internal/graphql"
 	"github.com/fathima-sithara/api-gateway/internal/middleware"
+	"github.com/fathima-sithara/api-gateway/internal/openapi"
 	"github.com/fathima-sithara/api-gateway/internal/proxy"
+	"github.com/fathima-sithara/platform/observability"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 )
 
+// protectedUpstreams maps public path prefixes, below /api/<version>, to the route
+// prefix each service serves them under.
+var protectedUpstreams = []struct {
+	service        string
+	prefix         string
+	upstreamPrefix string
+}{
+	{"user", "/users", "/api/v1/users"},
+	{"chat", "/chat", "/v1"},
+	{"message", "/message", "/v1"},
+	{"notification", "/notifications", "/api/v1/notifications"},
+}
+
 // RegisterRoutes registers gateway routes and maps them to services.
-// This file uses proxy.Forward(serviceName, pathPrefix)
-func RegisterRoutes(app *fiber.App, p *proxy.Proxy, jwt *middleware.JWTMiddleware, rl *middleware.IPRateLimiter, logger *zap.Logger) {
+// This file uses proxy.Forward(serviceName, pathPrefix, upstreamPrefix)
+func RegisterRoutes(app *fiber.App, p *proxy.Proxy, jwt *middleware.JWTMiddleware, rl *middleware.RateLimiter, cache *middleware.ResponseCache, idem *middleware.Idempotency, gql *graphql.Server, spec *openapi.Spec, limits config.LimitsConfig, security config.SecurityConfig, logger *zap.Logger) {
+	// correlation ids first so every later log line and upstream call carries them
+	app.Use(middleware.RequestContext())
+	app.Use(observability.Middleware())
+	app.Use(middleware.ForwardSpan())
+	app.Use(middleware.AccessLog(logger))
+
+	// browser policy; CORS runs early so preflights skip the rest of the chain
+	app.Use(middleware.SecurityHeaders(security))
+	app.Use(middleware.CORS(security))
+
+	// shed load before doing any work, then enforce per-route body sizes
+	app.Use(middleware.ConcurrencyLimiter(limits, logger))
+	app.Use(middleware.BodyLimit(limits))
+	app.Use(middleware.CSRF(security))
+
+	// never forward caller supplied identity headers; JWTMiddleware re-adds signed ones
+	app.Use(middleware.StripIdentityHeaders())
+
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.Status(http.StatusOK).JSON(fiber.Map{"status": "ok"})
 	})
+	app.Get("/healthz", func(c *fiber.Ctx) error {
+		return c.Status(http.StatusOK).JSON(fiber.Map{"status": "ok"})
+	})
+	// ready only while every active upstream has at least one ready instance
+	app.Get("/readyz", func(c *fiber.Ctx) error {
+		upstreams, ready := p.Readiness()
+		if !ready {
+			return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"status": "not ready", "upstreams": upstreams})
+		}
+		return c.Status(http.StatusOK).JSON(fiber.Map{"status": "ready", "upstreams": upstreams})
+	})
+	app.Get("/metrics", observability.MetricsHandler())
 
-	// PUBLIC (auth)
-	authHandler, err := p.Forward("auth", "/api/v1/auth")
-	if err == nil {
-		app.All("/api/v1/auth/*", authHandler)
-	}
+	// merged OpenAPI contract of every service
+	app.Get("/openapi.json", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(spec.JSON())
+	})
+	validate := middleware.ValidateRequest(spec)
 
-	// MEDIA (public example)
-	if h, err := p.Forward("media", "/api/v1/media"); err == nil {
-		app.All("/api/v1/media/*", h)
-	}
+	for _, version := range openapi.Versions {
+		base := "/api/" + version
+		app.Use(base, apiVersion(version))
 
-	// Protected group - uses JWT + rate limiter
-	protected := app.Group("/", jwt.Handler(), rl.Handler())
+		// PUBLIC (auth) - rate limited per IP
+		if h, err := p.Forward("auth", base+"/auth", "/api/v1/auth"); err == nil {
+			app.All(base+"/auth/*", rl.Handler(), validate, h)
+		}
 
-	if h, err := p.Forward("user", "/api/v1/users"); err == nil {
-		protected.All("/api/v1/users/*", h)
-	}
+		// MEDIA (public example, no spec)
+		if h, err := p.Forward("media", base+"/media", ""); err == nil {
+			app.All(base+"/media/*", rl.Handler(), h)
+		}
+
+		// Protected group - uses JWT + rate limiter (keyed per user) + per-user
+		// idempotency keys for retried writes + per-user response cache
+		protected := app.Group(base, jwt.Handler(), rl.Handler(), idem.Handler(), cache.Handler())
 
-	if h, err := p.Forward("chat", "/api/v1/chat"); err == nil {
-		protected.All("/api/v1/chat/*", h)
+		for _, u := range protectedUpstreams {
+			if h, err := p.Forward(u.service, base+u.prefix, u.upstreamPrefix); err == nil {
+				protected.All(u.prefix+"/*", validate, h)
+			}
+		}
+
+		// aggregated reads across services (inbox in one round trip)
+		protected.Post("/graphql", gql.Handler())
 	}
 
 	logger.Info("routes registered")
 }
+
+// apiVersion tells the upstream which public API version the caller used.
+func apiVersion(version string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Request().Header.Set("X-API-Version", version)
+		return c.Next()
+	}
+}
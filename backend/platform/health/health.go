@@ -0,0 +1,89 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// checkTimeout bounds a single readiness check so one hung dependency cannot
+// stall the probe.
+const checkTimeout = 2 * time.Second
+
+// Check reports whether a dependency is usable; nil means ready.
+type Check func(ctx context.Context) error
+
+// Checker serves the standard probes: /healthz answers as long as the process is
+// up, /readyz only when every registered dependency check passes.
+type Checker struct {
+	mu     sync.RWMutex
+	names  []string
+	checks map[string]Check
+}
+
+func New() *Checker {
+	return &Checker{checks: map[string]Check{}}
+}
+
+// Add registers a readiness check under name, e.g. "mongo" or "nats".
+func (h *Checker) Add(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.checks[name]; !ok {
+		h.names = append(h.names, name)
+	}
+	h.checks[name] = check
+}
+
+// Register mounts GET /healthz and GET /readyz on r.
+func (h *Checker) Register(r fiber.Router) {
+	r.Get("/healthz", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "ok"})
+	})
+	r.Get("/readyz", func(c *fiber.Ctx) error {
+		results, ready := h.run(c.UserContext())
+		if !ready {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"status": "not ready", "checks": results})
+		}
+		return c.JSON(fiber.Map{"status": "ready", "checks": results})
+	})
+}
+
+// run executes every check concurrently and reports "ok" or the error per check.
+func (h *Checker) run(ctx context.Context) (map[string]string, bool) {
+	h.mu.RLock()
+	names := append([]string(nil), h.names...)
+	checks := make([]Check, len(names))
+	for i, n := range names {
+		checks[i] = h.checks[n]
+	}
+	h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			errs[i] = check(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	results := make(map[string]string, len(names))
+	ready := true
+	for i, n := range names {
+		if errs[i] != nil {
+			results[n] = errs[i].Error()
+			ready = false
+			continue
+		}
+		results[n] = "ok"
+	}
+	return results, ready
+}
@@ -0,0 +1,63 @@
+package identity
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	const secret = "s3cret"
+	now := time.Unix(1_700_000_000, 0)
+	id := Identity{UserID: "u1", Roles: []string{"user", "admin"}, SessionID: "sess"}
+
+	tests := []struct {
+		name   string
+		secret string
+		at     time.Time
+		edit   func(h map[string]string)
+		want   error
+	}{
+		{name: "valid", secret: secret, at: now},
+		{name: "within skew", secret: secret, at: now.Add(MaxSkew)},
+		{name: "clock behind", secret: secret, at: now.Add(-MaxSkew)},
+		{name: "too old", secret: secret, at: now.Add(MaxSkew + time.Second), want: ErrExpired},
+		{name: "from the future", secret: secret, at: now.Add(-MaxSkew - time.Second), want: ErrExpired},
+		{name: "other secret", secret: "other", at: now, want: ErrInvalid},
+		{name: "missing user", secret: secret, at: now, edit: func(h map[string]string) { delete(h, HeaderUserID) }, want: ErrMissing},
+		{name: "missing signature", secret: secret, at: now, edit: func(h map[string]string) { delete(h, HeaderSignature) }, want: ErrMissing},
+		{name: "swapped user", secret: secret, at: now, edit: func(h map[string]string) { h[HeaderUserID] = "u2" }, want: ErrInvalid},
+		{name: "added role", secret: secret, at: now, edit: func(h map[string]string) { h[HeaderUserRoles] += ",owner" }, want: ErrInvalid},
+		{name: "swapped session", secret: secret, at: now, edit: func(h map[string]string) { h[HeaderSessionID] = "other" }, want: ErrInvalid},
+		{name: "bad timestamp", secret: secret, at: now, edit: func(h map[string]string) { h[HeaderTimestamp] = "soon" }, want: ErrInvalid},
+		{name: "signature not hex", secret: secret, at: now, edit: func(h map[string]string) { h[HeaderSignature] = "zz" }, want: ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Sign(secret, id, now)
+			if tt.edit != nil {
+				tt.edit(h)
+			}
+			got, err := Verify(tt.secret, func(k string) string { return h[k] }, tt.at)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && !reflect.DeepEqual(got, id) {
+				t.Fatalf("Verify = %+v, want %+v", got, id)
+			}
+		})
+	}
+}
+
+func TestVerifyNoRoles(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	h := Sign("s", Identity{UserID: "u1"}, now)
+	got, err := Verify("s", func(k string) string { return h[k] }, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Roles != nil {
+		t.Fatalf("Roles = %q, want nil", got.Roles)
+	}
+}
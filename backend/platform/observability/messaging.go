@@ -0,0 +1,29 @@
+package observability
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	messagesPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "messaging_published_total",
+		Help: "Messages published to the event bus by system, subject and outcome.",
+	}, []string{"system", "destination", "outcome"})
+
+	messagesConsumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "messaging_consumed_total",
+		Help: "Messages consumed from the event bus by system, subject and outcome.",
+	}, []string{"system", "destination", "outcome"})
+)
+
+func init() {
+	registry.MustRegister(messagesPublished, messagesConsumed)
+}
+
+// RecordPublish counts one publish attempt; system is "nats" or "kafka".
+func RecordPublish(system, destination string, err error) {
+	messagesPublished.WithLabelValues(system, destination, outcome(err)).Inc()
+}
+
+// RecordConsume counts one handled message; err is the handler's result.
+func RecordConsume(system, destination string, err error) {
+	messagesConsumed.WithLabelValues(system, destination, outcome(err)).Inc()
+}
@@ -0,0 +1,110 @@
+package observability
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// registry holds every collector exposed on /metrics. A private registry keeps
+// third-party packages from leaking their own metrics into the endpoint.
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by route template.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+	)
+}
+
+// MetricsHandler serves the registry in the Prometheus text format.
+func MetricsHandler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+}
+
+// Middleware wraps each request in a server span and records RED metrics keyed by
+// the route template, so /chats/:chat_id is one series rather than one per id.
+// It must run after reqctx.Middleware so the span joins the caller's trace.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		ctx, span := StartSpan(c.UserContext(), c.Method()+" "+c.Path(), trace.SpanKindServer,
+			attribute.String("http.request.method", c.Method()),
+			attribute.String("url.path", c.Path()),
+		)
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		route := c.Route().Path
+		if unmatched(err) {
+			// keep unknown paths out of the label set
+			route = "unmatched"
+		}
+		status := statusCode(c, err)
+		code := strconv.Itoa(status)
+
+		span.SetName(c.Method() + " " + route)
+		span.SetAttributes(attribute.String("http.route", route), attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, code)
+		}
+		span.End()
+
+		httpRequests.WithLabelValues(c.Method(), route, code).Inc()
+		httpDuration.WithLabelValues(c.Method(), route, code).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// statusCode predicts the status the error handler will write for err.
+func statusCode(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		return fe.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// unmatched reports whether err is fiber's own 404 for a path no handler matched.
+// c.Route() then names the last middleware, and for those fiber reports the
+// request method rather than "USE".
+func unmatched(err error) bool {
+	var fe *fiber.Error
+	return errors.As(err, &fe) && fe.Code == fiber.StatusNotFound && strings.HasPrefix(fe.Message, "Cannot ")
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,98 @@
+package observability
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fathima-sithara/platform/reqctx"
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddleware(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	app := fiber.New()
+	app.Use(reqctx.Middleware(), Middleware())
+	app.Get("/chats/:chat_id", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	app.Get("/missing", func(c *fiber.Ctx) error { return fiber.ErrNotFound })
+	app.Get("/broken", func(c *fiber.Ctx) error { return errors.New("boom") })
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	tests := []struct {
+		name       string
+		path       string
+		route      string
+		status     string
+		wantErrSet bool
+	}{
+		{name: "route template, not the path", path: "/chats/c1", route: "/chats/:chat_id", status: "200"},
+		{name: "fiber error status", path: "/missing", route: "/missing", status: "404"},
+		{name: "plain error is a 500", path: "/broken", route: "/broken", status: "500", wantErrSet: true},
+		{name: "unknown paths share one series", path: "/nope/123", route: "unmatched", status: "404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(httpRequests.WithLabelValues("GET", tt.route, tt.status))
+			rec.Reset()
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set(reqctx.HeaderTraceparent, "00-"+traceID+"-00f067aa0ba902b7-01")
+			if _, err := app.Test(req); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", tt.route, tt.status)) - before; got != 1 {
+				t.Fatalf("http_requests_total{route=%q,status=%q} grew by %v, want 1", tt.route, tt.status, got)
+			}
+			spans := rec.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("ended %d spans, want 1", len(spans))
+			}
+			s := spans[0]
+			if s.Name() != "GET "+tt.route {
+				t.Fatalf("span name = %q, want %q", s.Name(), "GET "+tt.route)
+			}
+			if s.SpanContext().TraceID().String() != traceID {
+				t.Fatalf("span trace id = %s, want the caller's %s", s.SpanContext().TraceID(), traceID)
+			}
+			if (s.Status().Code.String() == "Error") != tt.wantErrSet {
+				t.Fatalf("span status = %v, want error %v", s.Status().Code, tt.wantErrSet)
+			}
+		})
+	}
+}
+
+func TestRecordMessaging(t *testing.T) {
+	tests := []struct {
+		name    string
+		record  func(system, destination string, err error)
+		err     error
+		outcome string
+	}{
+		{"publish ok", RecordPublish, nil, "ok"},
+		{"publish failed", RecordPublish, errors.New("down"), "error"},
+		{"consume ok", RecordConsume, nil, "ok"},
+		{"consume failed", RecordConsume, errors.New("bad payload"), "error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vec := messagesPublished
+			if tt.name[:7] == "consume" {
+				vec = messagesConsumed
+			}
+			c := vec.WithLabelValues("nats", "chat.created", tt.outcome)
+			before := testutil.ToFloat64(c)
+			tt.record("nats", "chat.created", tt.err)
+			if got := testutil.ToFloat64(c) - before; got != 1 {
+				t.Fatalf("counter grew by %v, want 1", got)
+			}
+		})
+	}
+}
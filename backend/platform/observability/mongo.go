@@ -0,0 +1,30 @@
+package observability
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+var mongoDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "mongo_command_duration_seconds",
+	Help:    "MongoDB command latency by command name and outcome.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"command", "outcome"})
+
+func init() {
+	registry.MustRegister(mongoDuration)
+}
+
+// MongoMonitor returns a command monitor to pass to options.Client().SetMonitor.
+func MongoMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			mongoDuration.WithLabelValues(e.CommandName, "ok").Observe(e.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			mongoDuration.WithLabelValues(e.CommandName, "error").Observe(e.Duration.Seconds())
+		},
+	}
+}
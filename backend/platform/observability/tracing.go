@@ -0,0 +1,79 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fathima-sithara/platform/reqctx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/fathima-sithara/platform/observability")
+
+// InitTracing installs the global tracer provider. OTEL_TRACES_EXPORTER selects the
+// exporter: "otlp" sends to a collector (OTEL_EXPORTER_OTLP_ENDPOINT, defaulting to
+// localhost:4318), "stdout" prints spans, and "none" or empty disables tracing.
+// The returned function flushes pending spans and must be called on shutdown.
+func InitTracing(ctx context.Context, service string) (func(context.Context) error, error) {
+	var (
+		exp sdktrace.SpanExporter
+		err error
+	)
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_TRACES_EXPORTER"))) {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exp, err = otlptracehttp.New(ctx)
+	case "stdout", "console":
+		exp, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q", os.Getenv("OTEL_TRACES_EXPORTER"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("trace exporter: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return tp.Shutdown, nil
+}
+
+// StartSpan starts a span under the current span, or under the traceparent carried
+// by reqctx when ctx was built from message headers. The reqctx traceparent is then
+// pointed at the new span so anything injected downstream becomes its child.
+func StartSpan(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	info := reqctx.FromContext(ctx)
+	if !trace.SpanContextFromContext(ctx).IsValid() && info.Traceparent != "" {
+		ctx = propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{reqctx.HeaderTraceparent: info.Traceparent})
+	}
+
+	ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+	if sc := span.SpanContext(); sc.IsValid() {
+		info.Traceparent = "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-" + sc.TraceFlags().String()
+		ctx = reqctx.NewContext(ctx, info)
+	}
+	return ctx, span
+}
+
+// EndSpan records err on span, if any, and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
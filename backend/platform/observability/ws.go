@@ -0,0 +1,41 @@
+package observability
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	wsConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ws_connections",
+		Help: "WebSocket connections currently registered with the hub.",
+	})
+
+	wsRooms = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ws_rooms",
+		Help: "Rooms with at least one local connection.",
+	})
+
+	wsDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ws_broadcast_dropped_total",
+		Help: "Broadcasts dropped because a client's send buffer was full.",
+	})
+)
+
+// wsOnce registers the gauges on first use, so services without a hub do not
+// report an idle one.
+var wsOnce sync.Once
+
+func ws() {
+	wsOnce.Do(func() { registry.MustRegister(wsConnections, wsRooms, wsDropped) })
+}
+
+func WSConnected()    { ws(); wsConnections.Inc() }
+func WSDisconnected() { ws(); wsConnections.Dec() }
+
+// SetWSRooms reports the current number of rooms held by the hub.
+func SetWSRooms(n int) { ws(); wsRooms.Set(float64(n)) }
+
+// WSDropped counts one message that could not be delivered to a client.
+func WSDropped() { ws(); wsDropped.Inc() }
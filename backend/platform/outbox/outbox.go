@@ -0,0 +1,162 @@
+// Package outbox makes event publishing part of the write it describes. Events are
+// stored in the outbox collection inside the same Mongo transaction as the change,
+// and a Relay publishes them afterwards, retrying until NATS has them. An event is
+// therefore published if and only if its change committed; it may be delivered
+// more than once, so consumers must be idempotent.
+//
+// Transactions need MongoDB to run as a replica set (a single-node one is enough).
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/fathima-sithara/platform/reqctx"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Record is one stored event. Headers keep the correlation ids of the request
+// that caused it.
+type Record struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Subject    string             `bson:"subject"`
+	Data       []byte             `bson:"data"`
+	Headers    map[string]string  `bson:"headers,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at"`
+	SentAt     *time.Time         `bson:"sent_at,omitempty"`
+	Attempts   int                `bson:"attempts"`
+	LeaseUntil time.Time          `bson:"lease_until"`
+	LastError  string             `bson:"last_error,omitempty"`
+}
+
+var errEmpty = errors.New("outbox empty")
+
+type Store struct {
+	coll *mongo.Collection
+}
+
+// NewStore keeps sent records for retention, which bounds how far back a replay
+// can go; unsent records are kept until they are sent.
+func NewStore(db *mongo.Database, retention time.Duration) *Store {
+	coll := db.Collection("outbox")
+	_, _ = coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "sent_at", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName("pending_idx"),
+		},
+		{
+			Keys: bson.D{{Key: "sent_at", Value: 1}},
+			Options: options.Index().SetName("sent_ttl").
+				SetExpireAfterSeconds(int32(retention / time.Second)),
+		},
+	})
+	return &Store{coll: coll}
+}
+
+// Tx runs fn in a transaction. Repository calls made with the context fn receives
+// join it, so the outbox rows fn adds commit or roll back with them. fn may be
+// retried on transient errors.
+func (s *Store) Tx(ctx context.Context, fn func(ctx context.Context) error) error {
+	sess, err := s.coll.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer sess.EndSession(ctx)
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// Add stores v as JSON for publishing on subject. Call it inside Tx.
+func (s *Store) Add(ctx context.Context, subject string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	h := headers{}
+	reqctx.Inject(ctx, h)
+	_, err = s.coll.InsertOne(ctx, &Record{
+		ID:        primitive.NewObjectID(),
+		Subject:   subject,
+		Data:      data,
+		Headers:   h,
+		CreatedAt: time.Now().UTC(),
+	})
+	return err
+}
+
+// claim leases the oldest due record that has not been sent, so other relays
+// skip it until the lease runs out.
+func (s *Store) claim(ctx context.Context, now time.Time, lease time.Duration) (*Record, error) {
+	filter := bson.M{"sent_at": nil, "lease_until": bson.M{"$lte": now}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+	var rec Record
+	err := s.coll.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"lease_until": now.Add(lease)}}, opts).Decode(&rec)
+	if err == mongo.ErrNoDocuments {
+		return nil, errEmpty
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (s *Store) markSent(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	_, err := s.coll.UpdateByID(ctx, id, bson.M{
+		"$set":   bson.M{"sent_at": now},
+		"$unset": bson.M{"last_error": ""},
+	})
+	return err
+}
+
+// markFailed records a failed attempt and holds the record back until retryAt.
+func (s *Store) markFailed(ctx context.Context, id primitive.ObjectID, cause error, retryAt time.Time) error {
+	_, err := s.coll.UpdateByID(ctx, id, bson.M{
+		"$set": bson.M{"lease_until": retryAt, "last_error": cause.Error()},
+		"$inc": bson.M{"attempts": 1},
+	})
+	return err
+}
+
+// Scan calls fn for every record created at or after since, oldest first. An
+// empty subject matches all. It backs replays and leaves the records unchanged.
+func (s *Store) Scan(ctx context.Context, since time.Time, subject string, fn func(*Record) error) error {
+	filter := bson.M{"_id": bson.M{"$gte": primitive.NewObjectIDFromTimestamp(since)}}
+	if subject != "" {
+		filter["subject"] = subject
+	}
+	cur, err := s.coll.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		var rec Record
+		if err := cur.Decode(&rec); err != nil {
+			return err
+		}
+		if err := fn(&rec); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+// Context returns ctx carrying the correlation ids rec was written with.
+func (r *Record) Context(ctx context.Context) context.Context {
+	return reqctx.Extract(ctx, headers(r.Headers))
+}
+
+// headers lets reqctx read and write a record's stored headers.
+type headers map[string]string
+
+func (h headers) Get(key string) string { return h[key] }
+func (h headers) Set(key, value string) { h[key] = value }
@@ -0,0 +1,207 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fathima-sithara/platform/reqctx"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// fakeSender records what it publishes and fails subjects listed in fail.
+type fakeSender struct {
+	fail map[string]bool
+	sent []string
+	rids []string
+}
+
+func (f *fakeSender) Send(ctx context.Context, subject string, data []byte) error {
+	if f.fail[subject] {
+		return errors.New("nats down")
+	}
+	f.sent = append(f.sent, subject+" "+string(data))
+	f.rids = append(f.rids, reqctx.FromContext(ctx).RequestID)
+	return nil
+}
+
+func recordDoc(subject, data, rid string) bson.D {
+	return bson.D{
+		{Key: "_id", Value: primitive.NewObjectID()},
+		{Key: "subject", Value: subject},
+		{Key: "data", Value: []byte(data)},
+		{Key: "headers", Value: bson.D{{Key: reqctx.HeaderRequestID, Value: rid}}},
+		{Key: "attempts", Value: 2},
+	}
+}
+
+// claimed answers a claim with doc, or with nothing due when doc is nil.
+func claimed(doc bson.D) bson.D {
+	if doc == nil {
+		return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil})
+	}
+	return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: doc})
+}
+
+func TestRelayDrain(t *testing.T) {
+	updated := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	tests := []struct {
+		name      string
+		responses []bson.D
+		fail      map[string]bool
+		wantSent  []string
+		// wantUpdates are the fields the relay set on the records it handled
+		wantUpdates []string
+	}{
+		{
+			name:      "sends due records in order",
+			responses: []bson.D{claimed(recordDoc("a", "1", "r1")), updated, claimed(recordDoc("b", "2", "r2")), updated, claimed(nil)},
+			wantSent:  []string{"a 1", "b 2"}, wantUpdates: []string{"sent_at", "sent_at"},
+		},
+		{
+			name:        "a failed send is held back and stops the drain",
+			responses:   []bson.D{claimed(recordDoc("a", "1", "r1")), updated},
+			fail:        map[string]bool{"a": true},
+			wantUpdates: []string{"lease_until"},
+		},
+		{
+			name:      "nothing due",
+			responses: []bson.D{claimed(nil)},
+		},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			sender := &fakeSender{fail: tt.fail}
+			mt.AddMockResponses(tt.responses...)
+
+			NewRelay(&Store{coll: mt.Coll}, sender, time.Second).drain(context.Background())
+
+			if len(sender.sent) != len(tt.wantSent) {
+				mt.Fatalf("sent %v, want %v", sender.sent, tt.wantSent)
+			}
+			for i := range sender.sent {
+				if sender.sent[i] != tt.wantSent[i] {
+					mt.Fatalf("sent %v, want %v", sender.sent, tt.wantSent)
+				}
+				if want := "r" + string(rune('1'+i)); sender.rids[i] != want {
+					mt.Fatalf("record %d sent with request id %q, want %q", i, sender.rids[i], want)
+				}
+			}
+			var updates []string
+			for ev := mt.GetStartedEvent(); ev != nil; ev = mt.GetStartedEvent() {
+				if ev.CommandName != "update" {
+					continue
+				}
+				set := ev.Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u", "$set").Document()
+				elems, _ := set.Elements()
+				for _, e := range elems {
+					if e.Key() == "sent_at" || e.Key() == "lease_until" {
+						updates = append(updates, e.Key())
+					}
+				}
+				if tt.fail != nil {
+					retryAt := set.Lookup("lease_until").Time()
+					// the record had two attempts, so the third waits 4s
+					if d := time.Until(retryAt); d < 3*time.Second || d > 5*time.Second {
+						mt.Fatalf("failed record retried in %v, want about 4s", d)
+					}
+				}
+			}
+			if len(updates) != len(tt.wantUpdates) {
+				mt.Fatalf("updated %v, want %v", updates, tt.wantUpdates)
+			}
+			for i := range updates {
+				if updates[i] != tt.wantUpdates[i] {
+					mt.Fatalf("updated %v, want %v", updates, tt.wantUpdates)
+				}
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	tests := []struct {
+		name     string
+		opts     ReplayOptions
+		fail     map[string]bool
+		wantSent []string
+		wantErr  bool
+	}{
+		{name: "dry run sends nothing", opts: ReplayOptions{Subject: "a"}},
+		{name: "apply sends oldest first", opts: ReplayOptions{Subject: "a", Apply: true}, wantSent: []string{"a 1", "a 2"}},
+		{name: "a failed send stops the replay", opts: ReplayOptions{Subject: "a", Apply: true}, fail: map[string]bool{"a": true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			ns := "test." + mt.Coll.Name()
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, recordDoc("a", "1", "r1"), recordDoc("a", "2", "r2")),
+			)
+			sender := &fakeSender{fail: tt.fail}
+			err := Replay(context.Background(), &Store{coll: mt.Coll}, sender, tt.opts)
+			if (err != nil) != tt.wantErr {
+				mt.Fatalf("Replay err = %v, want error %v", err, tt.wantErr)
+			}
+			if len(sender.sent) != len(tt.wantSent) {
+				mt.Fatalf("sent %v, want %v", sender.sent, tt.wantSent)
+			}
+			for i := range sender.sent {
+				if sender.sent[i] != tt.wantSent[i] {
+					mt.Fatalf("sent %v, want %v", sender.sent, tt.wantSent)
+				}
+			}
+
+			ev := mt.GetStartedEvent()
+			if ev == nil || ev.CommandName != "find" {
+				mt.Fatalf("command = %v, want a find", ev)
+			}
+			if got := ev.Command.Lookup("filter", "subject").StringValue(); got != "a" {
+				mt.Fatalf("replay filtered on subject %q, want a", got)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{9, 256 * time.Second},
+		{10, maxBackoff},
+		{100, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "24h", want: now.Add(-24 * time.Hour)},
+		{in: "90m", want: now.Add(-90 * time.Minute)},
+		{in: "2024-05-01T08:00:00Z", want: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)},
+		{in: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSince(tt.in, now)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && !got.Equal(tt.want)) {
+			t.Errorf("parseSince(%q) = (%v, %v), want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// Sender publishes a record's payload. It must only return nil once the broker
+// has the message; each service's *events.Publisher implements it.
+type Sender interface {
+	Send(ctx context.Context, subject string, data []byte) error
+}
+
+const (
+	// lease is how long a claimed record stays hidden from other relays.
+	lease      = 30 * time.Second
+	maxBackoff = 5 * time.Minute
+)
+
+// Relay publishes stored records in order and marks them sent. Every replica can
+// run one; leases keep them from sending the same record at the same time.
+type Relay struct {
+	store    *Store
+	sender   Sender
+	interval time.Duration
+}
+
+func NewRelay(store *Store, sender Sender, interval time.Duration) *Relay {
+	return &Relay{store: store, sender: sender, interval: interval}
+}
+
+// Run polls for due records until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	for {
+		r.drain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// drain sends due records until none are left or one fails; a failed record is
+// retried with exponential backoff.
+func (r *Relay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		rec, err := r.store.claim(ctx, time.Now().UTC(), lease)
+		if errors.Is(err, errEmpty) {
+			return
+		}
+		if err != nil {
+			log.Printf("outbox claim: %v", err)
+			return
+		}
+		if err := r.sender.Send(rec.Context(ctx), rec.Subject, rec.Data); err != nil {
+			retryAt := time.Now().UTC().Add(backoff(rec.Attempts + 1))
+			log.Printf("outbox send %s %s (attempt %d): %v", rec.Subject, rec.ID.Hex(), rec.Attempts+1, err)
+			if err := r.store.markFailed(ctx, rec.ID, err, retryAt); err != nil {
+				log.Printf("outbox mark failed %s: %v", rec.ID.Hex(), err)
+			}
+			return
+		}
+		if err := r.store.markSent(ctx, rec.ID, time.Now().UTC()); err != nil {
+			// the lease runs out and the record goes again; consumers dedupe
+			log.Printf("outbox mark sent %s: %v", rec.ID.Hex(), err)
+		}
+	}
+}
+
+func backoff(attempt int) time.Duration {
+	d := time.Second
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
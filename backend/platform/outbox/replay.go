@@ -0,0 +1,79 @@
+package outbox
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ReplayOptions select the records a replay-events command sends again.
+type ReplayOptions struct {
+	Since   time.Time
+	Subject string // empty replays every subject
+	Apply   bool   // publish instead of only counting
+}
+
+// ParseReplayFlags reads -since, -subject and -apply from the command line.
+func ParseReplayFlags() (ReplayOptions, error) {
+	since := flag.String("since", "24h", "replay events newer than this duration ago, or since an RFC 3339 time")
+	subject := flag.String("subject", "", "only replay this subject")
+	apply := flag.Bool("apply", false, "publish instead of only counting")
+	flag.Parse()
+
+	from, err := parseSince(*since, time.Now().UTC())
+	if err != nil {
+		return ReplayOptions{}, fmt.Errorf("-since: %w", err)
+	}
+	return ReplayOptions{Since: from, Subject: *subject, Apply: *apply}, nil
+}
+
+// Replay publishes the records opts selects through sender again, oldest first,
+// and logs how many it sent. Without opts.Apply it only counts them and sender
+// may be nil. A sender with a Ready method is given 10 seconds to become ready.
+func Replay(ctx context.Context, store *Store, sender Sender, opts ReplayOptions) error {
+	if opts.Apply {
+		if r, ok := sender.(interface{ Ready(context.Context) error }); ok {
+			if err := waitReady(ctx, r.Ready, 10*time.Second); err != nil {
+				return err
+			}
+		}
+	}
+
+	n := 0
+	err := store.Scan(ctx, opts.Since, opts.Subject, func(rec *Record) error {
+		n++
+		if !opts.Apply {
+			return nil
+		}
+		return sender.Send(rec.Context(ctx), rec.Subject, rec.Data)
+	})
+	if err != nil {
+		return fmt.Errorf("replay stopped after %d events: %w", n, err)
+	}
+	if !opts.Apply {
+		log.Printf("dry run: %d events since %s; rerun with -apply", n, opts.Since.Format(time.RFC3339))
+		return nil
+	}
+	log.Printf("replayed %d events since %s", n, opts.Since.Format(time.RFC3339))
+	return nil
+}
+
+func parseSince(v string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func waitReady(ctx context.Context, ready func(context.Context) error, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := ready(ctx)
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
@@ -0,0 +1,119 @@
+package reqctx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Correlation headers propagated over HTTP, NATS and Kafka.
+const (
+	HeaderRequestID   = "X-Request-ID"
+	HeaderTraceparent = "traceparent"
+)
+
+type ctxKey struct{}
+
+// Info identifies the request a unit of work belongs to.
+type Info struct {
+	RequestID   string
+	Traceparent string
+}
+
+// TraceID returns the trace-id part of the W3C traceparent.
+func (i Info) TraceID() string {
+	parts := strings.Split(i.Traceparent, "-")
+	if len(parts) != 4 {
+		return ""
+	}
+	return parts[1]
+}
+
+func NewContext(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, ctxKey{}, info)
+}
+
+func FromContext(ctx context.Context) Info {
+	if ctx == nil {
+		return Info{}
+	}
+	info, _ := ctx.Value(ctxKey{}).(Info)
+	return info
+}
+
+// Carrier is implemented by nats.Header and http.Header.
+type Carrier interface {
+	Set(key, value string)
+	Get(key string) string
+}
+
+// Inject copies the correlation ids from ctx into outgoing message headers.
+func Inject(ctx context.Context, h Carrier) {
+	info := FromContext(ctx)
+	if info.RequestID != "" {
+		h.Set(HeaderRequestID, info.RequestID)
+	}
+	if info.Traceparent != "" {
+		h.Set(HeaderTraceparent, info.Traceparent)
+	}
+}
+
+// Extract returns a context carrying the correlation ids found in h, generating
+// fresh ones when the producer did not send any.
+func Extract(ctx context.Context, h Carrier) context.Context {
+	return NewContext(ctx, normalize(h.Get(HeaderRequestID), h.Get(HeaderTraceparent)))
+}
+
+// Middleware reads or generates X-Request-ID and traceparent, echoes the request id
+// back to the caller and exposes both to handlers via Locals and UserContext.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		info := normalize(c.Get(HeaderRequestID), c.Get(HeaderTraceparent))
+		c.Set(HeaderRequestID, info.RequestID)
+		c.Locals("request_id", info.RequestID)
+		c.Locals("trace_id", info.TraceID())
+		c.SetUserContext(NewContext(c.UserContext(), info))
+		return c.Next()
+	}
+}
+
+func normalize(requestID, traceparent string) Info {
+	if requestID == "" || len(requestID) > 128 {
+		requestID = uuid.NewString()
+	}
+	if !validTraceparent(traceparent) {
+		traceparent = "00-" + randomHex(16) + "-" + randomHex(8) + "-01"
+	}
+	return Info{RequestID: requestID, Traceparent: traceparent}
+}
+
+// validTraceparent checks the version-00 layout: 00-<32 hex>-<16 hex>-<2 hex>.
+func validTraceparent(tp string) bool {
+	parts := strings.Split(tp, "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return false
+	}
+	for _, p := range parts[1:] {
+		if _, err := hex.DecodeString(p); err != nil {
+			return false
+		}
+	}
+	return parts[1] != strings.Repeat("0", 32) && parts[2] != strings.Repeat("0", 16)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Fields returns the correlation ids in ctx as zap fields.
+func Fields(ctx context.Context) []zap.Field {
+	info := FromContext(ctx)
+	return []zap.Field{zap.String("request_id", info.RequestID), zap.String("trace_id", info.TraceID())}
+}
@@ -0,0 +1,117 @@
+package reqctx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const validTP = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		requestID   string
+		traceparent string
+		keepRID     bool
+		keepTP      bool
+	}{
+		{name: "both kept", requestID: "req-1", traceparent: validTP, keepRID: true, keepTP: true},
+		{name: "both generated"},
+		{name: "oversized request id", requestID: strings.Repeat("r", 129), traceparent: validTP, keepTP: true},
+		{name: "wrong version", requestID: "req-1", traceparent: "01" + validTP[2:], keepRID: true},
+		{name: "not hex", requestID: "req-1", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01", keepRID: true},
+		{name: "zero trace id", requestID: "req-1", traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", keepRID: true},
+		{name: "zero span id", requestID: "req-1", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", keepRID: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Info
+			var localRID, localTID any
+			app := fiber.New()
+			app.Use(Middleware())
+			app.Get("/", func(c *fiber.Ctx) error {
+				got = FromContext(c.UserContext())
+				localRID, localTID = c.Locals("request_id"), c.Locals("trace_id")
+				return nil
+			})
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.requestID != "" {
+				req.Header.Set(HeaderRequestID, tt.requestID)
+			}
+			if tt.traceparent != "" {
+				req.Header.Set(HeaderTraceparent, tt.traceparent)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.keepRID && got.RequestID != tt.requestID {
+				t.Fatalf("request id = %q, want %q", got.RequestID, tt.requestID)
+			}
+			if !tt.keepRID && (got.RequestID == "" || got.RequestID == tt.requestID) {
+				t.Fatalf("request id = %q, want a generated one", got.RequestID)
+			}
+			if tt.keepTP && got.Traceparent != tt.traceparent {
+				t.Fatalf("traceparent = %q, want %q", got.Traceparent, tt.traceparent)
+			}
+			if !tt.keepTP && (!validTraceparent(got.Traceparent) || got.Traceparent == tt.traceparent) {
+				t.Fatalf("traceparent = %q, want a generated one", got.Traceparent)
+			}
+			if resp.Header.Get(HeaderRequestID) != got.RequestID {
+				t.Fatalf("echoed request id = %q, want %q", resp.Header.Get(HeaderRequestID), got.RequestID)
+			}
+			if localRID != got.RequestID || localTID != got.TraceID() {
+				t.Fatalf("locals = %v, %v; want %q, %q", localRID, localTID, got.RequestID, got.TraceID())
+			}
+		})
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		info Info
+	}{
+		{"full", Info{RequestID: "req-1", Traceparent: validTP}},
+		{"request id only", Info{RequestID: "req-2"}},
+		{"nothing", Info{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			Inject(NewContext(context.Background(), tt.info), h)
+			got := FromContext(Extract(context.Background(), h))
+			if tt.info.RequestID != "" && got.RequestID != tt.info.RequestID {
+				t.Fatalf("request id = %q, want %q", got.RequestID, tt.info.RequestID)
+			}
+			if tt.info.Traceparent != "" && got.Traceparent != tt.info.Traceparent {
+				t.Fatalf("traceparent = %q, want %q", got.Traceparent, tt.info.Traceparent)
+			}
+			// a consumer always ends up with ids to log, even if the producer sent none
+			if got.RequestID == "" || !validTraceparent(got.Traceparent) {
+				t.Fatalf("extracted %+v, want generated ids", got)
+			}
+		})
+	}
+}
+
+func TestTraceID(t *testing.T) {
+	tests := []struct {
+		traceparent, want string
+	}{
+		{validTP, "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"", ""},
+		{"00-abc", ""},
+	}
+	for _, tt := range tests {
+		if got := (Info{Traceparent: tt.traceparent}).TraceID(); got != tt.want {
+			t.Errorf("TraceID(%q) = %q, want %q", tt.traceparent, got, tt.want)
+		}
+	}
+}
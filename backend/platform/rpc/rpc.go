@@ -0,0 +1,115 @@
+// Package rpc holds the gRPC plumbing shared by the internal APIs: correlation id
+// propagation, spans, default deadlines and error to status code mapping. The
+// contracts live in backend/proto; generated code is under each service's
+// internal/pb.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/reqctx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultTimeout bounds outgoing calls whose context carries no deadline.
+const DefaultTimeout = 3 * time.Second
+
+// NewServer returns a gRPC server whose handlers see the caller's correlation ids
+// and deadline in ctx. Errors that are not already statuses become Internal, or
+// DeadlineExceeded/Canceled when the context ran out.
+func NewServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.ChainUnaryInterceptor(serverInterceptor))
+	return grpc.NewServer(opts...)
+}
+
+// Dial connects to another service's internal API. Calls carry the correlation ids
+// from ctx and get DefaultTimeout unless ctx already has a deadline. Traffic stays
+// on the private network, so the transport is plaintext.
+func Dial(addr string) (*grpc.ClientConn, error) {
+	return grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(clientInterceptor),
+	)
+}
+
+func serverInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = reqctx.Extract(ctx, mdCarrier(md))
+	ctx, span := observability.StartSpan(ctx, info.FullMethod, trace.SpanKindServer, attribute.String("rpc.system", "grpc"))
+	resp, err := handler(ctx, req)
+	err = toStatus(err)
+	observability.EndSpan(span, err)
+	return resp, err
+}
+
+func clientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+	ctx, span := observability.StartSpan(ctx, method, trace.SpanKindClient, attribute.String("rpc.system", "grpc"))
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	reqctx.Inject(ctx, mdCarrier(md))
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// mdCarrier lets reqctx read and write gRPC metadata.
+type mdCarrier metadata.MD
+
+func (c mdCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c mdCarrier) Set(key, value string) { metadata.MD(c).Set(key, value) }
+
+// HTTPStatus maps the status of a failed internal call to the HTTP status a REST
+// handler should answer with: client mistakes pass through, an unreachable or slow
+// dependency is 503/504, and anything else is a 500.
+func HTTPStatus(err error) int {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		return 400
+	case codes.PermissionDenied:
+		return 403
+	case codes.NotFound:
+		return 404
+	case codes.AlreadyExists:
+		return 409
+	case codes.Unavailable:
+		return 503
+	case codes.DeadlineExceeded:
+		return 504
+	default:
+		return 500
+	}
+}
@@ -0,0 +1,110 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fathima-sithara/platform/reqctx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// probe is a health server whose Check fails with the error named by the
+// requested service and records the context the handler ran under.
+type probe struct {
+	grpc_health_v1.UnimplementedHealthServer
+	errs     map[string]error
+	info     reqctx.Info
+	deadline time.Duration
+}
+
+func (p *probe) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	p.info = reqctx.FromContext(ctx)
+	if d, ok := ctx.Deadline(); ok {
+		p.deadline = time.Until(d)
+	}
+	if err := p.errs[req.GetService()]; err != nil {
+		return nil, err
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+// serve runs p behind NewServer on a loopback port and returns a client that
+// calls it through Dial.
+func serve(t *testing.T, p *probe) grpc_health_v1.HealthClient {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer()
+	grpc_health_v1.RegisterHealthServer(srv, p)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	conn, err := Dial(lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+func TestServerAndDial(t *testing.T) {
+	p := &probe{errs: map[string]error{
+		"plain":    errors.New("mongo down"),
+		"status":   status.Error(codes.NotFound, "chat not found"),
+		"deadline": context.DeadlineExceeded,
+	}}
+	client := serve(t, p)
+
+	tests := []struct {
+		service  string
+		wantCode codes.Code
+		wantHTTP int
+	}{
+		{service: "", wantCode: codes.OK},
+		{service: "plain", wantCode: codes.Internal, wantHTTP: 500},
+		{service: "status", wantCode: codes.NotFound, wantHTTP: 404},
+		{service: "deadline", wantCode: codes.DeadlineExceeded, wantHTTP: 504},
+	}
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			tp := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+			ctx := reqctx.NewContext(context.Background(), reqctx.Info{RequestID: "req-" + tt.service, Traceparent: tp})
+			_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: tt.service})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %v (%v), want %v", code, err, tt.wantCode)
+			}
+			if err != nil && HTTPStatus(err) != tt.wantHTTP {
+				t.Fatalf("HTTPStatus = %d, want %d", HTTPStatus(err), tt.wantHTTP)
+			}
+			if p.info.RequestID != "req-"+tt.service {
+				t.Fatalf("server saw request id %q, want %q", p.info.RequestID, "req-"+tt.service)
+			}
+			if p.info.TraceID() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Fatalf("server saw traceparent %q, want trace 4bf92f...", p.info.Traceparent)
+			}
+			if p.deadline <= 0 || p.deadline > DefaultTimeout {
+				t.Fatalf("server deadline in %v, want the default %v", p.deadline, DefaultTimeout)
+			}
+		})
+	}
+}
+
+func TestDialKeepsCallerDeadline(t *testing.T) {
+	p := &probe{}
+	client := serve(t, p)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if p.deadline <= DefaultTimeout {
+		t.Fatalf("server deadline in %v, want the caller's 10s", p.deadline)
+	}
+}
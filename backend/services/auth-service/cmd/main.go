@@ -26,7 +26,7 @@ func main() {
 	defer cleanup(context.Background())
 	sugar := app.Sugar
 
-	fiberApp := server.New(app.Config, app.Handler, app.Logger)
+	fiberApp := server.New(app.Config, app.Handler, app.Health, app.Logger)
 
 	listenAddr := fmt.Sprintf(":%d", app.Config.App.Port)
 	go func() {
@@ -6,12 +6,14 @@ import (
 
 	"github.com/fathima-sithara/auth-service/internal/config"
 	"github.com/fathima-sithara/auth-service/internal/database"
-	"github.com/fathima-sithara/auth-service/internal/emailJS"
+	"github.com/fathima-sithara/auth-service/internal/emailjs"
 	"github.com/fathima-sithara/auth-service/internal/handlers"
 	"github.com/fathima-sithara/auth-service/internal/repository"
 	"github.com/fathima-sithara/auth-service/internal/services"
 	"github.com/fathima-sithara/auth-service/internal/twilio"
 	"github.com/fathima-sithara/auth-service/internal/utils"
+	"github.com/fathima-sithara/platform/health"
+	"github.com/fathima-sithara/platform/observability"
 	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
@@ -26,6 +28,7 @@ type AppContext struct {
 	Twilio  *twilio.Client
 	EmailJS *emailJS.Client
 	Handler *handlers.Handler
+	Health  *health.Checker
 }
 
 type CleanupFn func(context.Context)
@@ -47,6 +50,11 @@ func Init() (*AppContext, CleanupFn, error) {
 	app := &AppContext{Config: cfg, Logger: logger, Sugar: sugar}
 	sugar.Infof("Starting service in %s environment", cfg.App.Env)
 
+	shutdownTracing, err := observability.InitTracing(context.Background(), "auth-service")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	db, mongoClient, err := database.ConnectMongo(cfg.Mongo.URI, cfg.Mongo.Database, sugar)
 	if err != nil {
 		return nil, nil, err
@@ -71,7 +79,11 @@ func Init() (*AppContext, CleanupFn, error) {
 
 	userRepo := repository.NewMongoUserRepo(db, cfg.User.Collection)
 	authSvc := services.NewAuthService(userRepo, app.Twilio, app.EmailJS, rdb, jwtMgr, cfg.Security.OtpTTLMinutes, cfg.Security.OtpRateLimitPerPhonePerHour, logger)
-	app.Handler = handlers.NewHandler(authSvc, logger)
+	app.Handler = handlers.NewHandler(authSvc, cfg.Session, logger)
+
+	app.Health = health.New()
+	app.Health.Add("mongo", func(ctx context.Context) error { return mongoClient.Ping(ctx, nil) })
+	app.Health.Add("redis", func(ctx context.Context) error { return rdb.Ping(ctx).Err() })
 
 	return app, func(ctx context.Context) {
 		if cerr := logger.Sync(); cerr != nil {
@@ -85,5 +97,9 @@ func Init() (*AppContext, CleanupFn, error) {
 		if cerr := rdb.Close(); cerr != nil {
 			app.Sugar.Errorf("Redis client close error: %v", cerr)
 		}
+
+		if cerr := shutdownTracing(ctx); cerr != nil {
+			app.Sugar.Errorf("Tracer shutdown error: %v", cerr)
+		}
 	}, nil
 }
@@ -62,6 +62,24 @@ type SecurityCfg struct {
 	PasswordHashCost            int `yaml:"passwordHashCost"`
 }
 
+// SessionCfg controls cookie sessions for browser clients. With CookieMode on, a
+// client sending "X-Auth-Mode: cookie" gets its tokens as HttpOnly cookies instead
+// of in the response body.
+type SessionCfg struct {
+	CookieMode    bool   `yaml:"cookieMode"`
+	AccessCookie  string `yaml:"accessCookie"`
+	RefreshCookie string `yaml:"refreshCookie"`
+	CSRFCookie    string `yaml:"csrfCookie"`
+	// RefreshPath scopes the refresh cookie to the API so it is not sent with
+	// every request to the site
+	RefreshPath string `yaml:"refreshPath"`
+	Domain      string `yaml:"domain"`
+	Secure      bool   `yaml:"-"`
+
+	AccessTTL  time.Duration `yaml:"-"`
+	RefreshTTL time.Duration `yaml:"-"`
+}
+
 type Config struct {
 	App      AppCfg      `yaml:"app"`
 	Mongo    MongoCfg    `yaml:"mongo"`
@@ -70,6 +88,7 @@ type Config struct {
 	EmailJS  EmailJSCfg  `yaml:"emailjs"`
 	User     UserCfg     `yaml:"user"`
 	Security SecurityCfg `yaml:"security"`
+	Session  SessionCfg  `yaml:"session"`
 }
 
 func Load(path string) (*Config, error) {
@@ -139,6 +158,27 @@ func Load(path string) (*Config, error) {
 		}
 	})
 
+	if v := os.Getenv("SESSION_COOKIE_MODE"); v != "" {
+		cfg.Session.CookieMode = v == "true"
+	}
+	override("COOKIE_DOMAIN", func(v string) { cfg.Session.Domain = v })
+	cfg.Session.Secure = cfg.App.Env != "development"
+	override("COOKIE_SECURE", func(v string) { cfg.Session.Secure = v == "true" })
+	if cfg.Session.AccessCookie == "" {
+		cfg.Session.AccessCookie = "access_token"
+	}
+	if cfg.Session.RefreshCookie == "" {
+		cfg.Session.RefreshCookie = "refresh_token"
+	}
+	if cfg.Session.CSRFCookie == "" {
+		cfg.Session.CSRFCookie = "csrf_token"
+	}
+	if cfg.Session.RefreshPath == "" {
+		cfg.Session.RefreshPath = "/api"
+	}
+	cfg.Session.AccessTTL = time.Duration(cfg.App.JWT.AccessTTLMinutes) * time.Minute
+	cfg.Session.RefreshTTL = time.Duration(cfg.App.JWT.RefreshTTLDays) * 24 * time.Hour
+
 	// if cfg.App.JWT.Secret == "" {
 	// 	return nil, errors.New("JWT_SECRET is required (set in .env or config.yaml)")
 	// }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/fathima-sithara/platform/observability"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
@@ -13,7 +14,7 @@ func ConnectMongo(uri, dbName string, logger *zap.SugaredLogger) (*mongo.Databas
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetMonitor(observability.MongoMonitor()))
 	if err != nil {
 		logger.Errorf("MongoDB connection failed: %v", err)
 		return nil, nil, err
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/fathima-sithara/platform/observability"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
@@ -14,6 +15,7 @@ func ConnectRedis(addr, password string, db int, logger *zap.SugaredLogger) (*re
 		Password: password,
 		DB:       db,
 	})
+	observability.InstrumentRedis(rdb)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -3,18 +3,26 @@ package handlers
 import (
 	"errors"
 
+	"github.com/fathima-sithara/auth-service/internal/config"
 	"github.com/fathima-sithara/auth-service/internal/services"
+	"github.com/fathima-sithara/platform/reqctx"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 )
 
 type Handler struct {
-	svc *services.AuthService
-	log *zap.Logger
+	svc     *services.AuthService
+	session config.SessionCfg
+	log     *zap.Logger
 }
 
-func NewHandler(svc *services.AuthService, logger *zap.Logger) *Handler {
-	return &Handler{svc: svc, log: logger}
+func NewHandler(svc *services.AuthService, session config.SessionCfg, logger *zap.Logger) *Handler {
+	return &Handler{svc: svc, session: session, log: logger}
+}
+
+// logger returns the handler logger tagged with the request's correlation ids.
+func (h *Handler) logger(c *fiber.Ctx) *zap.Logger {
+	return h.log.With(reqctx.Fields(c.UserContext())...)
 }
 
 type errorResp struct {
@@ -39,7 +47,7 @@ type registerReq struct {
 func (h *Handler) Register(c *fiber.Ctx) error {
 	var req registerReq
 	if err := c.BodyParser(&req); err != nil {
-		h.log.Error("failed to parse register request body", zap.Error(err))
+		h.logger(c).Error("failed to parse register request body", zap.Error(err))
 		return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "invalid request body"})
 	}
 
@@ -47,9 +55,9 @@ func (h *Handler) Register(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "username, email, and password are required"})
 	}
 
-	err := h.svc.InitiateEmailRegistration(c.Context(), req.Username, req.Email, req.Password)
+	err := h.svc.InitiateEmailRegistration(c.UserContext(), req.Username, req.Email, req.Password)
 	if err != nil {
-		h.log.Error("failed to initiate email registration", zap.Error(err),
+		h.logger(c).Error("failed to initiate email registration", zap.Error(err),
 			zap.String("username", req.Username), zap.String("email", req.Email))
 		if errors.Is(err, services.ErrUserAlreadyExists) {
 			return c.Status(fiber.StatusConflict).JSON(errorResp{Error: err.Error()})
@@ -71,7 +79,7 @@ type verifyEmailReq struct {
 func (h *Handler) VerifyEmail(c *fiber.Ctx) error {
 	var req verifyEmailReq
 	if err := c.BodyParser(&req); err != nil {
-		h.log.Error("failed to parse verify email request body", zap.Error(err))
+		h.logger(c).Error("failed to parse verify email request body", zap.Error(err))
 		return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "invalid request body"})
 	}
 
@@ -79,18 +87,18 @@ func (h *Handler) VerifyEmail(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "email and OTP are required"})
 	}
 
-	access, refresh, err := h.svc.CompleteEmailVerification(c.Context(), req.Email, req.OTP)
+	access, refresh, err := h.svc.CompleteEmailVerification(c.UserContext(), req.Email, req.OTP)
 	if err != nil {
-		h.log.Error("failed to complete email verification", zap.Error(err), zap.String("email", req.Email))
+		h.logger(c).Error("failed to complete email verification", zap.Error(err), zap.String("email", req.Email))
 		if errors.Is(err, services.ErrInvalidOTP) {
 			return c.Status(fiber.StatusUnauthorized).JSON(errorResp{Error: err.Error()})
 		}
-		if errors.Is(err, services.ErrUserNotFound) { 
+		if errors.Is(err, services.ErrUserNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(errorResp{Error: err.Error()})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResp{Error: "failed to verify email"})
 	}
-	return c.JSON(tokenResp{AccessToken: access, RefreshToken: refresh})
+	return h.respondTokens(c, access, refresh)
 }
 
 type loginReq struct {
@@ -101,7 +109,7 @@ type loginReq struct {
 func (h *Handler) Login(c *fiber.Ctx) error {
 	var req loginReq
 	if err := c.BodyParser(&req); err != nil {
-		h.log.Error("failed to parse login request body", zap.Error(err))
+		h.logger(c).Error("failed to parse login request body", zap.Error(err))
 		return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "invalid request body"})
 	}
 
@@ -109,9 +117,9 @@ func (h *Handler) Login(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "email and password are required"})
 	}
 
-	access, refresh, err := h.svc.LoginWithPassword(c.Context(), req.Email, req.Password)
+	access, refresh, err := h.svc.LoginWithPassword(c.UserContext(), req.Email, req.Password)
 	if err != nil {
-		h.log.Error("login failed", zap.Error(err), zap.String("email", req.Email))
+		h.logger(c).Error("login failed", zap.Error(err), zap.String("email", req.Email))
 		if errors.Is(err, services.ErrInvalidCredentials) || errors.Is(err, services.ErrUserNotFound) {
 			return c.Status(fiber.StatusUnauthorized).JSON(errorResp{Error: "invalid email or password"})
 		}
@@ -120,7 +128,7 @@ func (h *Handler) Login(c *fiber.Ctx) error {
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResp{Error: "failed to login"})
 	}
-	return c.JSON(tokenResp{AccessToken: access, RefreshToken: refresh})
+	return h.respondTokens(c, access, refresh)
 }
 
 type requestOTPReq struct {
@@ -131,7 +139,7 @@ type requestOTPReq struct {
 func (h *Handler) RequestOTP(c *fiber.Ctx) error {
 	var req requestOTPReq
 	if err := c.BodyParser(&req); err != nil {
-		h.log.Error("failed to parse request OTP request body", zap.Error(err))
+		h.logger(c).Error("failed to parse request OTP request body", zap.Error(err))
 		return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "invalid request body"})
 	}
 
@@ -139,11 +147,11 @@ func (h *Handler) RequestOTP(c *fiber.Ctx) error {
 	// 	return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "phone number is required"})
 	// }
 
-	if err := h.svc.RequestOTP(c.Context(), req.Phone, req.Email); err != nil {
+	if err := h.svc.RequestOTP(c.UserContext(), req.Phone, req.Email); err != nil {
 		if errors.Is(err, services.ErrTooManyRequests) {
 			return c.Status(fiber.StatusTooManyRequests).JSON(errorResp{Error: err.Error()})
 		}
-		h.log.Error("request phone OTP failed", zap.Error(err), zap.String("phone", req.Phone))
+		h.logger(c).Error("request phone OTP failed", zap.Error(err), zap.String("phone", req.Phone))
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResp{Error: "failed to send OTP"})
 	}
 	return c.Status(fiber.StatusOK).JSON(messageResp{Message: "OTP sent successfully"})
@@ -158,7 +166,7 @@ type verifyOTPReq struct {
 func (h *Handler) VerifyOTP(c *fiber.Ctx) error {
 	var req verifyOTPReq
 	if err := c.BodyParser(&req); err != nil {
-		h.log.Error("failed to parse verify OTP request body", zap.Error(err))
+		h.logger(c).Error("failed to parse verify OTP request body", zap.Error(err))
 		return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "invalid request body"})
 	}
 
@@ -166,36 +174,45 @@ func (h *Handler) VerifyOTP(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "phone and OTP are required"})
 	}
 
-	access, refresh, err := h.svc.VerifyOTP(c.Context(), req.Phone, req.Email, req.OTP)
+	access, refresh, err := h.svc.VerifyOTP(c.UserContext(), req.Phone, req.Email, req.OTP)
 	if err != nil {
 		if errors.Is(err, services.ErrInvalidOTP) {
 			return c.Status(fiber.StatusUnauthorized).JSON(errorResp{Error: err.Error()})
 		}
-		h.log.Error("verify phone OTP failed", zap.Error(err), zap.String("phone", req.Phone))
+		h.logger(c).Error("verify phone OTP failed", zap.Error(err), zap.String("phone", req.Phone))
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResp{Error: "failed to verify OTP"})
 	}
-	return c.JSON(tokenResp{AccessToken: access, RefreshToken: refresh})
+	return h.respondTokens(c, access, refresh)
 }
 
 func (h *Handler) Refresh(c *fiber.Ctx) error {
 	var req struct {
 		RefreshToken string `json:"refresh_token"`
 	}
-	if err := c.BodyParser(&req); err != nil {
-		h.log.Error("failed to parse refresh token request body", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "invalid request body"})
+	// cookie sessions send no body; the refresh token comes from the cookie
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			h.logger(c).Error("failed to parse refresh token request body", zap.Error(err))
+			return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "invalid request body"})
+		}
+	}
+	if req.RefreshToken == "" && h.session.CookieMode {
+		req.RefreshToken = c.Cookies(h.session.RefreshCookie)
+	}
+	if req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "refresh_token is required"})
 	}
 
-	access, refresh, err := h.svc.RefreshToken(c.Context(), req.RefreshToken)
+	access, refresh, err := h.svc.RefreshToken(c.UserContext(), req.RefreshToken)
 	if err != nil {
-		h.log.Error("failed to refresh token", zap.Error(err))
+		h.logger(c).Error("failed to refresh token", zap.Error(err))
 		if errors.Is(err, services.ErrInvalidRefreshToken) {
 			return c.Status(fiber.StatusUnauthorized).JSON(errorResp{Error: err.Error()})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResp{Error: "failed to refresh token"})
 	}
 
-	return c.JSON(tokenResp{AccessToken: access, RefreshToken: refresh})
+	return h.respondTokens(c, access, refresh)
 }
 
 type logoutReq struct {
@@ -207,14 +224,21 @@ func (h *Handler) Logout(c *fiber.Ctx) error {
 	userID := c.Locals("userID")
 	if userID == nil {
 		var req logoutReq
-		if err := c.BodyParser(&req); err != nil {
-			h.log.Error("failed to parse logout request body", zap.Error(err))
-			return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "invalid request body"})
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				h.logger(c).Error("failed to parse logout request body", zap.Error(err))
+				return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "invalid request body"})
+			}
+		}
+		if req.AccessToken == "" && h.session.CookieMode {
+			req.AccessToken = c.Cookies(h.session.AccessCookie)
 		}
 
 		parsedUserID, err := h.svc.GetUserIDFromAccessToken(req.AccessToken)
 		if err != nil {
-			h.log.Warn("Failed to parse access token for logout", zap.Error(err))
+			h.logger(c).Warn("Failed to parse access token for logout", zap.Error(err))
+			// an expired session should still be able to drop its cookies
+			h.clearSession(c)
 			return c.Status(fiber.StatusUnauthorized).JSON(errorResp{Error: "invalid access token"})
 		}
 		userID = parsedUserID
@@ -222,18 +246,19 @@ func (h *Handler) Logout(c *fiber.Ctx) error {
 
 	uidStr, ok := userID.(string)
 	if !ok {
-		h.log.Error("userID not found in context or invalid type for logout", zap.Any("userID", userID))
+		h.logger(c).Error("userID not found in context or invalid type for logout", zap.Any("userID", userID))
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResp{Error: "authentication context missing"})
 	}
 
-	err := h.svc.Logout(c.Context(), uidStr)
+	err := h.svc.Logout(c.UserContext(), uidStr)
 	if err != nil {
-		h.log.Error("failed to logout user", zap.Error(err), zap.String("userID", uidStr))
+		h.logger(c).Error("failed to logout user", zap.Error(err), zap.String("userID", uidStr))
 		if errors.Is(err, services.ErrUserNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(errorResp{Error: err.Error()})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResp{Error: "failed to logout"})
 	}
+	h.clearSession(c)
 
 	return c.Status(fiber.StatusOK).JSON(messageResp{Message: "logged out"})
 }
@@ -246,7 +271,7 @@ type changePasswordReq struct {
 func (h *Handler) ChangePassword(c *fiber.Ctx) error {
 	var req changePasswordReq
 	if err := c.BodyParser(&req); err != nil {
-		h.log.Error("failed to parse change password request body", zap.Error(err))
+		h.logger(c).Error("failed to parse change password request body", zap.Error(err))
 		return c.Status(fiber.StatusBadRequest).JSON(errorResp{Error: "invalid request body"})
 	}
 
@@ -256,19 +281,19 @@ func (h *Handler) ChangePassword(c *fiber.Ctx) error {
 
 	userID := c.Locals("userID")
 	if userID == nil {
-		h.log.Warn("userID not found in context for change password")
+		h.logger(c).Warn("userID not found in context for change password")
 		return c.Status(fiber.StatusUnauthorized).JSON(errorResp{Error: "unauthorized"})
 	}
 
 	uidStr, ok := userID.(string)
 	if !ok {
-		h.log.Error("userID in context is not a string for change password", zap.Any("userID", userID))
+		h.logger(c).Error("userID in context is not a string for change password", zap.Any("userID", userID))
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResp{Error: "authentication context error"})
 	}
 
-	err := h.svc.ChangePassword(c.Context(), uidStr, req.OldPassword, req.NewPassword)
+	err := h.svc.ChangePassword(c.UserContext(), uidStr, req.OldPassword, req.NewPassword)
 	if err != nil {
-		h.log.Error("failed to change password", zap.Error(err), zap.String("userID", uidStr))
+		h.logger(c).Error("failed to change password", zap.Error(err), zap.String("userID", uidStr))
 		if errors.Is(err, services.ErrUserNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(errorResp{Error: err.Error()})
 		}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// headerAuthMode lets a browser client ask for a cookie session.
+const headerAuthMode = "X-Auth-Mode"
+
+type sessionResp struct {
+	AccessToken string `json:"access_token"`
+	CSRFToken   string `json:"csrf_token"`
+}
+
+// cookieMode reports whether cookie sessions are enabled and the client asked for one.
+func (h *Handler) cookieMode(c *fiber.Ctx) bool {
+	return h.session.CookieMode && strings.EqualFold(c.Get(headerAuthMode), "cookie")
+}
+
+// respondTokens sends a freshly issued token pair. In cookie mode the refresh token
+// only travels in an HttpOnly cookie scoped to the API, the access token is also set
+// as a cookie for the gateway, and the CSRF token is rotated so a token planted
+// before login cannot be reused.
+func (h *Handler) respondTokens(c *fiber.Ctx, access, refresh string) error {
+	if !h.cookieMode(c) {
+		return c.JSON(tokenResp{AccessToken: access, RefreshToken: refresh})
+	}
+
+	h.setCookie(c, h.session.AccessCookie, access, "/", h.session.AccessTTL, true, fiber.CookieSameSiteLaxMode)
+	h.setCookie(c, h.session.RefreshCookie, refresh, h.session.RefreshPath, h.session.RefreshTTL, true, fiber.CookieSameSiteStrictMode)
+
+	csrf := newCSRFToken()
+	// readable by scripts on purpose: the client echoes it in X-CSRF-Token
+	h.setCookie(c, h.session.CSRFCookie, csrf, "/", 0, false, fiber.CookieSameSiteLaxMode)
+	c.Set("X-CSRF-Token", csrf)
+
+	return c.JSON(sessionResp{AccessToken: access, CSRFToken: csrf})
+}
+
+// clearSession expires the session cookies.
+func (h *Handler) clearSession(c *fiber.Ctx) {
+	if !h.session.CookieMode {
+		return
+	}
+	h.setCookie(c, h.session.AccessCookie, "", "/", -1, true, fiber.CookieSameSiteLaxMode)
+	h.setCookie(c, h.session.RefreshCookie, "", h.session.RefreshPath, -1, true, fiber.CookieSameSiteStrictMode)
+}
+
+// setCookie sets a session cookie; a negative ttl deletes it and zero makes it last
+// for the browser session.
+func (h *Handler) setCookie(c *fiber.Ctx, name, value, path string, ttl time.Duration, httpOnly bool, sameSite string) {
+	ck := &fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   h.session.Domain,
+		Secure:   h.session.Secure,
+		HTTPOnly: httpOnly,
+		SameSite: sameSite,
+	}
+	switch {
+	case ttl < 0:
+		ck.MaxAge = -1
+		ck.Expires = time.Unix(0, 0)
+	case ttl > 0:
+		ck.MaxAge = int(ttl / time.Second)
+	}
+	c.Cookie(ck)
+}
+
+func newCSRFToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fathima-sithara/auth-service/internal/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+func sessionApp(cookieMode bool) *fiber.App {
+	h := &Handler{session: config.SessionCfg{
+		CookieMode:    cookieMode,
+		AccessCookie:  "access_token",
+		RefreshCookie: "refresh_token",
+		CSRFCookie:    "csrf_token",
+		RefreshPath:   "/api/v1/auth",
+		Secure:        true,
+		AccessTTL:     15 * time.Minute,
+		RefreshTTL:    7 * 24 * time.Hour,
+	}}
+	app := fiber.New()
+	app.Post("/login", func(c *fiber.Ctx) error { return h.respondTokens(c, "acc", "ref") })
+	app.Post("/logout", func(c *fiber.Ctx) error {
+		h.clearSession(c)
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+func TestRespondTokens(t *testing.T) {
+	tests := []struct {
+		name       string
+		cookieMode bool
+		authMode   string
+		wantCookie bool
+	}{
+		{name: "body tokens by default", cookieMode: true},
+		{name: "cookie session on request", cookieMode: true, authMode: "Cookie", wantCookie: true},
+		{name: "cookie mode disabled", cookieMode: false, authMode: "cookie"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/login", nil)
+			if tt.authMode != "" {
+				req.Header.Set(headerAuthMode, tt.authMode)
+			}
+			resp, err := sessionApp(tt.cookieMode).Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body map[string]string
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			cookies := map[string]*http.Cookie{}
+			for _, ck := range resp.Cookies() {
+				cookies[ck.Name] = ck
+			}
+
+			if !tt.wantCookie {
+				if len(cookies) != 0 || body["refresh_token"] != "ref" || body["access_token"] != "acc" {
+					t.Fatalf("body = %v, cookies = %v; want both tokens in the body and no cookies", body, cookies)
+				}
+				return
+			}
+
+			if _, leaked := body["refresh_token"]; leaked {
+				t.Fatal("refresh token leaked into the body of a cookie session")
+			}
+			access, refresh, csrf := cookies["access_token"], cookies["refresh_token"], cookies["csrf_token"]
+			if access == nil || access.Value != "acc" || !access.HttpOnly || !access.Secure || access.Path != "/" || access.MaxAge != 900 {
+				t.Errorf("access cookie = %+v", access)
+			}
+			if refresh == nil || refresh.Value != "ref" || !refresh.HttpOnly || refresh.Path != "/api/v1/auth" || refresh.SameSite != http.SameSiteStrictMode {
+				t.Errorf("refresh cookie = %+v", refresh)
+			}
+			if csrf == nil || csrf.HttpOnly || csrf.Value == "" {
+				t.Fatalf("csrf cookie = %+v, want a script readable token", csrf)
+			}
+			if body["csrf_token"] != csrf.Value || resp.Header.Get("X-CSRF-Token") != csrf.Value {
+				t.Errorf("csrf token not echoed: body %q, header %q, cookie %q", body["csrf_token"], resp.Header.Get("X-CSRF-Token"), csrf.Value)
+			}
+		})
+	}
+}
+
+func TestRespondTokensRotatesCSRF(t *testing.T) {
+	app := sessionApp(true)
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/login", nil)
+		req.Header.Set(headerAuthMode, "cookie")
+		req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "planted"})
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tok := resp.Header.Get("X-CSRF-Token")
+		if tok == "planted" || seen[tok] {
+			t.Fatalf("login %d reused csrf token %q", i, tok)
+		}
+		seen[tok] = true
+	}
+}
+
+func TestClearSession(t *testing.T) {
+	tests := []struct {
+		name       string
+		cookieMode bool
+		want       []string
+	}{
+		{name: "expires the session cookies", cookieMode: true, want: []string{"access_token", "refresh_token"}},
+		{name: "no cookies without cookie mode", cookieMode: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := sessionApp(tt.cookieMode).Test(httptest.NewRequest("POST", "/logout", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := resp.Cookies()
+			if len(got) != len(tt.want) {
+				t.Fatalf("cookies = %v, want %v", got, tt.want)
+			}
+			for i, ck := range got {
+				if ck.Name != tt.want[i] || ck.Value != "" || !ck.Expires.Equal(time.Unix(0, 0)) {
+					t.Errorf("cookie %d = %+v, want %s expired", i, ck, tt.want[i])
+				}
+			}
+		})
+	}
+}
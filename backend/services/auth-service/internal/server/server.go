@@ -6,20 +6,26 @@ import (
 	"github.com/fathima-sithara/auth-service/internal/config"
 	"github.com/fathima-sithara/auth-service/internal/handlers"
 	"github.com/fathima-sithara/auth-service/internal/routes"
+	"github.com/fathima-sithara/platform/health"
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/reqctx"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
 	"go.uber.org/zap"
 )
 
-func New(cfg *config.Config, h *handlers.Handler, logger *zap.Logger) *fiber.App {
+func New(cfg *config.Config, h *handlers.Handler, hc *health.Checker, logger *zap.Logger) *fiber.App {
 	app := fiber.New(fiber.Config{
 		ReadTimeout:  cfg.App.ReadTimeout,
 		WriteTimeout: cfg.App.WriteTimeout,
 		IdleTimeout:  cfg.App.IdleTimeout,
 	})
 
-	app.Use(cors.New())
-	app.Use(zapLoggerMiddleware(logger)) 
+	app.Use(reqctx.Middleware())
+	app.Use(observability.Middleware())
+	app.Use(zapLoggerMiddleware(logger))
+
+	app.Get("/metrics", observability.MetricsHandler())
+	hc.Register(app)
 
 	routes.Setup(app, h)
 
@@ -33,13 +39,13 @@ func zapLoggerMiddleware(logger *zap.Logger) fiber.Handler {
 		latency := time.Since(start)
 		status := c.Response().StatusCode()
 
-		fields := []zap.Field{
+		fields := append(reqctx.Fields(c.UserContext()),
 			zap.String("method", c.Method()),
 			zap.String("path", c.Path()),
 			zap.String("ip", c.IP()),
 			zap.Int("status", status),
 			zap.Duration("latency", latency),
-		}
+		)
 
 		if err != nil {
 			logger.Error("HTTP Request Error", append(fields, zap.Error(err))...)
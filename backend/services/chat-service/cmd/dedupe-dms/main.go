@@ -0,0 +1,135 @@
+// Command dedupe-dms merges the duplicate direct chats created before DMs were
+// keyed by their member pair, and stamps dm_key on the chats it keeps.
+//
+// For every pair it keeps the chat that already has a dm_key, or else the oldest
+// one. It asks message-service (NATS request/reply on chat.merged) to move the
+// duplicates' messages over, and deletes the duplicates only after that succeeds.
+// It uses the service's own configuration and only prints its plan unless run
+// with -apply. It is safe to run again after a failure.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/fathima-sithara/message-service/internal/config"
+	"github.com/fathima-sithara/message-service/internal/events"
+	"github.com/fathima-sithara/message-service/internal/models"
+	"github.com/fathima-sithara/message-service/internal/repository"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	apply := flag.Bool("apply", false, "merge and delete duplicates instead of only reporting them")
+	timeout := flag.Duration("merge-timeout", 30*time.Second, "how long to wait for message-service per merge")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("config:", err)
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.Mongo.URI))
+	if err != nil {
+		log.Fatal("mongo connect:", err)
+	}
+	defer client.Disconnect(ctx)
+	repo := repository.NewMongoRepository(client.Database(cfg.Mongo.Database).Collection("chats"))
+
+	var pub *events.Publisher
+	if *apply {
+		if pub, err = events.NewPublisher(cfg.NATS.URL); err != nil {
+			log.Fatal("nats:", err)
+		}
+	}
+
+	chats, err := repo.ListDirectChats(ctx)
+	if err != nil {
+		log.Fatal("list chats:", err)
+	}
+
+	var keys []string
+	pairs := map[string][]*models.Chat{}
+	for _, c := range chats {
+		if len(c.Members) != 2 || c.Members[0] == c.Members[1] {
+			log.Printf("skip %s: %d members", c.ID, len(c.Members))
+			continue
+		}
+		key := models.DMKey(c.Members[0], c.Members[1])
+		if _, ok := pairs[key]; !ok {
+			keys = append(keys, key)
+		}
+		pairs[key] = append(pairs[key], c)
+	}
+
+	var merged, keyed, failed int
+	for _, key := range keys {
+		keep, dups := pick(pairs[key], key)
+		if len(dups) == 0 && keep.DMKey == key {
+			continue
+		}
+		ids := make([]string, len(dups))
+		for i, d := range dups {
+			ids[i] = d.ID
+		}
+		log.Printf("%s: keep %s, merge %v", key, keep.ID, ids)
+		if !*apply {
+			continue
+		}
+
+		if len(ids) > 0 {
+			if err := pub.RequestChatMerged(ctx, keep.ID, ids, *timeout); err != nil {
+				log.Printf("%s: move messages: %v", key, err)
+				failed++
+				continue
+			}
+			if err := repo.DeleteChats(ctx, ids); err != nil {
+				log.Printf("%s: delete duplicates: %v", key, err)
+				failed++
+				continue
+			}
+			merged += len(ids)
+		}
+		if keep.DMKey != key {
+			if err := repo.SetDMKey(ctx, keep.ID, key); err != nil {
+				log.Printf("%s: set dm_key: %v", key, err)
+				failed++
+				continue
+			}
+			keyed++
+		}
+	}
+
+	if !*apply {
+		log.Printf("dry run: %d direct chats in %d pairs; rerun with -apply", len(chats), len(keys))
+		return
+	}
+	log.Printf("done: %d duplicates merged, %d chats keyed, %d pairs failed", merged, keyed, failed)
+	if failed > 0 {
+		log.Fatal("some pairs were not merged; fix the errors above and run again")
+	}
+}
+
+// pick chooses the chat to keep for a pair from chats, which are oldest first:
+// the one already holding the key, so the pair never lacks a keyed chat while
+// the merge runs, or else the oldest.
+func pick(chats []*models.Chat, key string) (keep *models.Chat, dups []*models.Chat) {
+	keep = chats[0]
+	for _, c := range chats {
+		if c.DMKey == key {
+			keep = c
+			break
+		}
+	}
+	for _, c := range chats {
+		if c != keep {
+			dups = append(dups, c)
+		}
+	}
+	return keep, dups
+}
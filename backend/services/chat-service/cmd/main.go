@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
@@ -12,12 +13,21 @@ import (
 	"github.com/fathima-sithara/message-service/internal/auth"
 	"github.com/fathima-sithara/message-service/internal/config"
 	"github.com/fathima-sithara/message-service/internal/events"
+	"github.com/fathima-sithara/message-service/internal/pb/chatv1"
+	"github.com/fathima-sithara/message-service/internal/pb/messagev1"
+	"github.com/fathima-sithara/message-service/internal/pb/userv1"
 	"github.com/fathima-sithara/message-service/internal/repository"
+	rpcsrv "github.com/fathima-sithara/message-service/internal/rpc"
 	"github.com/fathima-sithara/message-service/internal/service"
 	"github.com/fathima-sithara/message-service/internal/ws"
+	"github.com/fathima-sithara/platform/health"
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/outbox"
+	"github.com/fathima-sithara/platform/rpc"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -26,9 +36,14 @@ func main() {
 		log.Fatal("config:", err)
 	}
 
+	shutdownTracing, err := observability.InitTracing(context.Background(), "chat-service")
+	if err != nil {
+		log.Fatal("tracing:", err)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.Mongo.URI))
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.Mongo.URI).SetMonitor(observability.MongoMonitor()))
 	if err != nil {
 		log.Fatal("mongo connect:", err)
 	}
@@ -38,6 +53,9 @@ func main() {
 
 	coll := client.Database(cfg.Mongo.Database).Collection("chats")
 	repo := repository.NewMongoRepository(coll)
+	invites := repository.NewInviteRepository(client.Database(cfg.Mongo.Database))
+	subs := repository.NewSubscriberRepository(client.Database(cfg.Mongo.Database))
+	ob := outbox.NewStore(client.Database(cfg.Mongo.Database), cfg.Outbox.Retention)
 
 	jv, err := auth.NewJWTValidator(cfg.JWT.PublicKeyPath, cfg.JWT.Algorithm, cfg.JWT.Secret)
 	if err != nil {
@@ -50,13 +68,68 @@ func main() {
 		pub = nil
 	}
 
-	svc := service.NewChatService(repo, pub)
-	wsSrv := ws.NewServer(svc, jv)
-	app := api.NewServer(cfg, svc, wsSrv, jv)
+	var users userv1.UserServiceClient
+	if cfg.GRPC.UserAddr != "" {
+		conn, err := rpc.Dial(cfg.GRPC.UserAddr)
+		if err != nil {
+			log.Fatal("user-service grpc:", err)
+		}
+		defer conn.Close()
+		users = userv1.NewUserServiceClient(conn)
+	}
+	var msgs messagev1.MessageServiceClient
+	if cfg.GRPC.MessageAddr != "" {
+		conn, err := rpc.Dial(cfg.GRPC.MessageAddr)
+		if err != nil {
+			log.Fatal("message-service grpc:", err)
+		}
+		defer conn.Close()
+		msgs = messagev1.NewMessageServiceClient(conn)
+	}
 
-	errs := make(chan error, 1)
+	sub, err := events.NewSubscriber(cfg.NATS.URL, repo, subs)
+	if err != nil {
+		log.Println("nats subscriber warn:", err)
+	} else if err := sub.Start("chat-service"); err != nil {
+		log.Println("nats subscribe warn:", err)
+	}
+
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	if pub != nil {
+		go outbox.NewRelay(ob, pub, cfg.Outbox.PollInterval).Run(relayCtx)
+	}
+
+	svc := service.NewChatService(repo, invites, subs, ob, users)
+	wsSrv := ws.NewServer(svc, jv, msgs)
+	if pub != nil {
+		if err := pub.SubscribeChatEvents(wsSrv.ApplyChatEvent); err != nil {
+			log.Println("nats subscribe warn:", err)
+		}
+	}
+	app := api.NewServer(cfg, svc, wsSrv)
+
+	hc := health.New()
+	hc.Add("mongo", func(ctx context.Context) error { return client.Ping(ctx, nil) })
+	hc.Add("nats", pub.Ready)
+	hc.Add("nats_subscriber", sub.Ready)
+	hc.Register(app)
+
+	errs := make(chan error, 2)
 	go func() { errs <- app.Listen(":" + cfg.App.PortString()) }()
 
+	var grpcSrv *grpc.Server
+	if cfg.GRPC.Port != 0 {
+		lis, err := net.Listen("tcp", ":"+cfg.GRPC.PortString())
+		if err != nil {
+			log.Fatal("grpc listen:", err)
+		}
+		grpcSrv = rpc.NewServer()
+		chatv1.RegisterChatServiceServer(grpcSrv, rpcsrv.NewChatServer(svc))
+		go func() { errs <- grpcSrv.Serve(lis) }()
+		log.Printf("grpc on :%s", cfg.GRPC.PortString())
+	}
+
 	log.Printf("started on :%s", cfg.App.PortString())
 
 	sig := make(chan os.Signal, 1)
@@ -73,7 +146,14 @@ func main() {
 	if err := app.Shutdown(); err != nil {
 		log.Println("fiber shutdown:", err)
 	}
+	if grpcSrv != nil {
+		grpcSrv.GracefulStop()
+	}
+	stopRelay()
 	if err := client.Disconnect(shutdownCtx); err != nil {
 		log.Println("mongo disconnect:", err)
 	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Println("tracing shutdown:", err)
+	}
 }
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/fathima-sithara/message-service/internal/cachetag"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Headers read by the api-gateway response cache: Surrogate-Key tags a cacheable
+// response and X-Cache-Purge evicts every cached response carrying a tag.
+const (
+	headerSurrogateKey = "Surrogate-Key"
+	headerCachePurge   = "X-Cache-Purge"
+)
+
+func cacheTags(c *fiber.Ctx, tags ...string) {
+	c.Set(headerSurrogateKey, strings.Join(tags, " "))
+}
+
+func purgeCache(c *fiber.Ctx, tags ...string) {
+	c.Set(headerCachePurge, strings.Join(tags, " "))
+}
+
+// purgeMemberLists evicts the chat lists of everyone in a new chat.
+func purgeMemberLists(c *fiber.Ctx, chatID string, members []string) {
+	purgeCache(c, cachetag.Members(chatID, members)...)
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/fathima-sithara/message-service/internal/cachetag"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *Server) createChannel(c *fiber.Ctx) error {
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Name == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid"})
+	}
+	user := c.Locals("user_id").(string)
+	chat, err := s.svc.CreateChannel(c.UserContext(), user, body.Name, body.Description)
+	if err != nil {
+		return fail(c, err)
+	}
+	purgeMemberLists(c, chat.ID, chat.Members)
+	return c.Status(201).JSON(fiber.Map{"status": "success", "data": chat})
+}
+
+// listChannels pages through the channels the caller subscribes to, most recently
+// subscribed first. Query: limit and cursor (next_cursor of the previous page).
+// Channels the caller is on the team of are in the chat list instead.
+func (s *Server) listChannels(c *fiber.Ctx) error {
+	user := c.Locals("user_id").(string)
+	limit := c.QueryInt("limit", defaultPageSize)
+	if limit < 1 || limit > maxPageSize {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("limit must be between 1 and %d", maxPageSize)})
+	}
+	chats, next, err := s.svc.ListSubscribedChannels(c.UserContext(), user, c.Query("cursor"), int64(limit))
+	if err != nil {
+		return fail(c, err)
+	}
+	tags := []string{cachetag.UserChannels(user)}
+	for _, ch := range chats {
+		tags = append(tags, cachetag.Chat(ch.ID))
+	}
+	cacheTags(c, tags...)
+	return c.JSON(fiber.Map{"status": "success", "data": chats, "next_cursor": next})
+}
+
+func (s *Server) getChannel(c *fiber.Ctx) error {
+	chID := c.Params("chat_id")
+	user := c.Locals("user_id").(string)
+	ch, subscribed, err := s.svc.GetChannel(c.UserContext(), user, chID)
+	if err != nil {
+		return fail(c, err)
+	}
+	cacheTags(c, cachetag.Chat(chID), cachetag.UserChannels(user))
+	return c.JSON(fiber.Map{"status": "success", "data": ch, "subscribed": subscribed})
+}
+
+func (s *Server) subscribe(c *fiber.Ctx) error {
+	chID := c.Params("chat_id")
+	user := c.Locals("user_id").(string)
+	ch, err := s.svc.Subscribe(c.UserContext(), user, chID)
+	if err != nil {
+		return fail(c, err)
+	}
+	purgeCache(c, cachetag.Chat(chID), cachetag.UserChannels(user))
+	return c.JSON(fiber.Map{"status": "success", "data": ch, "subscribed": true})
+}
+
+func (s *Server) unsubscribe(c *fiber.Ctx) error {
+	chID := c.Params("chat_id")
+	user := c.Locals("user_id").(string)
+	if err := s.svc.Unsubscribe(c.UserContext(), user, chID); err != nil {
+		return fail(c, err)
+	}
+	purgeCache(c, cachetag.Chat(chID), cachetag.UserChannels(user))
+	return c.JSON(fiber.Map{"status": "success", "message": "unsubscribed"})
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"time"
+
+	"github.com/fathima-sithara/message-service/internal/cachetag"
+	"github.com/fathima-sithara/message-service/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *Server) createInvite(c *fiber.Ctx) error {
+	chatID := c.Params("chat_id")
+	var body struct {
+		// ExpiresIn is in seconds; 0 never expires
+		ExpiresIn       int  `json:"expires_in"`
+		MaxUses         int  `json:"max_uses"`
+		RequireApproval bool `json:"require_approval"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid"})
+	}
+	user := c.Locals("user_id").(string)
+	inv, err := s.svc.CreateInvite(c.UserContext(), user, chatID, service.InviteOptions{
+		TTL:             time.Duration(body.ExpiresIn) * time.Second,
+		MaxUses:         body.MaxUses,
+		RequireApproval: body.RequireApproval,
+	})
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.Status(201).JSON(fiber.Map{"status": "success", "data": inv})
+}
+
+func (s *Server) listInvites(c *fiber.Ctx) error {
+	user := c.Locals("user_id").(string)
+	invites, err := s.svc.ListInvites(c.UserContext(), user, c.Params("chat_id"))
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(fiber.Map{"status": "success", "data": invites})
+}
+
+func (s *Server) revokeInvite(c *fiber.Ctx) error {
+	user := c.Locals("user_id").(string)
+	if err := s.svc.RevokeInvite(c.UserContext(), user, c.Params("chat_id"), c.Params("token")); err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(fiber.Map{"status": "success", "message": "invite revoked"})
+}
+
+// joinByInvite answers 200 with the chat once the caller is a member, or 202 when
+// the link needs an admin to approve the request.
+func (s *Server) joinByInvite(c *fiber.Ctx) error {
+	user := c.Locals("user_id").(string)
+	chat, pending, err := s.svc.JoinByInvite(c.UserContext(), user, c.Params("token"))
+	if err != nil {
+		return fail(c, err)
+	}
+	if pending {
+		return c.Status(202).JSON(fiber.Map{"status": "pending", "message": "join request sent"})
+	}
+	purgeCache(c, cachetag.Chat(chat.ID), cachetag.UserChats(user))
+	return c.JSON(fiber.Map{"status": "success", "data": chat})
+}
+
+func (s *Server) listJoinRequests(c *fiber.Ctx) error {
+	user := c.Locals("user_id").(string)
+	reqs, err := s.svc.ListJoinRequests(c.UserContext(), user, c.Params("chat_id"))
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(fiber.Map{"status": "success", "data": reqs})
+}
+
+func (s *Server) approveJoinRequest(c *fiber.Ctx) error {
+	chatID := c.Params("chat_id")
+	userID := c.Params("user_id")
+	user := c.Locals("user_id").(string)
+	if err := s.svc.ApproveJoinRequest(c.UserContext(), user, chatID, userID); err != nil {
+		return fail(c, err)
+	}
+	purgeCache(c, cachetag.Chat(chatID), cachetag.UserChats(userID))
+	return c.JSON(fiber.Map{"status": "success", "message": "request approved"})
+}
+
+func (s *Server) rejectJoinRequest(c *fiber.Ctx) error {
+	user := c.Locals("user_id").(string)
+	if err := s.svc.RejectJoinRequest(c.UserContext(), user, c.Params("chat_id"), c.Params("user_id")); err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(fiber.Map{"status": "success", "message": "request rejected"})
+}
@@ -1,10 +1,20 @@
 package api
 
 import (
-	"github.com/fathima-sithara/message-service/internal/auth"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/fathima-sithara/message-service/internal/cachetag"
 	"github.com/fathima-sithara/message-service/internal/config"
+	"github.com/fathima-sithara/message-service/internal/models"
+	"github.com/fathima-sithara/message-service/internal/repository"
 	"github.com/fathima-sithara/message-service/internal/service"
 	"github.com/fathima-sithara/message-service/internal/ws"
+	"github.com/fathima-sithara/platform/identity"
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/reqctx"
+	"github.com/fathima-sithara/platform/rpc"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -17,29 +27,18 @@ type Server struct {
 	app  *fiber.App
 }
 
-func NewServer(cfg *config.Config, svc *service.ChatService, wsrv *ws.Server, jv *auth.JWTValidator) *fiber.App {
+func NewServer(cfg *config.Config, svc *service.ChatService, wsrv *ws.Server) *fiber.App {
 	app := fiber.New()
-	app.Use(logger.New())
+	app.Use(reqctx.Middleware())
+	app.Use(observability.Middleware())
+	app.Use(logger.New(logger.Config{
+		Format: "[${time}] ${status} - ${latency} ${method} ${path} request_id=${locals:request_id} trace_id=${locals:trace_id}\n",
+	}))
 	s := &Server{svc: svc, wsrv: wsrv, app: app}
+	app.Get("/metrics", observability.MetricsHandler())
 	api := app.Group("/v1")
 
-	api.Use(func(c *fiber.Ctx) error {
-		h := c.Get("Authorization")
-		if h == "" {
-			return c.Status(401).JSON(fiber.Map{"error": "missing auth"})
-		}
-		const pref = "Bearer "
-		if len(h) <= len(pref) || h[:len(pref)] != pref {
-			return c.Status(401).JSON(fiber.Map{"error": "invalid auth"})
-		}
-		token := h[len(pref):]
-		sub, err := jv.Validate(token)
-		if err != nil {
-			return c.Status(401).JSON(fiber.Map{"error": err.Error()})
-		}
-		c.Locals("user_id", sub)
-		return c.Next()
-	})
+	api.Use(identity.TrustedIdentity(cfg.Identity.Secret))
 
 	api.Post("/chats", s.createChat)
 	api.Post("/groups", s.createGroup)
@@ -47,25 +46,75 @@ func NewServer(cfg *config.Config, svc *service.ChatService, wsrv *ws.Server, jv
 	api.Get("/chats/:chat_id", s.getChat)
 	api.Post("/groups/:chat_id/members", s.addMember)
 	api.Delete("/groups/:chat_id/members/:user_id", s.removeMember)
+	api.Put("/groups/:chat_id/members/:user_id/role", s.setRole)
+	api.Post("/groups/:chat_id/leave", s.leaveGroup)
+	api.Post("/groups/:chat_id/owner", s.transferOwnership)
 	api.Patch("/chats/:chat_id", s.updateChat)
+	api.Delete("/chats/:chat_id", s.deleteChat)
+	api.Get("/chats/:chat_id/settings", s.getSettings)
+	api.Patch("/chats/:chat_id/settings", s.updateSettings)
+	api.Post("/groups/:chat_id/invites", s.createInvite)
+	api.Get("/groups/:chat_id/invites", s.listInvites)
+	api.Delete("/groups/:chat_id/invites/:token", s.revokeInvite)
+	api.Get("/groups/:chat_id/join-requests", s.listJoinRequests)
+	api.Post("/groups/:chat_id/join-requests/:user_id/approve", s.approveJoinRequest)
+	api.Post("/groups/:chat_id/join-requests/:user_id/reject", s.rejectJoinRequest)
+	api.Post("/join/:token", s.joinByInvite)
+	api.Post("/channels", s.createChannel)
+	api.Get("/channels", s.listChannels)
+	api.Get("/channels/:chat_id", s.getChannel)
+	api.Put("/channels/:chat_id/subscription", s.subscribe)
+	api.Delete("/channels/:chat_id/subscription", s.unsubscribe)
 	api.Get("/ws", websocket.New(wsrv.HandleWS()))
 
 	return app
 }
 
+// errorStatus picks the response status for a service error, passing through the
+// status of a failed user-service lookup.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalid), errors.Is(err, service.ErrUnknownUser):
+		return 400
+	case errors.Is(err, service.ErrForbidden):
+		return 403
+	case errors.Is(err, service.ErrNotFound):
+		return 404
+	case errors.Is(err, service.ErrConflict):
+		return 409
+	case errors.Is(err, service.ErrExpired):
+		return 410
+	}
+	return rpc.HTTPStatus(err)
+}
+
+// fail answers with the status errorStatus picks. Unexpected errors keep their
+// message out of the response.
+func fail(c *fiber.Ctx, err error) error {
+	code := errorStatus(err)
+	if code == 500 {
+		return c.Status(code).JSON(fiber.Map{"error": "internal error"})
+	}
+	return c.Status(code).JSON(fiber.Map{"error": err.Error()})
+}
+
 func (s *Server) createChat(c *fiber.Ctx) error {
 	var body struct {
 		ParticipantID string `json:"participant_id"`
 		Name          string `json:"name"`
 	}
-	if err := c.BodyParser(&body); err != nil {
+	if err := c.BodyParser(&body); err != nil || body.ParticipantID == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid"})
 	}
 	user := c.Locals("user_id").(string)
-	chat, err := s.svc.CreateDM(c.Context(), user, body.ParticipantID, body.Name)
+	chat, created, err := s.svc.CreateDM(c.UserContext(), user, body.ParticipantID, body.Name)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return fail(c, err)
 	}
+	if !created {
+		return c.JSON(fiber.Map{"status": "success", "data": chat})
+	}
+	purgeMemberLists(c, chat.ID, chat.Members)
 	return c.Status(201).JSON(fiber.Map{"status": "success", "data": chat})
 }
 
@@ -74,33 +123,90 @@ func (s *Server) createGroup(c *fiber.Ctx) error {
 		Name    string   `json:"name"`
 		Members []string `json:"members"`
 	}
-	if err := c.BodyParser(&body); err != nil {
+	if err := c.BodyParser(&body); err != nil || body.Name == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid"})
 	}
 	user := c.Locals("user_id").(string)
-	chat, err := s.svc.CreateGroup(c.Context(), user, body.Name, body.Members)
+	chat, err := s.svc.CreateGroup(c.UserContext(), user, body.Name, body.Members)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return fail(c, err)
 	}
+	purgeMemberLists(c, chat.ID, chat.Members)
 	return c.Status(201).JSON(fiber.Map{"status": "success", "data": chat})
 }
 
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// listChats pages through the caller's chats, most recent message first. Query:
+// limit, cursor (next_cursor of the previous page), type=group|channel|direct,
+// unread=true, archived=true, pinned=true, muted=true and q (name search).
 func (s *Server) listChats(c *fiber.Ctx) error {
 	user := c.Locals("user_id").(string)
-	chats, err := s.svc.ListUserChats(c.Context(), user, 50)
+	limit := c.QueryInt("limit", defaultPageSize)
+	if limit < 1 || limit > maxPageSize {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("limit must be between 1 and %d", maxPageSize)})
+	}
+	f := repository.ChatFilter{Kind: c.Query("type"), Query: c.Query("q")}
+	if f.Kind != "" && f.Kind != "group" && f.Kind != "channel" && f.Kind != "direct" {
+		return c.Status(400).JSON(fiber.Map{"error": "type must be group, channel or direct"})
+	}
+	if len(f.Query) > 100 {
+		return c.Status(400).JSON(fiber.Map{"error": "q is too long"})
+	}
+	var err error
+	if f.UnreadOnly, err = queryBool(c, "unread"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if f.Archived, err = queryBool(c, "archived"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if f.Pinned, err = queryBool(c, "pinned"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if f.Muted, err = queryBool(c, "muted"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	chats, next, err := s.svc.ListUserChats(c.UserContext(), user, f, c.Query("cursor"), int64(limit))
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return fail(c, err)
+	}
+	tags := []string{cachetag.UserChats(user)}
+	for _, ch := range chats {
+		tags = append(tags, cachetag.Chat(ch.ID))
 	}
-	return c.JSON(fiber.Map{"status": "success", "data": chats})
+	views := make([]*models.MemberView, len(chats))
+	for i, ch := range chats {
+		views[i] = ch.ViewFor(user)
+	}
+	cacheTags(c, tags...)
+	return c.JSON(fiber.Map{"status": "success", "data": views, "next_cursor": next})
+}
+
+func queryBool(c *fiber.Ctx, key string) (bool, error) {
+	v := c.Query(key)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false", key)
+	}
+	return b, nil
 }
 
 func (s *Server) getChat(c *fiber.Ctx) error {
 	chID := c.Params("chat_id")
-	ch, err := s.svc.GetChat(c.Context(), chID)
+	user := c.Locals("user_id").(string)
+	ch, err := s.svc.GetChatForUser(c.UserContext(), user, chID)
 	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+		return fail(c, err)
 	}
-	return c.JSON(fiber.Map{"status": "success", "data": ch})
+	cacheTags(c, cachetag.Chat(chID))
+	return c.JSON(fiber.Map{"status": "success", "data": ch.ViewFor(user)})
 }
 
 func (s *Server) addMember(c *fiber.Ctx) error {
@@ -111,31 +217,108 @@ func (s *Server) addMember(c *fiber.Ctx) error {
 	if err := c.BodyParser(&body); err != nil || body.UserID == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid"})
 	}
-	if err := s.svc.AddMember(c.Context(), chatID, body.UserID); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	user := c.Locals("user_id").(string)
+	if err := s.svc.AddMember(c.UserContext(), user, chatID, body.UserID); err != nil {
+		return fail(c, err)
 	}
+	purgeCache(c, cachetag.Chat(chatID), cachetag.UserChats(body.UserID))
 	return c.JSON(fiber.Map{"status": "success", "message": "member added"})
 }
 
 func (s *Server) removeMember(c *fiber.Ctx) error {
 	chatID := c.Params("chat_id")
 	userID := c.Params("user_id")
-	if err := s.svc.RemoveMember(c.Context(), chatID, userID); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	user := c.Locals("user_id").(string)
+	if err := s.svc.RemoveMember(c.UserContext(), user, chatID, userID); err != nil {
+		return fail(c, err)
 	}
+	purgeCache(c, cachetag.Chat(chatID), cachetag.UserChats(userID))
 	return c.JSON(fiber.Map{"status": "success", "message": "member removed"})
 }
 
+func (s *Server) setRole(c *fiber.Ctx) error {
+	chatID := c.Params("chat_id")
+	userID := c.Params("user_id")
+	var body struct {
+		Role models.Role `json:"role"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Role == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid"})
+	}
+	user := c.Locals("user_id").(string)
+	if err := s.svc.SetRole(c.UserContext(), user, chatID, userID, body.Role); err != nil {
+		return fail(c, err)
+	}
+	purgeCache(c, cachetag.Chat(chatID))
+	return c.JSON(fiber.Map{"status": "success", "message": "role updated"})
+}
+
+func (s *Server) leaveGroup(c *fiber.Ctx) error {
+	chatID := c.Params("chat_id")
+	user := c.Locals("user_id").(string)
+	if err := s.svc.Leave(c.UserContext(), user, chatID); err != nil {
+		return fail(c, err)
+	}
+	purgeCache(c, cachetag.Chat(chatID), cachetag.UserChats(user))
+	return c.JSON(fiber.Map{"status": "success", "message": "left group"})
+}
+
+func (s *Server) transferOwnership(c *fiber.Ctx) error {
+	chatID := c.Params("chat_id")
+	var body struct {
+		UserID string `json:"user_id"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.UserID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid"})
+	}
+	user := c.Locals("user_id").(string)
+	if err := s.svc.TransferOwnership(c.UserContext(), user, chatID, body.UserID); err != nil {
+		return fail(c, err)
+	}
+	purgeCache(c, cachetag.Chat(chatID))
+	return c.JSON(fiber.Map{"status": "success", "message": "ownership transferred"})
+}
+
 func (s *Server) updateChat(c *fiber.Ctx) error {
 	chID := c.Params("chat_id")
 	var body struct {
-		Name string `json:"name"`
+		Name               *string `json:"name"`
+		Description        *string `json:"description"`
+		AvatarURL          *string `json:"avatar_url"`
+		OnlyAdminsPost     *bool   `json:"only_admins_post"`
+		OnlyAdminsEditInfo *bool   `json:"only_admins_edit_info"`
+		MaxMembers         *int    `json:"max_members"`
 	}
-	if err := c.BodyParser(&body); err != nil || body.Name == "" {
+	if err := c.BodyParser(&body); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid"})
 	}
-	if err := s.svc.UpdateName(c.Context(), chID, body.Name); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	user := c.Locals("user_id").(string)
+	ch, err := s.svc.UpdateChat(c.UserContext(), user, chID, service.ChatUpdate{
+		Name:               body.Name,
+		Description:        body.Description,
+		AvatarURL:          body.AvatarURL,
+		OnlyAdminsPost:     body.OnlyAdminsPost,
+		OnlyAdminsEditInfo: body.OnlyAdminsEditInfo,
+		MaxMembers:         body.MaxMembers,
+	})
+	if err != nil {
+		return fail(c, err)
+	}
+	purgeCache(c, cachetag.Chat(chID))
+	return c.JSON(fiber.Map{"status": "success", "data": ch.ViewFor(user)})
+}
+
+func (s *Server) deleteChat(c *fiber.Ctx) error {
+	chID := c.Params("chat_id")
+	user := c.Locals("user_id").(string)
+	ch, err := s.svc.DeleteChat(c.UserContext(), user, chID)
+	if err != nil {
+		return fail(c, err)
+	}
+	tags := []string{cachetag.Chat(chID)}
+	for _, m := range ch.Members {
+		tags = append(tags, cachetag.UserChats(m))
 	}
-	return c.JSON(fiber.Map{"status": "success", "message": "updated"})
+	purgeCache(c, tags...)
+	return c.JSON(fiber.Map{"status": "success", "message": "chat deleted"})
 }
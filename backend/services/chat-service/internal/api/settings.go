@@ -0,0 +1,61 @@
+package api
+
+import (
+	"time"
+
+	"github.com/fathima-sithara/message-service/internal/cachetag"
+	"github.com/fathima-sithara/message-service/internal/models"
+	"github.com/fathima-sithara/message-service/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *Server) getSettings(c *fiber.Ctx) error {
+	chatID := c.Params("chat_id")
+	user := c.Locals("user_id").(string)
+	st, err := s.svc.GetSettings(c.UserContext(), user, chatID)
+	if err != nil {
+		return fail(c, err)
+	}
+	cacheTags(c, cachetag.Chat(chatID))
+	return c.JSON(fiber.Map{"status": "success", "data": st})
+}
+
+// updateSettings changes the caller's settings for a chat. Omitted fields keep
+// their value; muted_until is an RFC 3339 time, or "" to unmute.
+func (s *Server) updateSettings(c *fiber.Ctx) error {
+	chatID := c.Params("chat_id")
+	var body struct {
+		MutedUntil  *string             `json:"muted_until"`
+		Pinned      *bool               `json:"pinned"`
+		PinOrder    *int                `json:"pin_order"`
+		Archived    *bool               `json:"archived"`
+		NotifyLevel *models.NotifyLevel `json:"notification_level"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid"})
+	}
+	p := service.SettingsPatch{
+		Pinned:      body.Pinned,
+		PinOrder:    body.PinOrder,
+		Archived:    body.Archived,
+		NotifyLevel: body.NotifyLevel,
+	}
+	if body.MutedUntil != nil {
+		var until time.Time
+		if *body.MutedUntil != "" {
+			t, err := time.Parse(time.RFC3339, *body.MutedUntil)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "muted_until must be an RFC 3339 time"})
+			}
+			until = t
+		}
+		p.MutedUntil = &until
+	}
+	user := c.Locals("user_id").(string)
+	st, err := s.svc.UpdateSettings(c.UserContext(), user, chatID, p)
+	if err != nil {
+		return fail(c, err)
+	}
+	purgeCache(c, cachetag.Chat(chatID), cachetag.UserChats(user))
+	return c.JSON(fiber.Map{"status": "success", "data": st})
+}
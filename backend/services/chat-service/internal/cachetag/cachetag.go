@@ -0,0 +1,30 @@
+// Package cachetag names the tags the api-gateway response cache files
+// chat-service responses under. Handlers tag responses and purge tags through
+// response headers; event consumers purge over SubjectCachePurge.
+package cachetag
+
+// SubjectCachePurge carries a Purge to every api-gateway replica.
+const SubjectCachePurge = "cache.purge"
+
+// Purge asks the gateway to evict every cached response carrying one of Tags.
+type Purge struct {
+	Tags []string `json:"tags"`
+}
+
+// Chat covers every cached response that includes the chat; UserChats covers a
+// user's chat list, which changes when they join or create a chat or one of
+// their chats gets a message, and UserChannels the channels they subscribe to.
+func Chat(id string) string             { return "chat:" + id }
+func UserChats(userID string) string    { return "chats:user:" + userID }
+func UserChannels(userID string) string { return "channels:user:" + userID }
+
+// Members returns the chat's tag and the chat list tag of each member, whose
+// ordering or unread counts a change to the chat can alter.
+func Members(chatID string, members []string) []string {
+	tags := make([]string, 0, len(members)+1)
+	tags = append(tags, Chat(chatID))
+	for _, m := range members {
+		tags = append(tags, UserChats(m))
+	}
+	return tags
+}
@@ -0,0 +1,24 @@
+package cachetag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMembers(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []string
+		want    []string
+	}{
+		{"direct chat", []string{"u1", "u2"}, []string{"chat:c1", "chats:user:u1", "chats:user:u2"}},
+		{"no members left", nil, []string{"chat:c1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Members("c1", tt.members); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Members = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
@@ -24,7 +25,13 @@ type Mongo struct {
 type JWTCfg struct {
 	PublicKeyPath string `yaml:"public_key_path"`
 	Algorithm     string `yaml:"algorithm"`
-	Secret        string `yaml:"secret"` 
+	Secret        string `yaml:"secret"`
+}
+
+type Identity struct {
+	// Secret verifies identity headers signed by the api-gateway; required. JWT
+	// still authenticates WebSocket connections.
+	Secret string `yaml:"secret"`
 }
 
 type NATS struct {
@@ -35,13 +42,33 @@ type Kafka struct {
 	Brokers []string `yaml:"brokers"`
 }
 
+// GRPC configures the internal gRPC API. Port 0 leaves the server off; an empty
+// address disables the matching client.
+type GRPC struct {
+	Port        int    `yaml:"port"`
+	MessageAddr string `yaml:"message_addr"`
+	UserAddr    string `yaml:"user_addr"`
+}
+
+func (g *GRPC) PortString() string { return fmt.Sprintf("%d", g.Port) }
+
+// Outbox configures event delivery: how often the relay looks for unsent events
+// and how long sent ones are kept for replays.
+type Outbox struct {
+	PollInterval time.Duration `yaml:"poll_interval"`
+	Retention    time.Duration `yaml:"retention"`
+}
+
 type Config struct {
-	App    App    `yaml:"app"`
-	Mongo  Mongo  `yaml:"mongo"`
-	JWT    JWTCfg `yaml:"jwt"`
-	NATS   NATS   `yaml:"nats"`
-	Kafka  Kafka  `yaml:"kafka"`
-	AESKey string `yaml:"aes_key"`
+	App      App      `yaml:"app"`
+	GRPC     GRPC     `yaml:"grpc"`
+	Mongo    Mongo    `yaml:"mongo"`
+	JWT      JWTCfg   `yaml:"jwt"`
+	Identity Identity `yaml:"identity"`
+	NATS     NATS     `yaml:"nats"`
+	Kafka    Kafka    `yaml:"kafka"`
+	Outbox   Outbox   `yaml:"outbox"`
+	AESKey   string   `yaml:"aes_key"`
 }
 
 func Load() (*Config, error) {
@@ -70,6 +97,16 @@ func overrideFromEnv(cfg *Config) {
 		fmt.Sscanf(v, "%d", &cfg.App.Port)
 	}
 
+	if v := os.Getenv("GRPC_PORT"); v != "" {
+		fmt.Sscanf(v, "%d", &cfg.GRPC.Port)
+	}
+	if v := os.Getenv("MESSAGE_GRPC_ADDR"); v != "" {
+		cfg.GRPC.MessageAddr = v
+	}
+	if v := os.Getenv("USER_GRPC_ADDR"); v != "" {
+		cfg.GRPC.UserAddr = v
+	}
+
 	if v := os.Getenv("MONGO_URI"); v != "" {
 		cfg.Mongo.URI = v
 	}
@@ -87,6 +124,10 @@ func overrideFromEnv(cfg *Config) {
 		cfg.JWT.Secret = v
 	}
 
+	if v := os.Getenv("IDENTITY_HMAC_SECRET"); v != "" {
+		cfg.Identity.Secret = v
+	}
+
 	if v := os.Getenv("NATS_URL"); v != "" {
 		cfg.NATS.URL = v
 	}
@@ -94,6 +135,23 @@ func overrideFromEnv(cfg *Config) {
 		cfg.Kafka.Brokers = strings.Split(v, ",")
 	}
 
+	if v := os.Getenv("OUTBOX_POLL_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.Outbox.PollInterval = d
+		}
+	}
+	if v := os.Getenv("OUTBOX_RETENTION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.Outbox.Retention = d
+		}
+	}
+	if cfg.Outbox.PollInterval <= 0 {
+		cfg.Outbox.PollInterval = time.Second
+	}
+	if cfg.Outbox.Retention <= 0 {
+		cfg.Outbox.Retention = 7 * 24 * time.Hour
+	}
+
 	if v := os.Getenv("AES256_KEY"); v != "" {
 		cfg.AESKey = v
 	}
@@ -124,6 +182,10 @@ func validate(cfg *Config) error {
 		return errors.New("invalid jwt.algorithm (allowed: RS256, HS256)")
 	}
 
+	if cfg.Identity.Secret == "" {
+		return errors.New("identity.secret missing (set IDENTITY_HMAC_SECRET)")
+	}
+
 	if cfg.NATS.URL == "" {
 		return errors.New("nats.url missing")
 	}
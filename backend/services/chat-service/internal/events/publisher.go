@@ -1,35 +1,195 @@
 package events
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"time"
 
+	"github.com/fathima-sithara/message-service/internal/models"
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/reqctx"
 	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type ChatCreatedEvent struct {
-	ChatID  string   `json:"chat_id"`
-	Members []string `json:"members"`
-	Name    string   `json:"name"`
-	IsGroup bool     `json:"is_group"`
+// Chat lifecycle subjects.
+const (
+	SubjectChatCreated       = "chat.created"
+	SubjectChatMemberAdded   = "chat.member_added"
+	SubjectChatMemberRemoved = "chat.member_removed"
+	SubjectChatUpdated       = "chat.updated"      // name, description, avatar or group settings
+	SubjectChatRoleChanged   = "chat.role_changed" // promotion, demotion or ownership transfer
+	SubjectChatDeleted       = "chat.deleted"
+	// SubjectChatRenamed is no longer published; renames are chat.updated. It is
+	// still consumed so older outbox records can be replayed.
+	SubjectChatRenamed = "chat.renamed"
+)
+
+// LifecycleSubjects lists every chat lifecycle subject.
+var LifecycleSubjects = []string{
+	SubjectChatCreated, SubjectChatMemberAdded, SubjectChatMemberRemoved, SubjectChatUpdated, SubjectChatRoleChanged,
+	SubjectChatDeleted, SubjectChatRenamed,
+}
+
+// ChatEvent is the payload of every lifecycle subject. It carries the chat's
+// state after the change together with the version of that state, so consumers
+// can apply events idempotently and in any order by ignoring versions they have
+// already seen. UserID is the member a member_added, member_removed or
+// role_changed event is about; ActorID is who made the change. Notices are the lines message-service
+// records in the chat as system messages, such as "Alice changed the group name".
+type ChatEvent struct {
+	ChatID             string                 `json:"chat_id"`
+	Version            int64                  `json:"version"`
+	Name               string                 `json:"name"`
+	Description        string                 `json:"description,omitempty"`
+	AvatarURL          string                 `json:"avatar_url,omitempty"`
+	IsGroup            bool                   `json:"is_group"`
+	IsChannel          bool                   `json:"is_channel,omitempty"`
+	OnlyAdminsPost     bool                   `json:"only_admins_post,omitempty"`
+	OnlyAdminsEditInfo bool                   `json:"only_admins_edit_info,omitempty"`
+	MaxMembers         int                    `json:"max_members,omitempty"`
+	Members            []string               `json:"members"`
+	Roles              map[string]models.Role `json:"roles,omitempty"` // owner and admins
+	UserID             string                 `json:"user_id,omitempty"`
+	ActorID            string                 `json:"actor_id,omitempty"`
+	Notices            []string               `json:"notices,omitempty"`
+	At                 time.Time              `json:"at"`
+}
+
+// SubjectChatSettingsUpdated carries a member's new settings for a chat. It is not
+// a lifecycle subject: only the member's own clients and notification-service care.
+const SubjectChatSettingsUpdated = "chat.settings.updated"
+
+// SettingsEvent is one member's settings for a chat after a change. Version counts
+// that member's changes to that chat, so consumers keep the highest they have seen.
+type SettingsEvent struct {
+	ChatID      string     `json:"chat_id"`
+	UserID      string     `json:"user_id"`
+	Version     int64      `json:"version"`
+	MutedUntil  *time.Time `json:"muted_until,omitempty"`
+	NotifyLevel string     `json:"notification_level"`
+	Pinned      bool       `json:"pinned"`
+	PinOrder    int        `json:"pin_order"`
+	Archived    bool       `json:"archived"`
+	At          time.Time  `json:"at"`
+}
+
+// SubjectChannelDelivery hands a channel message to a batch of its subscribers.
+// Subscribers never join a channel's room, so this is how they receive posts.
+const SubjectChannelDelivery = "channel.message.delivery"
+
+// ChannelDeliveryEvent is one batch of a channel message's fan-out. A message
+// goes out as many events as the channel has subscriber batches.
+type ChannelDeliveryEvent struct {
+	ChatID      string          `json:"chat_id"`
+	ChannelName string          `json:"channel_name"`
+	Message     *models.Message `json:"message"`
+	UserIDs     []string        `json:"user_ids"`
+}
+
+// ChatMergedEvent asks message-service to fold the duplicate direct chats
+// MergedIDs into ChatID.
+type ChatMergedEvent struct {
+	ChatID    string   `json:"chat_id"`
+	MergedIDs []string `json:"merged_ids"`
 }
 
 type Publisher struct{ nc *nats.Conn }
 
 func NewPublisher(url string) (*Publisher, error) {
-	nc, err := nats.Connect(url)
+	// keep trying in the background when NATS is down at startup; the outbox
+	// relay holds events back until the connection is up
+	nc, err := nats.Connect(url, nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1))
 	if err != nil {
 		return nil, err
 	}
 	return &Publisher{nc: nc}, nil
 }
 
-func (p *Publisher) PublishChatCreated(chatID, name string, members []string, isGroup bool) error {
-	if p == nil || p.nc == nil { return nil }
-	ev := ChatCreatedEvent{ChatID: chatID, Name: name, Members: members, IsGroup: isGroup}
-	b, _ := json.Marshal(ev)
-	if err := p.nc.Publish("chat.created", b); err != nil {
-		log.Println("publish chat.created:", err)
+// Ready reports whether the NATS connection is up, for the readiness probe.
+func (p *Publisher) Ready(context.Context) error {
+	if p == nil || p.nc == nil || !p.nc.IsConnected() {
+		return errors.New("nats not connected")
+	}
+	return nil
+}
+
+// SubscribeChatEvents calls fn for every lifecycle event. Each replica gets every
+// event, so it suits per-process state such as websocket rooms.
+func (p *Publisher) SubscribeChatEvents(fn func(ctx context.Context, subject string, ev ChatEvent)) error {
+	for _, subject := range LifecycleSubjects {
+		_, err := p.nc.Subscribe(subject, func(m *nats.Msg) {
+			var ev ChatEvent
+			if err := json.Unmarshal(m.Data, &ev); err != nil {
+				log.Printf("invalid %s event: %v", m.Subject, err)
+				return
+			}
+			ctx := context.Background()
+			if m.Header != nil {
+				ctx = reqctx.Extract(ctx, m.Header)
+			}
+			fn(ctx, m.Subject, ev)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RequestChatMerged asks message-service to move the messages of merged into
+// chatID and waits for it to confirm, so the duplicates are only deleted once
+// nothing points at them.
+func (p *Publisher) RequestChatMerged(ctx context.Context, chatID string, merged []string, timeout time.Duration) error {
+	b, _ := json.Marshal(ChatMergedEvent{ChatID: chatID, MergedIDs: merged})
+	ctx, span := observability.StartSpan(ctx, "chat.merged request", trace.SpanKindClient,
+		attribute.String("messaging.system", "nats"),
+		attribute.String("messaging.destination.name", "chat.merged"),
+	)
+	msg := nats.NewMsg("chat.merged")
+	msg.Data = b
+	reqctx.Inject(ctx, msg.Header)
+	reply, err := p.nc.RequestMsg(msg, timeout)
+	if err == nil && string(reply.Data) != "ok" {
+		err = fmt.Errorf("message-service: %s", reply.Data)
+	}
+	observability.RecordPublish("nats", "chat.merged", err)
+	observability.EndSpan(span, err)
+	return err
+}
+
+// Send publishes an outbox record and waits for the server to acknowledge it, so
+// the relay never marks a message sent that is still sitting in a client buffer.
+func (p *Publisher) Send(ctx context.Context, subject string, data []byte) error {
+	if !p.nc.IsConnected() {
+		return errors.New("nats not connected")
+	}
+	if err := p.publish(ctx, subject, data); err != nil {
+		return err
+	}
+	return p.nc.FlushTimeout(5 * time.Second)
+}
+
+// publish sends data on subject inside a producer span, carrying the correlation
+// headers so consumers can continue the trace.
+func (p *Publisher) publish(ctx context.Context, subject string, data []byte) error {
+	ctx, span := observability.StartSpan(ctx, subject+" publish", trace.SpanKindProducer,
+		attribute.String("messaging.system", "nats"),
+		attribute.String("messaging.destination.name", subject),
+	)
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	reqctx.Inject(ctx, msg.Header)
+	err := p.nc.PublishMsg(msg)
+	observability.RecordPublish("nats", subject, err)
+	observability.EndSpan(span, err)
+	if err != nil {
+		info := reqctx.FromContext(ctx)
+		log.Printf("publish %s: %v request_id=%s trace_id=%s", subject, err, info.RequestID, info.TraceID())
 		return err
 	}
 	return nil
@@ -0,0 +1,239 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/fathima-sithara/message-service/internal/cachetag"
+	"github.com/fathima-sithara/message-service/internal/models"
+	"github.com/fathima-sithara/message-service/internal/repository"
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/reqctx"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MessageCreatedEvent and MessageReadEvent are message-service's payloads, trimmed
+// to what the chat list needs.
+type MessageCreatedEvent struct {
+	ChatID  string `json:"chat_id"`
+	Message struct {
+		ID        string    `json:"id"`
+		SenderID  string    `json:"sender_id"`
+		Content   string    `json:"content"`
+		MsgType   string    `json:"msg_type"`
+		ThreadID  string    `json:"thread_id"`
+		CreatedAt time.Time `json:"created_at"`
+	} `json:"message"`
+}
+
+type MessageReadEvent struct {
+	ChatID    string    `json:"chat_id"`
+	MessageID string    `json:"message_id"`
+	UserID    string    `json:"user_id"`
+	MessageAt time.Time `json:"message_at"`
+	Unread    int64     `json:"unread"`
+}
+
+// Subscriber keeps each chat's last message and members' unread counts current
+// from message-service events, and fans channel messages out to subscribers.
+type Subscriber struct {
+	nc   *nats.Conn
+	repo *repository.Repository
+	subs *repository.SubscriberRepository
+}
+
+func NewSubscriber(natsURL string, repo *repository.Repository, subs *repository.SubscriberRepository) (*Subscriber, error) {
+	nc, err := nats.Connect(natsURL, nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	return &Subscriber{nc: nc, repo: repo, subs: subs}, nil
+}
+
+// Ready reports whether the NATS connection is up, for the readiness probe.
+func (s *Subscriber) Ready(context.Context) error {
+	if s == nil || s.nc == nil || !s.nc.IsConnected() {
+		return errors.New("nats not connected")
+	}
+	return nil
+}
+
+// Start subscribes in queue, so each event is handled by one replica.
+func (s *Subscriber) Start(queue string) error {
+	if _, err := s.nc.QueueSubscribe("message.created", queue, s.consume(s.handleMessageCreated)); err != nil {
+		return err
+	}
+	_, err := s.nc.QueueSubscribe("message.read", queue, s.consume(s.handleMessageRead))
+	return err
+}
+
+// consume restores the producer's correlation ids, wraps handle in a consumer span
+// and records the outcome.
+func (s *Subscriber) consume(handle func(context.Context, *nats.Msg) error) nats.MsgHandler {
+	return func(m *nats.Msg) {
+		ctx := context.Background()
+		if m.Header != nil {
+			ctx = reqctx.Extract(ctx, m.Header)
+		}
+		ctx, span := observability.StartSpan(ctx, m.Subject+" process", trace.SpanKindConsumer,
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", m.Subject),
+		)
+		err := handle(ctx, m)
+		if err != nil {
+			info := reqctx.FromContext(ctx)
+			log.Printf("%s: %v request_id=%s trace_id=%s", m.Subject, err, info.RequestID, info.TraceID())
+		}
+		observability.RecordConsume("nats", m.Subject, err)
+		observability.EndSpan(span, err)
+	}
+}
+
+func (s *Subscriber) handleMessageCreated(base context.Context, m *nats.Msg) error {
+	var ev MessageCreatedEvent
+	if err := json.Unmarshal(m.Data, &ev); err != nil || ev.ChatID == "" || ev.Message.ID == "" {
+		return errors.New("invalid message.created event")
+	}
+	if ev.Message.ThreadID != "" {
+		// thread replies stay out of the chat list and unread counts; message-service
+		// notifies the thread's participants itself
+		return nil
+	}
+	msg := &models.Message{
+		ID:        ev.Message.ID,
+		SenderID:  ev.Message.SenderID,
+		Content:   ev.Message.Content,
+		MsgType:   ev.Message.MsgType,
+		CreatedAt: ev.Message.CreatedAt,
+	}
+	var (
+		chat    *models.Chat
+		counted bool
+	)
+	err := retry(base, func(ctx context.Context) error {
+		var err error
+		chat, err = s.repo.GetChat(ctx, ev.ChatID)
+		if errors.Is(err, repository.ErrNotFound) {
+			// deleted since; nothing to update
+			chat = nil
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		ok, err := s.repo.ApplyMessage(ctx, chat, msg)
+		counted = counted || ok
+		return err
+	})
+	if err != nil || chat == nil {
+		return err
+	}
+	// the message moves the chat up every member's list; a redelivery purges again,
+	// which is harmless
+	s.purge(base, cachetag.Members(chat.ID, chat.Members)...)
+	// a redelivered message was fanned out the first time round
+	if !chat.IsChannel || !counted {
+		return nil
+	}
+	return s.fanOut(base, chat, msg)
+}
+
+// Channel fan-out limits: subscribers per delivery event and the time allowed to
+// walk one channel's subscribers.
+const (
+	deliveryBatch = 500
+	fanOutTimeout = 2 * time.Minute
+)
+
+// fanOut publishes msg to the channel's subscribers in batches on
+// SubjectChannelDelivery, so they get it without joining the channel's room.
+func (s *Subscriber) fanOut(base context.Context, chat *models.Chat, msg *models.Message) error {
+	ctx, cancel := context.WithTimeout(base, fanOutTimeout)
+	defer cancel()
+	return s.subs.Scan(ctx, chat.ID, deliveryBatch, func(userIDs []string) error {
+		b, err := json.Marshal(ChannelDeliveryEvent{
+			ChatID:      chat.ID,
+			ChannelName: chat.Name,
+			Message:     msg,
+			UserIDs:     userIDs,
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.publish(ctx, SubjectChannelDelivery, b); err != nil {
+			return err
+		}
+		tags := make([]string, len(userIDs))
+		for i, id := range userIDs {
+			tags[i] = cachetag.UserChannels(id)
+		}
+		s.purge(ctx, tags...)
+		return nil
+	})
+}
+
+// purge asks the api-gateway to evict cached responses carrying tags. The change
+// is already stored, so a lost purge only leaves a cached page stale until its
+// TTL runs out; it is logged rather than failing the event.
+func (s *Subscriber) purge(ctx context.Context, tags ...string) {
+	b, err := json.Marshal(cachetag.Purge{Tags: tags})
+	if err == nil {
+		err = s.publish(ctx, cachetag.SubjectCachePurge, b)
+	}
+	if err != nil {
+		info := reqctx.FromContext(ctx)
+		log.Printf("cache purge %v: %v request_id=%s trace_id=%s", tags, err, info.RequestID, info.TraceID())
+	}
+}
+
+// publish sends data on subject inside a producer span, carrying the correlation
+// headers of the event being handled.
+func (s *Subscriber) publish(ctx context.Context, subject string, data []byte) error {
+	ctx, span := observability.StartSpan(ctx, subject+" publish", trace.SpanKindProducer,
+		attribute.String("messaging.system", "nats"),
+		attribute.String("messaging.destination.name", subject),
+	)
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	reqctx.Inject(ctx, msg.Header)
+	err := s.nc.PublishMsg(msg)
+	observability.RecordPublish("nats", subject, err)
+	observability.EndSpan(span, err)
+	return err
+}
+
+func (s *Subscriber) handleMessageRead(base context.Context, m *nats.Msg) error {
+	var ev MessageReadEvent
+	if err := json.Unmarshal(m.Data, &ev); err != nil || ev.ChatID == "" || ev.UserID == "" {
+		return errors.New("invalid message.read event")
+	}
+	rs := models.ReadState{MessageID: ev.MessageID, At: ev.MessageAt}
+	err := retry(base, func(ctx context.Context) error {
+		return s.repo.ApplyRead(ctx, ev.ChatID, ev.UserID, rs, ev.Unread)
+	})
+	if err != nil {
+		return err
+	}
+	s.purge(base, cachetag.Chat(ev.ChatID), cachetag.UserChats(ev.UserID))
+	return nil
+}
+
+// retry runs fn up to three times with a short backoff.
+func retry(base context.Context, fn func(context.Context) error) error {
+	var err error
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(base, 3*time.Second)
+		err = fn(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		time.Sleep(time.Duration(i+1) * 200 * time.Millisecond)
+	}
+	return err
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Subscription is a user following a channel. Subscribers are kept out of
+// Chat.Members, which only holds the channel's team, so a channel can have far
+// more of them than a chat document could.
+type Subscription struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	ChatID    string             `bson:"chat_id"`
+	UserID    string             `bson:"user_id"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
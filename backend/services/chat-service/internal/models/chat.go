@@ -2,6 +2,22 @@ package models
 
 import "time"
 
+// MsgTypeSystem marks messages message-service records for chat changes, such as
+// a rename; they do not count as unread.
+const MsgTypeSystem = "system"
+
+// userMsgTypes are the types users may send.
+var userMsgTypes = map[string]bool{"text": true, "image": true, "video": true, "audio": true, "file": true}
+
+// UserMsgType reports whether users may send messages of type t, which defaults
+// to text when empty, and returns the type to send.
+func UserMsgType(t string) (string, bool) {
+	if t == "" {
+		return "text", true
+	}
+	return t, userMsgTypes[t]
+}
+
 type Message struct {
 	ID        string    `bson:"_id,omitempty" json:"id"`
 	SenderID  string    `bson:"sender_id" json:"sender_id"`
@@ -10,12 +26,150 @@ type Message struct {
 	CreatedAt time.Time `bson:"created_at" json:"created_at"`
 }
 
+// Role is a group member's standing. Only owners and admins are stored in
+// Chat.Roles; every other member is a RoleMember.
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+)
+
+// rank orders roles so a user may only act on members below them.
+func (r Role) rank() int {
+	switch r {
+	case RoleOwner:
+		return 3
+	case RoleAdmin:
+		return 2
+	case RoleMember:
+		return 1
+	}
+	return 0
+}
+
+// Outranks reports whether r may manage a member holding other.
+func (r Role) Outranks(other Role) bool { return r.rank() > other.rank() }
+
 type Chat struct {
-	ID          string    `bson:"_id,omitempty" json:"id"`
-	Name        string    `bson:"name,omitempty" json:"name"`
-	IsGroup     bool      `bson:"is_group" json:"is_group"`
-	Members     []string  `bson:"members" json:"members"` 
-	LastMessage *Message  `bson:"last_message,omitempty" json:"last_message,omitempty"`
-	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
-	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+	ID                 string                    `bson:"_id,omitempty" json:"id"`
+	Name               string                    `bson:"name,omitempty" json:"name"`
+	IsGroup            bool                      `bson:"is_group" json:"is_group"`
+	IsChannel          bool                      `bson:"is_channel,omitempty" json:"is_channel,omitempty"` // a group whose members are its team; readers subscribe
+	SubscriberCount    int64                     `bson:"subscriber_count,omitempty" json:"subscriber_count,omitempty"`
+	Members            []string                  `bson:"members" json:"members"`
+	Roles              map[string]Role           `bson:"roles,omitempty" json:"roles,omitempty"`
+	Description        string                    `bson:"description,omitempty" json:"description,omitempty"`
+	AvatarURL          string                    `bson:"avatar_url,omitempty" json:"avatar_url,omitempty"` // file_url of a message-service media upload
+	OnlyAdminsPost     bool                      `bson:"only_admins_post" json:"only_admins_post"`
+	OnlyAdminsEditInfo bool                      `bson:"only_admins_edit_info" json:"only_admins_edit_info"` // name, description and avatar
+	MaxMembers         int                       `bson:"max_members,omitempty" json:"max_members,omitempty"` // 0 is MaxGroupMembers
+	DMKey              string                    `bson:"dm_key,omitempty" json:"-"`                          // member pair of a direct chat; unique
+	LastMessage        *Message                  `bson:"last_message,omitempty" json:"last_message,omitempty"`
+	LastMessageAt      time.Time                 `bson:"last_message_at" json:"last_message_at"` // orders chat lists; starts at creation
+	Unread             map[string]int64          `bson:"unread,omitempty" json:"-"`              // unread message count per member
+	Reads              map[string]ReadState      `bson:"reads,omitempty" json:"-"`               // read position per member
+	RecentIDs          []string                  `bson:"recent_message_ids,omitempty" json:"-"`  // last messages counted, to skip redeliveries
+	Settings           map[string]MemberSettings `bson:"settings,omitempty" json:"-"`            // per-member preferences
+	ArchivedBy         []string                  `bson:"archived_by,omitempty" json:"-"`         // members who archived the chat; mirrors Settings
+	Version            int64                     `bson:"version" json:"version"`                 // bumped by every change; orders lifecycle events
+	CreatedAt          time.Time                 `bson:"created_at" json:"created_at"`
+	UpdatedAt          time.Time                 `bson:"updated_at" json:"updated_at"`
+}
+
+// ReadState is the last message a member has read.
+type ReadState struct {
+	MessageID string    `bson:"message_id"`
+	At        time.Time `bson:"at"` // when that message was sent
+}
+
+// MemberView is a chat as one member sees it in their inbox.
+type MemberView struct {
+	*Chat
+	UnreadCount       int64          `json:"unread_count"`
+	LastReadMessageID string         `json:"last_read_message_id,omitempty"`
+	Settings          MemberSettings `json:"settings"`
+}
+
+// ViewFor returns the chat with userID's unread count and read position.
+func (c *Chat) ViewFor(userID string) *MemberView {
+	return &MemberView{
+		Chat:              c,
+		UnreadCount:       c.Unread[userID],
+		LastReadMessageID: c.Reads[userID].MessageID,
+		Settings:          c.SettingsFor(userID),
+	}
+}
+
+// DMKey is the canonical key of the direct chat between a and b, independent of
+// who started it.
+func DMKey(a, b string) string {
+	if b < a {
+		a, b = b, a
+	}
+	return a + ":" + b
+}
+
+func (c *Chat) IsMember(userID string) bool {
+	for _, m := range c.Members {
+		if m == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleOf returns userID's role, or "" for non-members. Both sides of a direct chat
+// are plain members.
+func (c *Chat) RoleOf(userID string) Role {
+	if !c.IsMember(userID) {
+		return ""
+	}
+	if r, ok := c.Roles[userID]; ok && c.IsGroup {
+		return r
+	}
+	return RoleMember
+}
+
+// MaxGroupMembers caps every group, including those without their own MaxMembers.
+const MaxGroupMembers = 1000
+
+// CanManage reports whether userID may add and remove members and change the
+// group's settings.
+func (c *Chat) CanManage(userID string) bool {
+	r := c.RoleOf(userID)
+	return c.IsGroup && (r == RoleOwner || r == RoleAdmin)
+}
+
+// CanEditInfo reports whether userID may change the name, description and
+// avatar: any member of a direct chat, and in a group any member unless
+// OnlyAdminsEditInfo is set.
+func (c *Chat) CanEditInfo(userID string) bool {
+	if c.IsGroup && c.OnlyAdminsEditInfo {
+		return c.CanManage(userID)
+	}
+	return c.IsMember(userID)
+}
+
+// CanPost reports whether userID may send messages to the chat.
+func (c *Chat) CanPost(userID string) bool {
+	if c.AdminsOnlyPost() {
+		return c.CanManage(userID)
+	}
+	return c.IsMember(userID)
+}
+
+// AdminsOnlyPost reports whether only the owner and admins may send messages:
+// always in a channel, and in a group with OnlyAdminsPost set.
+func (c *Chat) AdminsOnlyPost() bool {
+	return c.IsChannel || (c.IsGroup && c.OnlyAdminsPost)
+}
+
+// MemberCap is how many members the group may have.
+func (c *Chat) MemberCap() int {
+	if c.MaxMembers > 0 && c.MaxMembers < MaxGroupMembers {
+		return c.MaxMembers
+	}
+	return MaxGroupMembers
 }
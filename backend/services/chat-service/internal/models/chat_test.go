@@ -0,0 +1,160 @@
+package models
+
+import "testing"
+
+func TestRoleOf(t *testing.T) {
+	group := &Chat{
+		IsGroup: true,
+		Members: []string{"owner", "admin", "member"},
+		Roles:   map[string]Role{"owner": RoleOwner, "admin": RoleAdmin, "left": RoleAdmin},
+	}
+	direct := &Chat{
+		Members: []string{"a", "b"},
+		Roles:   map[string]Role{"a": RoleOwner},
+	}
+	tests := []struct {
+		name       string
+		chat       *Chat
+		user       string
+		want       Role
+		wantManage bool
+	}{
+		{"group owner", group, "owner", RoleOwner, true},
+		{"group admin", group, "admin", RoleAdmin, true},
+		{"plain member", group, "member", RoleMember, false},
+		{"role left behind by a former member", group, "left", "", false},
+		{"stranger", group, "stranger", "", false},
+		{"direct chats have no roles", direct, "a", RoleMember, false},
+		{"direct chat stranger", direct, "c", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.chat.RoleOf(tt.user); got != tt.want {
+				t.Fatalf("RoleOf(%q) = %q, want %q", tt.user, got, tt.want)
+			}
+			if got := tt.chat.CanManage(tt.user); got != tt.wantManage {
+				t.Fatalf("CanManage(%q) = %v, want %v", tt.user, got, tt.wantManage)
+			}
+		})
+	}
+}
+
+func TestRoleOutranks(t *testing.T) {
+	tests := []struct {
+		r, other Role
+		want     bool
+	}{
+		{RoleOwner, RoleAdmin, true},
+		{RoleOwner, RoleMember, true},
+		{RoleAdmin, RoleMember, true},
+		{RoleAdmin, RoleAdmin, false},
+		{RoleAdmin, RoleOwner, false},
+		{RoleMember, RoleMember, false},
+		{RoleMember, "", true},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := tt.r.Outranks(tt.other); got != tt.want {
+			t.Errorf("%q.Outranks(%q) = %v, want %v", tt.r, tt.other, got, tt.want)
+		}
+	}
+}
+
+func TestDMKey(t *testing.T) {
+	tests := []struct {
+		name, a, b, want string
+	}{
+		{"ordered", "u1", "u2", "u1:u2"},
+		{"reversed", "u2", "u1", "u1:u2"},
+		{"byte order, not numeric", "u10", "u9", "u10:u9"},
+		{"same user", "u1", "u1", "u1:u1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DMKey(tt.a, tt.b); got != tt.want {
+				t.Fatalf("DMKey(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+			if DMKey(tt.a, tt.b) != DMKey(tt.b, tt.a) {
+				t.Fatalf("DMKey depends on argument order")
+			}
+		})
+	}
+}
+
+func TestCanPostAndEditInfo(t *testing.T) {
+	roles := map[string]Role{"owner": RoleOwner, "admin": RoleAdmin}
+	members := []string{"owner", "admin", "member"}
+	tests := []struct {
+		name     string
+		chat     *Chat
+		user     string
+		wantPost bool
+		wantEdit bool
+	}{
+		{"direct chat member", &Chat{Members: []string{"a", "b"}}, "a", true, true},
+		{"direct chat with admin-only flags", &Chat{Members: []string{"a", "b"}, OnlyAdminsPost: true, OnlyAdminsEditInfo: true}, "a", true, true},
+		{"stranger", &Chat{IsGroup: true, Members: members, Roles: roles}, "stranger", false, false},
+		{"open group member", &Chat{IsGroup: true, Members: members, Roles: roles}, "member", true, true},
+		{"admin-only posting, member", &Chat{IsGroup: true, Members: members, Roles: roles, OnlyAdminsPost: true}, "member", false, true},
+		{"admin-only posting, admin", &Chat{IsGroup: true, Members: members, Roles: roles, OnlyAdminsPost: true}, "admin", true, true},
+		{"admin-only info, member", &Chat{IsGroup: true, Members: members, Roles: roles, OnlyAdminsEditInfo: true}, "member", true, false},
+		{"admin-only info, owner", &Chat{IsGroup: true, Members: members, Roles: roles, OnlyAdminsEditInfo: true}, "owner", true, true},
+		{"channel member", &Chat{IsGroup: true, IsChannel: true, Members: members, Roles: roles}, "member", false, true},
+		{"channel owner", &Chat{IsGroup: true, IsChannel: true, Members: members, Roles: roles}, "owner", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.chat.CanPost(tt.user); got != tt.wantPost {
+				t.Fatalf("CanPost(%q) = %v, want %v", tt.user, got, tt.wantPost)
+			}
+			if got := tt.chat.CanEditInfo(tt.user); got != tt.wantEdit {
+				t.Fatalf("CanEditInfo(%q) = %v, want %v", tt.user, got, tt.wantEdit)
+			}
+		})
+	}
+}
+
+func TestMemberCap(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxMembers int
+		want       int
+	}{
+		{"unset", 0, MaxGroupMembers},
+		{"negative", -5, MaxGroupMembers},
+		{"own limit", 50, 50},
+		{"just below the cap", MaxGroupMembers - 1, MaxGroupMembers - 1},
+		{"at the cap", MaxGroupMembers, MaxGroupMembers},
+		{"above the cap", MaxGroupMembers * 10, MaxGroupMembers},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (&Chat{MaxMembers: tt.maxMembers}).MemberCap(); got != tt.want {
+				t.Fatalf("MemberCap = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestViewFor(t *testing.T) {
+	chat := &Chat{
+		Members: []string{"a", "b", "c"},
+		Unread:  map[string]int64{"a": 4, "b": 0},
+		Reads:   map[string]ReadState{"b": {MessageID: "m9"}},
+	}
+	tests := []struct {
+		user       string
+		wantUnread int64
+		wantRead   string
+	}{
+		{"a", 4, ""},
+		{"b", 0, "m9"},
+		{"c", 0, ""},
+	}
+	for _, tt := range tests {
+		v := chat.ViewFor(tt.user)
+		if v.Chat != chat || v.UnreadCount != tt.wantUnread || v.LastReadMessageID != tt.wantRead {
+			t.Errorf("ViewFor(%q) = {%d, %q}, want {%d, %q}", tt.user, v.UnreadCount, v.LastReadMessageID, tt.wantUnread, tt.wantRead)
+		}
+	}
+}
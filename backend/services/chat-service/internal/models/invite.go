@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Invite is a link token that lets users join a group without being added by
+// an admin.
+type Invite struct {
+	Token     string     `bson:"_id" json:"token"`
+	ChatID    string     `bson:"chat_id" json:"chat_id"`
+	CreatedBy string     `bson:"created_by" json:"created_by"`
+	ExpiresAt *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	// MaxUses of 0 means unlimited
+	MaxUses         int       `bson:"max_uses" json:"max_uses"`
+	Uses            int       `bson:"uses" json:"uses"`
+	RequireApproval bool      `bson:"require_approval" json:"require_approval"`
+	Revoked         bool      `bson:"revoked" json:"revoked"`
+	CreatedAt       time.Time `bson:"created_at" json:"created_at"`
+}
+
+// Usable reports whether the invite can still admit someone at now.
+func (i *Invite) Usable(now time.Time) bool {
+	if i.Revoked || (i.ExpiresAt != nil && !now.Before(*i.ExpiresAt)) {
+		return false
+	}
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
+
+// JoinRequest is a pending join through an invite that requires approval.
+type JoinRequest struct {
+	ChatID      string    `bson:"chat_id" json:"chat_id"`
+	UserID      string    `bson:"user_id" json:"user_id"`
+	InviteToken string    `bson:"invite_token" json:"-"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+}
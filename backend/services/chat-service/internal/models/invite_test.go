@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInviteUsable(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+	tests := []struct {
+		name   string
+		invite Invite
+		want   bool
+	}{
+		{"unlimited", Invite{}, true},
+		{"revoked", Invite{Revoked: true}, false},
+		{"before expiry", Invite{ExpiresAt: at(time.Second)}, true},
+		{"at expiry", Invite{ExpiresAt: at(0)}, false},
+		{"after expiry", Invite{ExpiresAt: at(-time.Second)}, false},
+		{"uses left", Invite{MaxUses: 2, Uses: 1}, true},
+		{"used up", Invite{MaxUses: 2, Uses: 2}, false},
+		{"unlimited uses ignore the count", Invite{Uses: 500}, true},
+		{"revoked with uses left", Invite{MaxUses: 5, Revoked: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.invite.Usable(now); got != tt.want {
+				t.Fatalf("Usable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// NotifyLevel is which messages in a chat notify a member.
+type NotifyLevel string
+
+const (
+	NotifyAll      NotifyLevel = "all"
+	NotifyMentions NotifyLevel = "mentions"
+	NotifyNone     NotifyLevel = "none"
+)
+
+func (l NotifyLevel) Valid() bool {
+	return l == NotifyAll || l == NotifyMentions || l == NotifyNone
+}
+
+// MemberSettings are one member's own preferences for a chat; other members never
+// see them.
+type MemberSettings struct {
+	MutedUntil  *time.Time  `bson:"muted_until,omitempty" json:"muted_until,omitempty"`
+	Pinned      bool        `bson:"pinned" json:"pinned"`
+	PinOrder    int         `bson:"pin_order" json:"pin_order"` // lower first among pinned chats
+	Archived    bool        `bson:"archived" json:"archived"`
+	NotifyLevel NotifyLevel `bson:"notify_level,omitempty" json:"notification_level"`
+	Version     int64       `bson:"version" json:"-"` // counts changes; orders settings events
+	UpdatedAt   time.Time   `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// Muted reports whether the chat is muted at now.
+func (s MemberSettings) Muted(now time.Time) bool {
+	return s.MutedUntil != nil && s.MutedUntil.After(now)
+}
+
+// SettingsFor returns userID's settings, with defaults for a member who never
+// changed them.
+func (c *Chat) SettingsFor(userID string) MemberSettings {
+	s := c.Settings[userID]
+	if s.NotifyLevel == "" {
+		s.NotifyLevel = NotifyAll
+	}
+	return s
+}
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"regexp"
 	"time"
 
 	"github.com/fathima-sithara/message-service/internal/models"
@@ -11,16 +14,64 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var ErrNotFound = errors.New("not found")
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrDuplicate = errors.New("duplicate")
+	// ErrBadCursor is returned for a page cursor ListChatsForUser did not issue.
+	ErrBadCursor = errors.New("invalid cursor")
+	// ErrStale is returned by UpdateChat when the chat changed since it was read.
+	ErrStale = errors.New("chat changed concurrently")
+)
 
 type Repository struct{ coll *mongo.Collection }
 
 func NewMongoRepository(coll *mongo.Collection) *Repository {
-	idx := mongo.IndexModel{
-		Keys:    bson.D{{Key: "members", Value: 1}},
-		Options: options.Index().SetBackground(true).SetName("members_idx"),
+	idx := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "members", Value: 1}},
+			Options: options.Index().SetBackground(true).SetName("members_idx"),
+		},
+		{
+			// chat lists: a member's chats, most recent message first
+			Keys: bson.D{
+				{Key: "members", Value: 1},
+				{Key: "last_message_at", Value: -1},
+				{Key: "_id", Value: -1},
+			},
+			Options: options.Index().SetName("member_activity_idx"),
+		},
+		{
+			// one direct chat per member pair; chats without a key are not indexed
+			Keys: bson.D{{Key: "dm_key", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("dm_key_unique").
+				SetPartialFilterExpression(bson.M{"dm_key": bson.M{"$type": "string"}}),
+		},
 	}
-	_, _ = coll.Indexes().CreateOne(context.Background(), idx)
+	_, _ = coll.Indexes().CreateMany(context.Background(), idx)
+	// chats from before last_message_at existed sort by their last message or,
+	// failing that, their creation time
+	_, _ = coll.UpdateMany(context.Background(),
+		bson.M{"last_message_at": bson.M{"$exists": false}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"last_message_at": bson.M{"$ifNull": bson.A{"$last_message.created_at", "$created_at"}},
+		}}}},
+	)
+	// renaming a group was limited to owners and admins before it was a setting
+	_, _ = coll.UpdateMany(context.Background(),
+		bson.M{"is_group": true, "only_admins_edit_info": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"only_admins_edit_info": true}},
+	)
+	// groups from before roles existed have no owner and did not record who
+	// created them; their first member becomes the owner so the group can still
+	// be managed
+	_, _ = coll.UpdateMany(context.Background(),
+		bson.M{"is_group": true, "roles": bson.M{"$exists": false}, "members.0": bson.M{"$exists": true}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"roles": bson.M{"$arrayToObject": bson.A{bson.A{
+				bson.M{"k": bson.M{"$arrayElemAt": bson.A{"$members", 0}}, "v": string(models.RoleOwner)},
+			}}},
+		}}}},
+	)
 	return &Repository{coll: coll}
 }
 
@@ -28,7 +79,51 @@ func (r *Repository) CreateChat(ctx context.Context, chat *models.Chat) error {
 	now := time.Now().UTC()
 	chat.CreatedAt = now
 	chat.UpdatedAt = now
+	chat.LastMessageAt = now
+	chat.Version = 1
 	_, err := r.coll.InsertOne(ctx, chat)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// GetDM returns the direct chat with the given models.DMKey.
+func (r *Repository) GetDM(ctx context.Context, key string) (*models.Chat, error) {
+	var c models.Chat
+	if err := r.coll.FindOne(ctx, bson.M{"dm_key": key}).Decode(&c); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListDirectChats returns every direct chat, oldest first.
+func (r *Repository) ListDirectChats(ctx context.Context) ([]*models.Chat, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cur, err := r.coll.Find(ctx, bson.M{"is_group": false}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out []*models.Chat
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *Repository) SetDMKey(ctx context.Context, chatID, key string) error {
+	_, err := r.coll.UpdateByID(ctx, chatID, bson.M{"$set": bson.M{"dm_key": key}})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *Repository) DeleteChats(ctx context.Context, ids []string) error {
+	_, err := r.coll.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
 	return err
 }
 
@@ -43,23 +138,96 @@ func (r *Repository) GetChat(ctx context.Context, id string) (*models.Chat, erro
 	return &c, nil
 }
 
-func (r *Repository) ListChatsForUser(ctx context.Context, userID string, limit int64) ([]*models.Chat, error) {
+// ChatFilter narrows a member's chat list. Archived chats are listed only when
+// Archived is set, and then exclusively.
+type ChatFilter struct {
+	Kind       string // "group", "channel", "direct" or "" for all
+	UnreadOnly bool
+	Archived   bool
+	Pinned     bool   // only chats the user pinned
+	Muted      bool   // only chats the user has muted right now
+	Query      string // case-insensitive substring of the name
+}
+
+// chatCursor is the position after the last chat of a page.
+type chatCursor struct {
+	At time.Time `json:"t"`
+	ID string    `json:"id"`
+}
+
+// ListChatsForUser returns up to limit of userID's chats matching f, most recent
+// message first, starting after cursor ("" for the first page). next is the
+// cursor of the following page, or "" on the last one.
+func (r *Repository) ListChatsForUser(ctx context.Context, userID string, f ChatFilter, cursor string, limit int64) (chats []*models.Chat, next string, err error) {
 	filter := bson.M{"members": userID}
-	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}}).SetLimit(limit)
+	switch f.Kind {
+	case "group":
+		filter["is_group"] = true
+		filter["is_channel"] = bson.M{"$ne": true}
+	case "channel":
+		filter["is_channel"] = true
+	case "direct":
+		filter["is_group"] = false
+	}
+	if f.UnreadOnly {
+		filter["unread."+userID] = bson.M{"$gt": 0}
+	}
+	if f.Archived {
+		filter["archived_by"] = userID
+	} else {
+		filter["archived_by"] = bson.M{"$ne": userID}
+	}
+	if f.Pinned {
+		filter["settings."+userID+".pinned"] = true
+	}
+	if f.Muted {
+		filter["settings."+userID+".muted_until"] = bson.M{"$gt": time.Now().UTC()}
+	}
+	if f.Query != "" {
+		filter["name"] = bson.M{"$regex": regexp.QuoteMeta(f.Query), "$options": "i"}
+	}
+	if cursor != "" {
+		c, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		filter["$or"] = bson.A{
+			bson.M{"last_message_at": bson.M{"$lt": c.At}},
+			bson.M{"last_message_at": c.At, "_id": bson.M{"$lt": c.ID}},
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "last_message_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(limit + 1)
 	cur, err := r.coll.Find(ctx, filter, opts)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	defer cur.Close(ctx)
-	var out []*models.Chat
-	for cur.Next(ctx) {
-		var c models.Chat
-		if err := cur.Decode(&c); err != nil {
-			return nil, err
-		}
-		out = append(out, &c)
+	out := []*models.Chat{}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, "", err
 	}
-	return out, nil
+	if int64(len(out)) > limit {
+		out = out[:limit]
+		last := out[len(out)-1]
+		next = encodeCursor(chatCursor{At: last.LastMessageAt, ID: last.ID})
+	}
+	return out, next, nil
+}
+
+func encodeCursor(c chatCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (chatCursor, error) {
+	var c chatCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &c) != nil || c.ID == "" {
+		return c, ErrBadCursor
+	}
+	return c, nil
 }
 
 func (r *Repository) AddMember(ctx context.Context, chatID, userID string) error {
@@ -74,15 +242,197 @@ func (r *Repository) RemoveMember(ctx context.Context, chatID, userID string) er
 	return err
 }
 
+// UpdateChat saves chat's info, members and roles if the stored chat is still at
+// chat.Version, and fails with ErrStale otherwise, so concurrent changes cannot
+// overwrite each other. On success chat.Version is the new version.
 func (r *Repository) UpdateChat(ctx context.Context, chat *models.Chat) error {
 	if chat == nil || chat.ID == "" {
 		return errors.New("invalid chat")
 	}
+	filter := versionFilter(chat)
 	chat.UpdatedAt = time.Now().UTC()
-	_, err := r.coll.UpdateByID(ctx, chat.ID, bson.M{"$set": bson.M{
-		"name":       chat.Name,
-		"members":    chat.Members,
-		"updated_at": chat.UpdatedAt,
-	}})
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"version": 1})
+	var updated struct {
+		Version int64 `bson:"version"`
+	}
+	err := r.coll.FindOneAndUpdate(ctx, filter, bson.M{
+		"$set": bson.M{
+			"name":                  chat.Name,
+			"members":               chat.Members,
+			"roles":                 chat.Roles,
+			"description":           chat.Description,
+			"avatar_url":            chat.AvatarURL,
+			"only_admins_post":      chat.OnlyAdminsPost,
+			"only_admins_edit_info": chat.OnlyAdminsEditInfo,
+			"max_members":           chat.MaxMembers,
+			"updated_at":            chat.UpdatedAt,
+		},
+		"$inc": bson.M{"version": 1},
+	}, opts).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return r.staleOrMissing(ctx, chat.ID)
+	}
+	if err != nil {
+		return err
+	}
+	chat.Version = updated.Version
+	return nil
+}
+
+// DeleteChat deletes chat if it is still at chat.Version, and fails with ErrStale
+// otherwise, like UpdateChat. On success chat.Version is one past the deleted
+// version so the deletion orders after every change before it.
+func (r *Repository) DeleteChat(ctx context.Context, chat *models.Chat) error {
+	if chat == nil || chat.ID == "" {
+		return errors.New("invalid chat")
+	}
+	res, err := r.coll.DeleteOne(ctx, versionFilter(chat))
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return r.staleOrMissing(ctx, chat.ID)
+	}
+	chat.Version++
+	return nil
+}
+
+// versionFilter matches chat only at the version it was read at.
+func versionFilter(chat *models.Chat) bson.M {
+	filter := bson.M{"_id": chat.ID, "version": chat.Version}
+	if chat.Version == 0 {
+		// chats from before versions existed
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	return filter
+}
+
+// staleOrMissing tells why a version-guarded write matched nothing: ErrStale
+// when the chat is still there at another version, ErrNotFound when it is gone.
+func (r *Repository) staleOrMissing(ctx context.Context, chatID string) error {
+	n, err := r.coll.CountDocuments(ctx, bson.M{"_id": chatID})
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrStale
+	}
+	return ErrNotFound
+}
+
+// recentMessages is how many counted message ids a chat remembers to recognise
+// redelivered message.created events.
+const recentMessages = 100
+
+// ApplyMessage records a new message: it becomes the last message unless a newer
+// one is already there, and every member but the sender gains an unread message
+// unless it is a system message. A message that was already counted is not
+// counted again; counted reports whether this call counted it.
+func (r *Repository) ApplyMessage(ctx context.Context, chat *models.Chat, m *models.Message) (counted bool, err error) {
+	inc := bson.M{}
+	for _, member := range chat.Members {
+		if member != m.SenderID && m.MsgType != models.MsgTypeSystem {
+			inc["unread."+member] = 1
+		}
+	}
+	update := bson.M{
+		"$max":  bson.M{"last_message_at": m.CreatedAt},
+		"$set":  bson.M{"updated_at": time.Now().UTC()},
+		"$push": bson.M{"recent_message_ids": bson.M{"$each": bson.A{m.ID}, "$slice": -recentMessages}},
+	}
+	if len(inc) > 0 {
+		update["$inc"] = inc
+	}
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": chat.ID, "recent_message_ids": bson.M{"$ne": m.ID}}, update)
+	if err != nil {
+		return false, err
+	}
+	_, err = r.coll.UpdateOne(ctx, bson.M{"_id": chat.ID, "$or": bson.A{
+		bson.M{"last_message": nil},
+		bson.M{"last_message.created_at": bson.M{"$lte": m.CreatedAt}},
+	}}, bson.M{"$set": bson.M{"last_message": m}})
+	return res.ModifiedCount > 0, err
+}
+
+// ApplyRead moves userID's read position to rs and sets their unread count,
+// unless they have already read past it.
+func (r *Repository) ApplyRead(ctx context.Context, chatID, userID string, rs models.ReadState, unread int64) error {
+	key := "reads." + userID
+	filter := bson.M{"_id": chatID, "members": userID, "$or": bson.A{
+		bson.M{key: bson.M{"$exists": false}},
+		bson.M{key + ".at": bson.M{"$lt": rs.At}},
+	}}
+	_, err := r.coll.UpdateOne(ctx, filter, bson.M{"$set": bson.M{key: rs, "unread." + userID: unread}})
+	return err
+}
+
+// SetMemberSettings stores userID's settings for a chat they are in, keeping
+// archived_by in step, and sets st.Version to the stored version.
+func (r *Repository) SetMemberSettings(ctx context.Context, chatID, userID string, st *models.MemberSettings) error {
+	key := "settings." + userID + "."
+	set := bson.M{
+		key + "pinned":       st.Pinned,
+		key + "pin_order":    st.PinOrder,
+		key + "archived":     st.Archived,
+		key + "notify_level": st.NotifyLevel,
+		key + "updated_at":   st.UpdatedAt,
+	}
+	update := bson.M{"$inc": bson.M{key + "version": 1}}
+	if st.MutedUntil != nil {
+		set[key+"muted_until"] = st.MutedUntil
+	} else {
+		update["$unset"] = bson.M{key + "muted_until": ""}
+	}
+	update["$set"] = set
+	if st.Archived {
+		update["$addToSet"] = bson.M{"archived_by": userID}
+	} else {
+		update["$pull"] = bson.M{"archived_by": userID}
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{key + "version": 1})
+	var updated models.Chat
+	err := r.coll.FindOneAndUpdate(ctx, bson.M{"_id": chatID, "members": userID}, update, opts).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	st.Version = updated.Settings[userID].Version
+	return nil
+}
+
+// AddSubscribers moves a channel's subscriber count by delta.
+func (r *Repository) AddSubscribers(ctx context.Context, chatID string, delta int64) error {
+	_, err := r.coll.UpdateByID(ctx, chatID, bson.M{"$inc": bson.M{"subscriber_count": delta}})
 	return err
 }
+
+// GetChats returns the chats with the given ids in that order, skipping any that
+// no longer exist.
+func (r *Repository) GetChats(ctx context.Context, ids []string) ([]*models.Chat, error) {
+	cur, err := r.coll.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	var found []*models.Chat
+	if err := cur.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Chat, len(found))
+	for _, c := range found {
+		byID[c.ID] = c
+	}
+	out := make([]*models.Chat, 0, len(ids))
+	for _, id := range ids {
+		if c, ok := byID[id]; ok {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fathima-sithara/message-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		c    chatCursor
+	}{
+		{"utc", chatCursor{At: time.Date(2024, 5, 1, 10, 30, 0, 123_000_000, time.UTC), ID: "c1"}},
+		{"zero time", chatCursor{ID: "c2"}},
+		{"id with url characters", chatCursor{At: time.Unix(1_700_000_000, 0).UTC(), ID: "a/b+c?d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := encodeCursor(tt.c)
+			got, err := decodeCursor(s)
+			if err != nil {
+				t.Fatalf("decodeCursor(%q) error = %v", s, err)
+			}
+			if !got.At.Equal(tt.c.At) || got.ID != tt.c.ID {
+				t.Fatalf("decodeCursor = %+v, want %+v", got, tt.c)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	tests := []struct {
+		name, cursor string
+	}{
+		{"empty", ""},
+		{"not base64", "%%%"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"id":"c1"}`))},
+		{"not json", b64([]byte("c1"))},
+		{"no id", b64([]byte(`{"t":"2024-05-01T10:30:00Z"}`))},
+		{"bad time", b64([]byte(`{"t":"yesterday","id":"c1"}`))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.cursor); !errors.Is(err, ErrBadCursor) {
+				t.Fatalf("decodeCursor(%q) error = %v, want ErrBadCursor", tt.cursor, err)
+			}
+		})
+	}
+}
+
+func TestApplyMessage(t *testing.T) {
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	updated := func(n int) bson.D {
+		return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}, bson.E{Key: "nModified", Value: n})
+	}
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	tests := []struct {
+		name        string
+		msgType     string
+		responses   []bson.D
+		wantCounted bool
+		wantErr     bool
+		// wantInc are the unread counters the message bumps
+		wantInc []string
+		// wantLast is whether the message was offered as the last message
+		wantLast bool
+	}{
+		{name: "new message counts for everyone but the sender", msgType: "text", responses: []bson.D{updated(1), updated(1)},
+			wantCounted: true, wantInc: []string{"unread.u2", "unread.u3"}, wantLast: true},
+		{name: "a redelivery is not counted again", msgType: "text", responses: []bson.D{updated(0), updated(0)},
+			wantInc: []string{"unread.u2", "unread.u3"}, wantLast: true},
+		{name: "system messages leave unread counts alone", msgType: models.MsgTypeSystem, responses: []bson.D{updated(1), updated(1)},
+			wantCounted: true, wantLast: true},
+		{name: "a failed count stops", msgType: "text", wantErr: true, wantInc: []string{"unread.u2", "unread.u3"},
+			responses: []bson.D{mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 2, Message: "bad value"})}},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			r := &Repository{coll: mt.Coll}
+			mt.AddMockResponses(tt.responses...)
+			chat := &models.Chat{ID: "c1", Members: []string{"u1", "u2", "u3"}}
+			msg := &models.Message{ID: "m1", SenderID: "u1", MsgType: tt.msgType, CreatedAt: at}
+
+			counted, err := r.ApplyMessage(context.Background(), chat, msg)
+			if (err != nil) != tt.wantErr || counted != tt.wantCounted {
+				mt.Fatalf("ApplyMessage = (%v, %v), want (%v, error %v)", counted, err, tt.wantCounted, tt.wantErr)
+			}
+
+			count := mt.GetStartedEvent()
+			stmt := count.Command.Lookup("updates").Array().Index(0).Value().Document()
+			if got := stmt.Lookup("q", "recent_message_ids", "$ne").StringValue(); got != "m1" {
+				mt.Fatalf("count filter skips %q, want m1", got)
+			}
+			var inc []string
+			if v, err := stmt.LookupErr("u", "$inc"); err == nil {
+				elems, _ := v.Document().Elements()
+				for _, e := range elems {
+					inc = append(inc, e.Key())
+				}
+			}
+			sort.Strings(inc)
+			if strings.Join(inc, ",") != strings.Join(tt.wantInc, ",") {
+				mt.Fatalf("incremented %v, want %v", inc, tt.wantInc)
+			}
+			if got := stmt.Lookup("u", "$max", "last_message_at").Time(); !got.Equal(at) {
+				mt.Fatalf("last_message_at raised to %v, want %v", got, at)
+			}
+
+			last := mt.GetStartedEvent()
+			if (last != nil) != tt.wantLast {
+				mt.Fatalf("last message update sent = %v, want %v", last != nil, tt.wantLast)
+			}
+			if last == nil {
+				return
+			}
+			stmt = last.Command.Lookup("updates").Array().Index(0).Value().Document()
+			newer := stmt.Lookup("q", "$or").Array().Index(1).Value().Document()
+			if got := newer.Lookup("last_message.created_at", "$lte").Time(); !got.Equal(at) {
+				mt.Fatalf("last message replaced when older than %v, want %v", got, at)
+			}
+			if got := stmt.Lookup("u", "$set", "last_message", "_id").StringValue(); got != "m1" {
+				mt.Fatalf("last message set to %q, want m1", got)
+			}
+		})
+	}
+}
+
+func TestApplyRead(t *testing.T) {
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("moves the read position forward only", func(mt *mtest.T) {
+		r := &Repository{coll: mt.Coll}
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		if err := r.ApplyRead(context.Background(), "c1", "u2", models.ReadState{MessageID: "m7", At: at}, 3); err != nil {
+			mt.Fatalf("ApplyRead error = %v", err)
+		}
+
+		stmt := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		if got := stmt.Lookup("q", "members").StringValue(); got != "u2" {
+			mt.Fatalf("filter member = %q, want u2", got)
+		}
+		later := stmt.Lookup("q", "$or").Array().Index(1).Value().Document()
+		if got := later.Lookup("reads.u2.at", "$lt").Time(); !got.Equal(at) {
+			mt.Fatalf("read position moved when older than %v, want %v", got, at)
+		}
+		set := stmt.Lookup("u", "$set")
+		if got := set.Document().Lookup("reads.u2", "message_id").StringValue(); got != "m7" {
+			mt.Fatalf("read position set to %q, want m7", got)
+		}
+		if got := set.Document().Lookup("unread.u2").AsInt64(); got != 3 {
+			mt.Fatalf("unread count set to %d, want 3", got)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/fathima-sithara/message-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InviteRepository stores group invite links and the join requests waiting on
+// approval.
+type InviteRepository struct {
+	invites  *mongo.Collection
+	requests *mongo.Collection
+}
+
+func NewInviteRepository(db *mongo.Database) *InviteRepository {
+	r := &InviteRepository{
+		invites:  db.Collection("invites"),
+		requests: db.Collection("join_requests"),
+	}
+	_, _ = r.invites.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "chat_id", Value: 1}},
+		Options: options.Index().SetName("chat_idx"),
+	})
+	_, _ = r.requests.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "chat_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("chat_user_unique"),
+	})
+	return r
+}
+
+func (r *InviteRepository) CreateInvite(ctx context.Context, inv *models.Invite) error {
+	_, err := r.invites.InsertOne(ctx, inv)
+	return err
+}
+
+func (r *InviteRepository) GetInvite(ctx context.Context, token string) (*models.Invite, error) {
+	var inv models.Invite
+	if err := r.invites.FindOne(ctx, bson.M{"_id": token}).Decode(&inv); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// ListInvites returns the chat's links that have not been revoked, newest first.
+func (r *InviteRepository) ListInvites(ctx context.Context, chatID string) ([]*models.Invite, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cur, err := r.invites.Find(ctx, bson.M{"chat_id": chatID, "revoked": false}, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := []*models.Invite{}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *InviteRepository) RevokeInvite(ctx context.Context, chatID, token string) error {
+	res, err := r.invites.UpdateOne(ctx, bson.M{"_id": token, "chat_id": chatID}, bson.M{"$set": bson.M{"revoked": true}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UseInvite takes one use of the invite, returning ErrNotFound when it has been
+// revoked, has expired or is used up. The check and the increment are a single
+// update so concurrent joins cannot overrun MaxUses.
+func (r *InviteRepository) UseInvite(ctx context.Context, token string, now time.Time) error {
+	filter := bson.M{
+		"_id":     token,
+		"revoked": false,
+		"$and": bson.A{
+			bson.M{"$or": bson.A{bson.M{"expires_at": nil}, bson.M{"expires_at": bson.M{"$gt": now}}}},
+			bson.M{"$or": bson.A{bson.M{"max_uses": 0}, bson.M{"$expr": bson.M{"$lt": bson.A{"$uses", "$max_uses"}}}}},
+		},
+	}
+	res, err := r.invites.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"uses": 1}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateJoinRequest returns ErrDuplicate if the user already has one pending
+// for the chat.
+func (r *InviteRepository) CreateJoinRequest(ctx context.Context, req *models.JoinRequest) error {
+	_, err := r.requests.InsertOne(ctx, req)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *InviteRepository) ListJoinRequests(ctx context.Context, chatID string) ([]*models.JoinRequest, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cur, err := r.requests.Find(ctx, bson.M{"chat_id": chatID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := []*models.JoinRequest{}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *InviteRepository) GetJoinRequest(ctx context.Context, chatID, userID string) (*models.JoinRequest, error) {
+	var req models.JoinRequest
+	if err := r.requests.FindOne(ctx, bson.M{"chat_id": chatID, "user_id": userID}).Decode(&req); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &req, nil
+}
+
+// DeleteJoinRequest removes a pending request, returning ErrNotFound if there
+// was none.
+func (r *InviteRepository) DeleteJoinRequest(ctx context.Context, chatID, userID string) error {
+	res, err := r.requests.DeleteOne(ctx, bson.M{"chat_id": chatID, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
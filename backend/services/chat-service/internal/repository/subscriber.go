@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/fathima-sithara/message-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SubscriberRepository stores channel subscriptions, one document each.
+type SubscriberRepository struct {
+	coll *mongo.Collection
+}
+
+func NewSubscriberRepository(db *mongo.Database) *SubscriberRepository {
+	coll := db.Collection("channel_subscribers")
+	_, _ = coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "chat_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("chat_user_unique"),
+		},
+		{
+			// fan-out walks a channel's subscribers in _id order
+			Keys:    bson.D{{Key: "chat_id", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName("chat_scan_idx"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetName("user_idx"),
+		},
+	})
+	return &SubscriberRepository{coll: coll}
+}
+
+// Add subscribes userID to the channel and reports whether they were not
+// already. It is an upsert rather than an insert so an existing subscription does
+// not abort the surrounding transaction.
+func (r *SubscriberRepository) Add(ctx context.Context, chatID, userID string) (bool, error) {
+	res, err := r.coll.UpdateOne(ctx,
+		bson.M{"chat_id": chatID, "user_id": userID},
+		bson.M{"$setOnInsert": &models.Subscription{
+			ID:        primitive.NewObjectID(),
+			ChatID:    chatID,
+			UserID:    userID,
+			CreatedAt: time.Now().UTC(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.UpsertedCount > 0, nil
+}
+
+// Remove unsubscribes userID and reports whether they were subscribed.
+func (r *SubscriberRepository) Remove(ctx context.Context, chatID, userID string) (bool, error) {
+	res, err := r.coll.DeleteOne(ctx, bson.M{"chat_id": chatID, "user_id": userID})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (r *SubscriberRepository) Exists(ctx context.Context, chatID, userID string) (bool, error) {
+	n, err := r.coll.CountDocuments(ctx, bson.M{"chat_id": chatID, "user_id": userID}, options.Count().SetLimit(1))
+	return n > 0, err
+}
+
+// ListByUser returns up to limit ids of the channels userID follows, most
+// recently subscribed first, starting after cursor ("" for the first page).
+func (r *SubscriberRepository) ListByUser(ctx context.Context, userID, cursor string, limit int64) (chatIDs []string, next string, err error) {
+	filter := bson.M{"user_id": userID}
+	if cursor != "" {
+		after, err := primitive.ObjectIDFromHex(cursor)
+		if err != nil {
+			return nil, "", ErrBadCursor
+		}
+		filter["_id"] = bson.M{"$lt": after}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit + 1)
+	cur, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	var subs []models.Subscription
+	if err := cur.All(ctx, &subs); err != nil {
+		return nil, "", err
+	}
+	if int64(len(subs)) > limit {
+		subs = subs[:limit]
+		next = subs[len(subs)-1].ID.Hex()
+	}
+	chatIDs = make([]string, len(subs))
+	for i, s := range subs {
+		chatIDs[i] = s.ChatID
+	}
+	return chatIDs, next, nil
+}
+
+// Scan calls fn with the channel's subscriber ids, batch at a time.
+func (r *SubscriberRepository) Scan(ctx context.Context, chatID string, batch int, fn func(userIDs []string) error) error {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"user_id": 1}).
+		SetBatchSize(int32(batch))
+	cur, err := r.coll.Find(ctx, bson.M{"chat_id": chatID}, opts)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	ids := make([]string, 0, batch)
+	for cur.Next(ctx) {
+		var s models.Subscription
+		if err := cur.Decode(&s); err != nil {
+			return err
+		}
+		ids = append(ids, s.UserID)
+		if len(ids) == batch {
+			if err := fn(ids); err != nil {
+				return err
+			}
+			ids = make([]string, 0, batch)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		return fn(ids)
+	}
+	return nil
+}
+
+// DeleteChannel removes every subscription to a deleted channel.
+func (r *SubscriberRepository) DeleteChannel(ctx context.Context, chatID string) (int64, error) {
+	res, err := r.coll.DeleteMany(ctx, bson.M{"chat_id": chatID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestSubscriberScan(t *testing.T) {
+	errStop := errors.New("stop")
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	tests := []struct {
+		name    string
+		subs    int
+		batch   int
+		failAt  int // batch fn fails on, from 1; 0 never
+		want    []string
+		wantErr error
+	}{
+		{name: "full batches and a remainder", subs: 5, batch: 2, want: []string{"[u0 u1]", "[u2 u3]", "[u4]"}},
+		{name: "exact batches", subs: 4, batch: 2, want: []string{"[u0 u1]", "[u2 u3]"}},
+		{name: "no subscribers", subs: 0, batch: 2},
+		{name: "a failed batch stops the scan", subs: 5, batch: 2, failAt: 1, want: []string{"[u0 u1]"}, wantErr: errStop},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			r := &SubscriberRepository{coll: mt.Coll}
+			docs := make([]bson.D, tt.subs)
+			for i := range docs {
+				docs[i] = bson.D{{Key: "user_id", Value: fmt.Sprintf("u%d", i)}}
+			}
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "test."+mt.Coll.Name(), mtest.FirstBatch, docs...))
+
+			var got []string
+			err := r.Scan(context.Background(), "c1", tt.batch, func(ids []string) error {
+				got = append(got, fmt.Sprint(ids))
+				if len(got) == tt.failAt {
+					return errStop
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				mt.Fatalf("Scan err = %v, want %v", err, tt.wantErr)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				mt.Fatalf("batches %v, want %v", got, tt.want)
+			}
+			if q := mt.GetStartedEvent().Command.Lookup("filter", "chat_id").StringValue(); q != "c1" {
+				mt.Fatalf("scanned chat %q, want c1", q)
+			}
+		})
+	}
+}
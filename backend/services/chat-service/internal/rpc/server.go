@@ -0,0 +1,57 @@
+// Package rpc serves chat.v1 over the chat store. The plumbing every internal
+// API shares lives in platform/rpc.
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fathima-sithara/message-service/internal/pb/chatv1"
+	"github.com/fathima-sithara/message-service/internal/repository"
+	"github.com/fathima-sithara/message-service/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ChatServer implements chat.v1.ChatService on top of the chat store.
+type ChatServer struct {
+	chatv1.UnimplementedChatServiceServer
+	svc *service.ChatService
+}
+
+func NewChatServer(svc *service.ChatService) *ChatServer {
+	return &ChatServer{svc: svc}
+}
+
+func (s *ChatServer) GetMembership(ctx context.Context, req *chatv1.GetMembershipRequest) (*chatv1.GetMembershipResponse, error) {
+	if req.GetChatId() == "" || req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "chat_id and user_id are required")
+	}
+	chat, err := s.svc.GetChat(ctx, req.GetChatId())
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "chat not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &chatv1.GetMembershipResponse{
+		Member:         chat.IsMember(req.GetUserId()),
+		IsGroup:        chat.IsGroup,
+		Role:           string(chat.RoleOf(req.GetUserId())),
+		OnlyAdminsPost: chat.AdminsOnlyPost(),
+	}, nil
+}
+
+func (s *ChatServer) ListMembers(ctx context.Context, req *chatv1.ListMembersRequest) (*chatv1.ListMembersResponse, error) {
+	if req.GetChatId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "chat_id is required")
+	}
+	chat, err := s.svc.GetChat(ctx, req.GetChatId())
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "chat not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &chatv1.ListMembersResponse{UserIds: chat.Members, IsGroup: chat.IsGroup}, nil
+}
@@ -0,0 +1,72 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fathima-sithara/message-service/internal/pb/chatv1"
+	"github.com/fathima-sithara/message-service/internal/repository"
+	"github.com/fathima-sithara/message-service/internal/service"
+	"github.com/fathima-sithara/platform/outbox"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetMembership(t *testing.T) {
+	chat := func(fields ...bson.E) bson.D {
+		return append(bson.D{
+			{Key: "_id", Value: "c1"},
+			{Key: "members", Value: bson.A{"owner", "member"}},
+			{Key: "roles", Value: bson.D{{Key: "owner", Value: "owner"}}},
+		}, fields...)
+	}
+	group := bson.E{Key: "is_group", Value: true}
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	tests := []struct {
+		name string
+		user string
+		// chat is the stored chat; nil when there is none
+		chat     bson.D
+		want     *chatv1.GetMembershipResponse
+		wantCode codes.Code
+	}{
+		{name: "open group", user: "member", chat: chat(group),
+			want: &chatv1.GetMembershipResponse{Member: true, IsGroup: true, Role: "member"}},
+		{name: "admin-only group", user: "member", chat: chat(group, bson.E{Key: "only_admins_post", Value: true}),
+			want: &chatv1.GetMembershipResponse{Member: true, IsGroup: true, Role: "member", OnlyAdminsPost: true}},
+		{name: "channel", user: "owner", chat: chat(group, bson.E{Key: "is_channel", Value: true}),
+			want: &chatv1.GetMembershipResponse{Member: true, IsGroup: true, Role: "owner", OnlyAdminsPost: true}},
+		{name: "direct chat ignores the flag", user: "member", chat: chat(bson.E{Key: "only_admins_post", Value: true}),
+			want: &chatv1.GetMembershipResponse{Member: true, Role: "member"}},
+		{name: "outsider", user: "stranger", chat: chat(group, bson.E{Key: "only_admins_post", Value: true}),
+			want: &chatv1.GetMembershipResponse{IsGroup: true, OnlyAdminsPost: true}},
+		{name: "unknown chat", user: "member", wantCode: codes.NotFound},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			svc := service.NewChatService(repository.NewMongoRepository(mt.Coll), repository.NewInviteRepository(mt.DB),
+				repository.NewSubscriberRepository(mt.DB), outbox.NewStore(mt.DB, time.Hour), nil)
+			ns := "test." + mt.Coll.Name()
+			if tt.chat != nil {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, tt.chat))
+			} else {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch))
+			}
+
+			got, err := NewChatServer(svc).GetMembership(context.Background(), &chatv1.GetMembershipRequest{ChatId: "c1", UserId: tt.user})
+			if status.Code(err) != tt.wantCode {
+				mt.Fatalf("GetMembership err = %v, want %v", err, tt.wantCode)
+			}
+			if tt.want == nil {
+				return
+			}
+			if got.GetMember() != tt.want.Member || got.GetIsGroup() != tt.want.IsGroup ||
+				got.GetRole() != tt.want.Role || got.GetOnlyAdminsPost() != tt.want.OnlyAdminsPost {
+				mt.Fatalf("GetMembership = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fathima-sithara/message-service/internal/events"
+	"github.com/fathima-sithara/message-service/internal/models"
+	"github.com/fathima-sithara/message-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+// CreateChannel starts a broadcast channel owned by owner. The channel's members
+// are its team, who are managed like group members; only the owner and admins
+// post, and readers subscribe instead of joining.
+func (s *ChatService) CreateChannel(ctx context.Context, owner, name, description string) (*models.Chat, error) {
+	name = strings.TrimSpace(name)
+	if owner == "" || name == "" || len(name) > maxNameLen {
+		return nil, fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalid, maxNameLen)
+	}
+	if len(description) > maxDescriptionLen {
+		return nil, fmt.Errorf("%w: description must be at most %d characters", ErrInvalid, maxDescriptionLen)
+	}
+	chat := &models.Chat{
+		ID:                 uuid.NewString(),
+		Name:               name,
+		Description:        description,
+		IsGroup:            true,
+		IsChannel:          true,
+		Members:            []string{owner},
+		Roles:              map[string]models.Role{owner: models.RoleOwner},
+		OnlyAdminsPost:     true,
+		OnlyAdminsEditInfo: true,
+		CreatedAt:          time.Now().UTC(),
+		UpdatedAt:          time.Now().UTC(),
+	}
+	err := s.outbox.Tx(ctx, func(ctx context.Context) error {
+		if err := s.repo.CreateChat(ctx, chat); err != nil {
+			return err
+		}
+		return s.emit(ctx, events.SubjectChatCreated, chat, owner, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chat, nil
+}
+
+// GetChannel returns a channel and whether userID subscribes to it. Channels are
+// public: anyone who knows the id may look one up and subscribe.
+func (s *ChatService) GetChannel(ctx context.Context, userID, chatID string) (chat *models.Chat, subscribed bool, err error) {
+	chat, err = s.repo.GetChat(ctx, chatID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !chat.IsChannel {
+		return nil, false, ErrNotFound
+	}
+	subscribed, err = s.subs.Exists(ctx, chatID, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	return chat, subscribed, nil
+}
+
+// Subscribe makes userID a subscriber of the channel. Subscribing again changes
+// nothing; the team already gets every post and cannot subscribe.
+func (s *ChatService) Subscribe(ctx context.Context, userID, chatID string) (*models.Chat, error) {
+	chat, _, err := s.GetChannel(ctx, userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if chat.IsMember(userID) {
+		return nil, fmt.Errorf("%w: channel team members cannot subscribe", ErrConflict)
+	}
+	err = s.outbox.Tx(ctx, func(ctx context.Context) error {
+		added, err := s.subs.Add(ctx, chatID, userID)
+		if err != nil || !added {
+			return err
+		}
+		return s.repo.AddSubscribers(ctx, chatID, 1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetChat(ctx, chatID)
+}
+
+// Unsubscribe stops userID's subscription to the channel, if they have one.
+func (s *ChatService) Unsubscribe(ctx context.Context, userID, chatID string) error {
+	if _, _, err := s.GetChannel(ctx, userID, chatID); err != nil {
+		return err
+	}
+	return s.outbox.Tx(ctx, func(ctx context.Context) error {
+		removed, err := s.subs.Remove(ctx, chatID, userID)
+		if err != nil || !removed {
+			return err
+		}
+		return s.repo.AddSubscribers(ctx, chatID, -1)
+	})
+}
+
+// ListSubscribedChannels returns a page of the channels userID subscribes to,
+// most recently subscribed first, and the cursor of the next page.
+func (s *ChatService) ListSubscribedChannels(ctx context.Context, userID, cursor string, limit int64) ([]*models.Chat, string, error) {
+	ids, next, err := s.subs.ListByUser(ctx, userID, cursor, limit)
+	if errors.Is(err, repository.ErrBadCursor) {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if len(ids) == 0 {
+		return []*models.Chat{}, next, nil
+	}
+	chats, err := s.repo.GetChats(ctx, ids)
+	if err != nil {
+		return nil, "", err
+	}
+	return chats, next, nil
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// channelDoc is a stored channel run by owner, as the mock returns it from a find.
+func channelDoc(subscribers int) bson.D {
+	return bson.D{
+		{Key: "_id", Value: "c1"},
+		{Key: "is_group", Value: true},
+		{Key: "is_channel", Value: true},
+		{Key: "members", Value: bson.A{"owner"}},
+		{Key: "roles", Value: bson.D{{Key: "owner", Value: "owner"}}},
+		{Key: "subscriber_count", Value: subscribers},
+	}
+}
+
+// subscriberWrites lists the subscription writes and subscriber count changes
+// the mock received, in order.
+func subscriberWrites(mt *mtest.T) (subs []string, counts []int64) {
+	for _, c := range started(mt) {
+		switch {
+		case c.name == "update" && c.cmd.Lookup("update").StringValue() == "channel_subscribers":
+			subs = append(subs, "add "+c.first("updates").Lookup("q", "user_id").StringValue())
+		case c.name == "delete" && c.cmd.Lookup("delete").StringValue() == "channel_subscribers":
+			subs = append(subs, "remove "+c.first("deletes").Lookup("q", "user_id").StringValue())
+		case c.name == "update":
+			counts = append(counts, c.first("updates").Lookup("u", "$inc", "subscriber_count").AsInt64())
+		}
+	}
+	return subs, counts
+}
+
+func TestSubscribe(t *testing.T) {
+	none := func(mt *mtest.T) bson.D {
+		return mtest.CreateCursorResponse(0, "test."+mt.Coll.Name(), mtest.FirstBatch)
+	}
+	upserted := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1},
+		bson.E{Key: "upserted", Value: bson.A{bson.D{{Key: "index", Value: 0}, {Key: "_id", Value: "s1"}}}})
+	matched := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 0})
+	ok := mtest.CreateSuccessResponse()
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	tests := []struct {
+		name       string
+		user       string
+		responses  func(mt *mtest.T) []bson.D
+		wantErr    error
+		wantSubs   []string
+		wantCounts []int64
+	}{
+		{
+			name: "new subscriber is counted",
+			user: "reader",
+			responses: func(mt *mtest.T) []bson.D {
+				return []bson.D{found(mt, channelDoc(0)), none(mt), upserted, ok, ok, found(mt, channelDoc(1))}
+			},
+			wantSubs: []string{"add reader"}, wantCounts: []int64{1},
+		},
+		{
+			name: "subscribing again counts nothing",
+			user: "reader",
+			responses: func(mt *mtest.T) []bson.D {
+				return []bson.D{found(mt, channelDoc(1)), none(mt), matched, ok, found(mt, channelDoc(1))}
+			},
+			wantSubs: []string{"add reader"},
+		},
+		{
+			name: "team members cannot subscribe",
+			user: "owner",
+			responses: func(mt *mtest.T) []bson.D {
+				return []bson.D{found(mt, channelDoc(0)), none(mt)}
+			},
+			wantErr: ErrConflict,
+		},
+		{
+			name: "groups are not channels",
+			user: "reader",
+			responses: func(mt *mtest.T) []bson.D {
+				return []bson.D{found(mt, chatDoc(1, true, nil))}
+			},
+			wantErr: ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			svc := mockService(mt)
+			mt.AddMockResponses(tt.responses(mt)...)
+
+			_, err := svc.Subscribe(context.Background(), tt.user, "c1")
+			if !errors.Is(err, tt.wantErr) {
+				mt.Fatalf("Subscribe err = %v, want %v", err, tt.wantErr)
+			}
+			subs, counts := subscriberWrites(mt)
+			if !slices.Equal(subs, tt.wantSubs) || !slices.Equal(counts, tt.wantCounts) {
+				mt.Fatalf("writes %v, counts %v; want %v, %v", subs, counts, tt.wantSubs, tt.wantCounts)
+			}
+		})
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	removed := func(n int) bson.D { return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}) }
+	ok := mtest.CreateSuccessResponse()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	tests := []struct {
+		name       string
+		responses  []bson.D
+		wantCounts []int64
+	}{
+		{name: "subscriber leaves", responses: []bson.D{removed(1), ok, ok}, wantCounts: []int64{-1}},
+		{name: "not subscribed", responses: []bson.D{removed(0), ok}},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			svc := mockService(mt)
+			mt.AddMockResponses(found(mt, channelDoc(1)), mtest.CreateCursorResponse(0, "test."+mt.Coll.Name(), mtest.FirstBatch))
+			mt.AddMockResponses(tt.responses...)
+
+			if err := svc.Unsubscribe(context.Background(), "reader", "c1"); err != nil {
+				mt.Fatalf("Unsubscribe err = %v", err)
+			}
+			subs, counts := subscriberWrites(mt)
+			if !slices.Equal(subs, []string{"remove reader"}) || !slices.Equal(counts, tt.wantCounts) {
+				mt.Fatalf("writes %v, counts %v; want [remove reader], %v", subs, counts, tt.wantCounts)
+			}
+		})
+	}
+}
@@ -3,21 +3,67 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/fathima-sithara/message-service/internal/events"
 	"github.com/fathima-sithara/message-service/internal/models"
+	"github.com/fathima-sithara/message-service/internal/pb/userv1"
 	"github.com/fathima-sithara/message-service/internal/repository"
+	"github.com/fathima-sithara/platform/outbox"
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrNotFound covers unknown chats and chats the caller is not in, so
+	// non-members cannot probe which chat ids exist.
+	ErrNotFound = repository.ErrNotFound
+	// ErrForbidden is returned when the caller's role does not allow the action.
+	ErrForbidden = errors.New("forbidden")
+	// ErrInvalid wraps malformed input.
+	ErrInvalid = errors.New("invalid request")
+	// ErrConflict wraps actions that clash with the chat's current state, such as
+	// adding an existing member or the owner leaving without a successor.
+	ErrConflict = errors.New("conflict")
+	// ErrUnknownUser is returned when a participant has no user-service profile.
+	ErrUnknownUser = errors.New("unknown user")
+	// ErrExpired is returned for invite links that were revoked, have expired or
+	// are used up.
+	ErrExpired = errors.New("invite no longer valid")
+)
+
 type ChatService struct {
-	repo *repository.Repository
-	pub  *events.Publisher
+	repo    *repository.Repository
+	invites *repository.InviteRepository
+	subs    *repository.SubscriberRepository
+	outbox  *outbox.Store
+	users   userv1.UserServiceClient
 }
 
-func NewChatService(r *repository.Repository, p *events.Publisher) *ChatService {
-	return &ChatService{repo: r, pub: p}
+// NewChatService wires the chat, invite and channel subscriber stores. Lifecycle
+// events are written to ob with the change they describe. users may be nil, in
+// which case participants are not checked against user-service.
+func NewChatService(r *repository.Repository, inv *repository.InviteRepository, subs *repository.SubscriberRepository, ob *outbox.Store, users userv1.UserServiceClient) *ChatService {
+	return &ChatService{repo: r, invites: inv, subs: subs, outbox: ob, users: users}
+}
+
+// checkUsers fails with ErrUnknownUser unless every id has a profile.
+func (s *ChatService) checkUsers(ctx context.Context, ids ...string) error {
+	if s.users == nil || len(ids) == 0 {
+		return nil
+	}
+	resp, err := s.users.BatchGetProfiles(ctx, &userv1.BatchGetProfilesRequest{UserIds: ids})
+	if err != nil {
+		return fmt.Errorf("user lookup: %w", err)
+	}
+	for _, id := range ids {
+		if _, ok := resp.GetProfiles()[id]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownUser, id)
+		}
+	}
+	return nil
 }
 
 func contains(arr []string, id string) bool {
@@ -29,70 +75,459 @@ func contains(arr []string, id string) bool {
 	return false
 }
 
-func (s *ChatService) CreateDM(ctx context.Context, a, b, name string) (*models.Chat, error) {
+// CreateDM returns the direct chat between a and b, creating it on first use;
+// created reports whether it is new. name only applies to a new chat.
+func (s *ChatService) CreateDM(ctx context.Context, a, b, name string) (chat *models.Chat, created bool, err error) {
 	if a == "" || b == "" || a == b {
-		return nil, errors.New("invalid participants")
+		return nil, false, fmt.Errorf("%w: invalid participants", ErrInvalid)
 	}
-	chat := &models.Chat{
+	key := models.DMKey(a, b)
+	existing, err := s.repo.GetDM(ctx, key)
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, false, err
+	}
+	if err := s.checkUsers(ctx, b); err != nil {
+		return nil, false, err
+	}
+	chat = &models.Chat{
 		ID:        uuid.NewString(),
 		Name:      name,
 		IsGroup:   false,
 		Members:   []string{a, b},
+		DMKey:     key,
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
 	}
-	if err := s.repo.CreateChat(ctx, chat); err != nil {
-		return nil, err
+	err = s.outbox.Tx(ctx, func(ctx context.Context) error {
+		if err := s.repo.CreateChat(ctx, chat); err != nil {
+			return err
+		}
+		return s.emit(ctx, events.SubjectChatCreated, chat, a, "")
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		// a concurrent request created it first
+		existing, err := s.repo.GetDM(ctx, key)
+		return existing, false, err
 	}
-	if s.pub != nil {
-		_ = s.pub.PublishChatCreated(chat.ID, chat.Name, chat.Members, chat.IsGroup)
+	if err != nil {
+		return nil, false, err
 	}
-	return chat, nil
+	return chat, true, nil
 }
 
 func (s *ChatService) CreateGroup(ctx context.Context, owner, name string, members []string) (*models.Chat, error) {
 	if owner == "" || name == "" {
-		return nil, errors.New("invalid data")
+		return nil, fmt.Errorf("%w: owner and name are required", ErrInvalid)
 	}
 	if !contains(members, owner) {
 		members = append(members, owner)
 	}
-	chat := &models.Chat{ID: uuid.NewString(), Name: name, IsGroup: true, Members: members, CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC()}
-	if err := s.repo.CreateChat(ctx, chat); err != nil {
+	if len(members) > models.MaxGroupMembers {
+		return nil, fmt.Errorf("%w: a group has at most %d members", ErrInvalid, models.MaxGroupMembers)
+	}
+	if err := s.checkUsers(ctx, members...); err != nil {
 		return nil, err
 	}
-	if s.pub != nil {
-		_ = s.pub.PublishChatCreated(chat.ID, chat.Name, chat.Members, chat.IsGroup)
+	chat := &models.Chat{
+		ID:                 uuid.NewString(),
+		Name:               name,
+		IsGroup:            true,
+		Members:            members,
+		Roles:              map[string]models.Role{owner: models.RoleOwner},
+		OnlyAdminsEditInfo: true,
+		CreatedAt:          time.Now().UTC(),
+		UpdatedAt:          time.Now().UTC(),
+	}
+	err := s.outbox.Tx(ctx, func(ctx context.Context) error {
+		if err := s.repo.CreateChat(ctx, chat); err != nil {
+			return err
+		}
+		return s.emit(ctx, events.SubjectChatCreated, chat, owner, "")
+	})
+	if err != nil {
+		return nil, err
 	}
 	return chat, nil
 }
 
+// GetChat loads a chat without any access check; it backs internal callers such
+// as the gRPC API. Handlers acting for a user use GetChatForUser.
 func (s *ChatService) GetChat(ctx context.Context, id string) (*models.Chat, error) {
 	return s.repo.GetChat(ctx, id)
 }
 
-func (s *ChatService) ListUserChats(ctx context.Context, userID string, limit int64) ([]*models.Chat, error) {
-	return s.repo.ListChatsForUser(ctx, userID, limit)
+// GetChatForUser returns the chat if userID is a member and ErrNotFound otherwise.
+func (s *ChatService) GetChatForUser(ctx context.Context, userID, chatID string) (*models.Chat, error) {
+	chat, err := s.repo.GetChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if !chat.IsMember(userID) {
+		return nil, ErrNotFound
+	}
+	return chat, nil
 }
 
-func (s *ChatService) AddMember(ctx context.Context, chatID, userID string) error {
-	chat, err := s.repo.GetChat(ctx, chatID)
+// manageableGroup loads a group actor may administer.
+func (s *ChatService) manageableGroup(ctx context.Context, actor, chatID string) (*models.Chat, error) {
+	chat, err := s.GetChatForUser(ctx, actor, chatID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if !chat.IsGroup {
+		return nil, fmt.Errorf("%w: not a group", ErrInvalid)
 	}
-	if contains(chat.Members, userID) {
-		return errors.New("already a member")
+	if !chat.CanManage(actor) {
+		return nil, ErrForbidden
+	}
+	return chat, nil
+}
+
+// ListUserChats returns a page of userID's chats and the cursor of the next one.
+func (s *ChatService) ListUserChats(ctx context.Context, userID string, f repository.ChatFilter, cursor string, limit int64) ([]*models.Chat, string, error) {
+	chats, next, err := s.repo.ListChatsForUser(ctx, userID, f, cursor, limit)
+	if errors.Is(err, repository.ErrBadCursor) {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return chats, next, err
+}
+
+// AddMember lets a group owner or admin add userID.
+func (s *ChatService) AddMember(ctx context.Context, actor, chatID, userID string) error {
+	return retryStale(func() error {
+		chat, err := s.manageableGroup(ctx, actor, chatID)
+		if err != nil {
+			return err
+		}
+		if chat.IsMember(userID) {
+			return fmt.Errorf("%w: already a member", ErrConflict)
+		}
+		if err := s.checkUsers(ctx, userID); err != nil {
+			return err
+		}
+		return s.outbox.Tx(ctx, func(ctx context.Context) error {
+			return s.addMember(ctx, actor, chat, userID)
+		})
+	})
+}
+
+// addMember adds userID to chat unless the group is full. Call it inside
+// outbox.Tx.
+func (s *ChatService) addMember(ctx context.Context, actor string, chat *models.Chat, userID string) error {
+	if len(chat.Members) >= chat.MemberCap() {
+		return fmt.Errorf("%w: group is full", ErrConflict)
 	}
 	chat.Members = append(chat.Members, userID)
 	chat.UpdatedAt = time.Now().UTC()
-	return s.repo.UpdateChat(ctx, chat)
+	return s.save(ctx, chat, events.SubjectChatMemberAdded, actor, userID)
+}
+
+// RemoveMember lets a group owner or admin remove a member ranked below them.
+// Removing yourself is Leave.
+func (s *ChatService) RemoveMember(ctx context.Context, actor, chatID, userID string) error {
+	if actor == userID {
+		return s.Leave(ctx, actor, chatID)
+	}
+	return retryStale(func() error {
+		chat, err := s.manageableGroup(ctx, actor, chatID)
+		if err != nil {
+			return err
+		}
+		target := chat.RoleOf(userID)
+		if target == "" {
+			return fmt.Errorf("%w: not a member", ErrNotFound)
+		}
+		if !chat.RoleOf(actor).Outranks(target) {
+			return ErrForbidden
+		}
+		return s.removeMember(ctx, actor, chat, userID)
+	})
+}
+
+// Leave removes userID from a group. The owner has to transfer ownership first
+// unless they are the last member.
+func (s *ChatService) Leave(ctx context.Context, userID, chatID string) error {
+	return retryStale(func() error {
+		chat, err := s.GetChatForUser(ctx, userID, chatID)
+		if err != nil {
+			return err
+		}
+		if !chat.IsGroup {
+			return fmt.Errorf("%w: direct chats cannot be left", ErrInvalid)
+		}
+		if chat.RoleOf(userID) == models.RoleOwner && len(chat.Members) > 1 {
+			return fmt.Errorf("%w: transfer ownership before leaving", ErrConflict)
+		}
+		return s.removeMember(ctx, userID, chat, userID)
+	})
+}
+
+// TransferOwnership hands the group to another member; the previous owner stays
+// on as an admin.
+func (s *ChatService) TransferOwnership(ctx context.Context, actor, chatID, newOwner string) error {
+	return retryStale(func() error {
+		chat, err := s.GetChatForUser(ctx, actor, chatID)
+		if err != nil {
+			return err
+		}
+		if !chat.IsGroup {
+			return fmt.Errorf("%w: not a group", ErrInvalid)
+		}
+		if chat.RoleOf(actor) != models.RoleOwner {
+			return ErrForbidden
+		}
+		if newOwner == actor {
+			return nil
+		}
+		if !chat.IsMember(newOwner) {
+			return fmt.Errorf("%w: new owner must be a member", ErrInvalid)
+		}
+		chat.Roles[actor] = models.RoleAdmin
+		chat.Roles[newOwner] = models.RoleOwner
+		chat.UpdatedAt = time.Now().UTC()
+		return s.update(ctx, chat, events.SubjectChatRoleChanged, actor, newOwner)
+	})
+}
+
+// SetRole lets the owner promote a member to admin or demote an admin.
+func (s *ChatService) SetRole(ctx context.Context, actor, chatID, userID string, role models.Role) error {
+	if role != models.RoleAdmin && role != models.RoleMember {
+		return fmt.Errorf("%w: role must be admin or member", ErrInvalid)
+	}
+	return retryStale(func() error {
+		chat, err := s.GetChatForUser(ctx, actor, chatID)
+		if err != nil {
+			return err
+		}
+		if !chat.IsGroup {
+			return fmt.Errorf("%w: not a group", ErrInvalid)
+		}
+		if chat.RoleOf(actor) != models.RoleOwner {
+			return ErrForbidden
+		}
+		switch chat.RoleOf(userID) {
+		case "":
+			return fmt.Errorf("%w: not a member", ErrNotFound)
+		case models.RoleOwner:
+			return fmt.Errorf("%w: use ownership transfer", ErrConflict)
+		}
+		if role == models.RoleMember {
+			delete(chat.Roles, userID)
+		} else {
+			chat.Roles[userID] = role
+		}
+		chat.UpdatedAt = time.Now().UTC()
+		return s.update(ctx, chat, events.SubjectChatRoleChanged, actor, userID)
+	})
+}
+
+// Limits on chat info.
+const (
+	maxNameLen        = 100
+	maxDescriptionLen = 500
+	maxAvatarURLLen   = 2048
+)
+
+// ChatUpdate changes some of a chat's info and settings; nil fields are left
+// alone. An empty Description or AvatarURL removes it, and a zero MaxMembers
+// falls back to models.MaxGroupMembers.
+type ChatUpdate struct {
+	Name               *string
+	Description        *string
+	AvatarURL          *string
+	OnlyAdminsPost     *bool
+	OnlyAdminsEditInfo *bool
+	MaxMembers         *int
+}
+
+// UpdateChat applies u for actor and returns the chat afterwards. Name,
+// description and avatar follow Chat.CanEditInfo; the group settings need an
+// owner or admin. Direct chats only have a name. Each change is recorded in the
+// chat as a system message.
+func (s *ChatService) UpdateChat(ctx context.Context, actor, chatID string, u ChatUpdate) (chat *models.Chat, err error) {
+	err = retryStale(func() error {
+		chat, err = s.updateChat(ctx, actor, chatID, u)
+		return err
+	})
+	return chat, err
+}
+
+func (s *ChatService) updateChat(ctx context.Context, actor, chatID string, u ChatUpdate) (*models.Chat, error) {
+	chat, err := s.GetChatForUser(ctx, actor, chatID)
+	if err != nil {
+		return nil, err
+	}
+	info := u.Name != nil || u.Description != nil || u.AvatarURL != nil
+	settings := u.OnlyAdminsPost != nil || u.OnlyAdminsEditInfo != nil || u.MaxMembers != nil
+	if !info && !settings {
+		return nil, fmt.Errorf("%w: nothing to update", ErrInvalid)
+	}
+	if !chat.IsGroup && (settings || u.Description != nil || u.AvatarURL != nil) {
+		return nil, fmt.Errorf("%w: direct chats only have a name", ErrInvalid)
+	}
+	if chat.IsChannel && u.OnlyAdminsPost != nil {
+		return nil, fmt.Errorf("%w: only admins post to a channel", ErrInvalid)
+	}
+	if info && !chat.CanEditInfo(actor) {
+		return nil, ErrForbidden
+	}
+	if settings && !chat.CanManage(actor) {
+		return nil, ErrForbidden
+	}
+
+	kind := "chat"
+	switch {
+	case chat.IsChannel:
+		kind = "channel"
+	case chat.IsGroup:
+		kind = "group"
+	}
+	var changes []string
+	if u.Name != nil {
+		name := strings.TrimSpace(*u.Name)
+		if name == "" || len(name) > maxNameLen {
+			return nil, fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalid, maxNameLen)
+		}
+		if name != chat.Name {
+			chat.Name = name
+			changes = append(changes, fmt.Sprintf("changed the %s name to %q", kind, name))
+		}
+	}
+	if u.Description != nil && *u.Description != chat.Description {
+		if len(*u.Description) > maxDescriptionLen {
+			return nil, fmt.Errorf("%w: description must be at most %d characters", ErrInvalid, maxDescriptionLen)
+		}
+		chat.Description = *u.Description
+		changes = append(changes, pick(chat.Description == "", "removed the "+kind+" description", "changed the "+kind+" description"))
+	}
+	if u.AvatarURL != nil && *u.AvatarURL != chat.AvatarURL {
+		if *u.AvatarURL != "" && !validMediaURL(*u.AvatarURL) {
+			return nil, fmt.Errorf("%w: avatar_url must be an http(s) URL of at most %d characters", ErrInvalid, maxAvatarURLLen)
+		}
+		chat.AvatarURL = *u.AvatarURL
+		changes = append(changes, pick(chat.AvatarURL == "", "removed the "+kind+" photo", "changed the "+kind+" photo"))
+	}
+	if u.OnlyAdminsPost != nil && *u.OnlyAdminsPost != chat.OnlyAdminsPost {
+		chat.OnlyAdminsPost = *u.OnlyAdminsPost
+		changes = append(changes, pick(chat.OnlyAdminsPost,
+			"allowed only admins to send messages", "allowed all members to send messages"))
+	}
+	if u.OnlyAdminsEditInfo != nil && *u.OnlyAdminsEditInfo != chat.OnlyAdminsEditInfo {
+		chat.OnlyAdminsEditInfo = *u.OnlyAdminsEditInfo
+		changes = append(changes, pick(chat.OnlyAdminsEditInfo,
+			"allowed only admins to edit group info", "allowed all members to edit group info"))
+	}
+	if u.MaxMembers != nil && *u.MaxMembers != chat.MaxMembers {
+		n := *u.MaxMembers
+		if n != 0 && (n < len(chat.Members) || n > models.MaxGroupMembers) {
+			return nil, fmt.Errorf("%w: max_members must be 0 or between the current member count and %d", ErrInvalid, models.MaxGroupMembers)
+		}
+		chat.MaxMembers = n
+		changes = append(changes, pick(n == 0, "removed the member limit", fmt.Sprintf("set the member limit to %d", n)))
+	}
+	if len(changes) == 0 {
+		return chat, nil
+	}
+
+	who := s.displayName(ctx, actor)
+	notices := make([]string, len(changes))
+	for i, c := range changes {
+		notices[i] = who + " " + c
+	}
+	chat.UpdatedAt = time.Now().UTC()
+	if err := s.update(ctx, chat, events.SubjectChatUpdated, actor, "", notices...); err != nil {
+		return nil, err
+	}
+	return chat, nil
 }
 
-func (s *ChatService) RemoveMember(ctx context.Context, chatID, userID string) error {
+// CheckCanPost returns ErrForbidden unless userID may send messages to the chat.
+func (s *ChatService) CheckCanPost(ctx context.Context, chatID, userID string) error {
 	chat, err := s.repo.GetChat(ctx, chatID)
 	if err != nil {
 		return err
 	}
+	if !chat.CanPost(userID) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// displayName is how system messages name a user: their username, or their id
+// when user-service cannot say.
+func (s *ChatService) displayName(ctx context.Context, userID string) string {
+	if s.users == nil {
+		return userID
+	}
+	resp, err := s.users.GetProfile(ctx, &userv1.GetProfileRequest{UserId: userID})
+	if err != nil || resp.GetProfile().GetUsername() == "" {
+		return userID
+	}
+	return resp.GetProfile().GetUsername()
+}
+
+func validMediaURL(s string) bool {
+	if len(s) > maxAvatarURLLen {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+func pick(cond bool, yes, no string) string {
+	if cond {
+		return yes
+	}
+	return no
+}
+
+// DeleteChat lets the owner delete a group or channel and returns what was
+// deleted. Direct chats cannot be deleted. A change that lands between reading
+// the chat and deleting it makes the owner's checks run again on the new state.
+func (s *ChatService) DeleteChat(ctx context.Context, actor, chatID string) (chat *models.Chat, err error) {
+	err = retryStale(func() error {
+		chat, err = s.deleteChat(ctx, actor, chatID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if chat.IsChannel {
+		// too many for the transaction; subscriptions left behind by a failure
+		// point at a missing chat, which listings skip and fan-out never reaches
+		_, _ = s.subs.DeleteChannel(ctx, chatID)
+	}
+	return chat, nil
+}
+
+func (s *ChatService) deleteChat(ctx context.Context, actor, chatID string) (*models.Chat, error) {
+	chat, err := s.GetChatForUser(ctx, actor, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if !chat.IsGroup {
+		return nil, fmt.Errorf("%w: direct chats cannot be deleted", ErrInvalid)
+	}
+	if chat.RoleOf(actor) != models.RoleOwner {
+		return nil, ErrForbidden
+	}
+	chat.UpdatedAt = time.Now().UTC()
+	err = s.outbox.Tx(ctx, func(ctx context.Context) error {
+		if err := s.repo.DeleteChat(ctx, chat); err != nil {
+			return err
+		}
+		return s.emit(ctx, events.SubjectChatDeleted, chat, actor, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chat, nil
+}
+
+func (s *ChatService) removeMember(ctx context.Context, actor string, chat *models.Chat, userID string) error {
 	newList := []string{}
 	for _, m := range chat.Members {
 		if m != userID {
@@ -100,16 +535,61 @@ func (s *ChatService) RemoveMember(ctx context.Context, chatID, userID string) e
 		}
 	}
 	chat.Members = newList
+	delete(chat.Roles, userID)
 	chat.UpdatedAt = time.Now().UTC()
-	return s.repo.UpdateChat(ctx, chat)
+	return s.update(ctx, chat, events.SubjectChatMemberRemoved, actor, userID)
 }
 
-func (s *ChatService) UpdateName(ctx context.Context, chatID, name string) error {
-	chat, err := s.repo.GetChat(ctx, chatID)
-	if err != nil {
+// staleRetries bounds how often a change is redone after losing a race with
+// another change to the same chat.
+const staleRetries = 5
+
+// retryStale runs op again while it loses such a race. op must load the chat
+// afresh each time, so its checks see the winning change.
+func retryStale(op func() error) error {
+	for i := 0; i < staleRetries; i++ {
+		if err := op(); !errors.Is(err, repository.ErrStale) {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: the chat is changing too quickly, try again", ErrConflict)
+}
+
+// update saves chat and records subject for it in one transaction. It fails
+// with repository.ErrStale when chat changed since it was loaded.
+func (s *ChatService) update(ctx context.Context, chat *models.Chat, subject, actor, user string, notices ...string) error {
+	return s.outbox.Tx(ctx, func(ctx context.Context) error {
+		return s.save(ctx, chat, subject, actor, user, notices...)
+	})
+}
+
+// save is update for callers already inside outbox.Tx.
+func (s *ChatService) save(ctx context.Context, chat *models.Chat, subject, actor, user string, notices ...string) error {
+	if err := s.repo.UpdateChat(ctx, chat); err != nil {
 		return err
 	}
-	chat.Name = name
-	chat.UpdatedAt = time.Now().UTC()
-	return s.repo.UpdateChat(ctx, chat)
+	return s.emit(ctx, subject, chat, actor, user, notices...)
+}
+
+// emit adds a lifecycle event for chat to the outbox; user is the member it
+// concerns, if any, and notices become system messages. Call it inside outbox.Tx.
+func (s *ChatService) emit(ctx context.Context, subject string, chat *models.Chat, actor, user string, notices ...string) error {
+	return s.outbox.Add(ctx, subject, events.ChatEvent{
+		ChatID:             chat.ID,
+		Version:            chat.Version,
+		Name:               chat.Name,
+		Description:        chat.Description,
+		AvatarURL:          chat.AvatarURL,
+		IsGroup:            chat.IsGroup,
+		IsChannel:          chat.IsChannel,
+		OnlyAdminsPost:     chat.OnlyAdminsPost,
+		OnlyAdminsEditInfo: chat.OnlyAdminsEditInfo,
+		MaxMembers:         chat.MaxMembers,
+		Members:            chat.Members,
+		Roles:              chat.Roles,
+		UserID:             user,
+		ActorID:            actor,
+		Notices:            notices,
+		At:                 chat.UpdatedAt,
+	})
 }
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fathima-sithara/message-service/internal/events"
+	"github.com/fathima-sithara/message-service/internal/repository"
+	"github.com/fathima-sithara/platform/outbox"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// mockService runs a ChatService against mt's mock deployment, which answers
+// each command with the next response queued by mt.AddMockResponses.
+func mockService(mt *mtest.T) *ChatService {
+	svc := NewChatService(repository.NewMongoRepository(mt.Coll), repository.NewInviteRepository(mt.DB),
+		repository.NewSubscriberRepository(mt.DB), outbox.NewStore(mt.DB, time.Hour), nil)
+	// index creation and migrations ran against an empty queue
+	mt.ClearEvents()
+	return svc
+}
+
+// chatDoc is a stored chat as the mock returns it from a find.
+func chatDoc(version int, isGroup bool, roles bson.D) bson.D {
+	return bson.D{
+		{Key: "_id", Value: "c1"},
+		{Key: "is_group", Value: isGroup},
+		{Key: "members", Value: bson.A{"owner", "member"}},
+		{Key: "roles", Value: roles},
+		{Key: "version", Value: version},
+	}
+}
+
+func found(mt *mtest.T, doc bson.D) bson.D {
+	return mtest.CreateCursorResponse(0, "test."+mt.Coll.Name(), mtest.FirstBatch, doc)
+}
+
+// started lists the commands the mock received, in order.
+func started(mt *mtest.T) []*commandEvent {
+	var out []*commandEvent
+	for ev := mt.GetStartedEvent(); ev != nil; ev = mt.GetStartedEvent() {
+		out = append(out, &commandEvent{name: ev.CommandName, cmd: ev.Command})
+	}
+	return out
+}
+
+type commandEvent struct {
+	name string
+	cmd  bson.Raw
+}
+
+// first returns the first document of the command's array field, such as the
+// statements of a delete or the documents of an insert.
+func (c *commandEvent) first(field string) bson.Raw {
+	return c.cmd.Lookup(field).Array().Index(0).Value().Document()
+}
+
+func TestDeleteChat(t *testing.T) {
+	owner := bson.D{{Key: "owner", Value: "owner"}}
+	deleted := func(n int) bson.D { return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}) }
+	count := func(mt *mtest.T, n int) bson.D {
+		return found(mt, bson.D{{Key: "n", Value: n}})
+	}
+	ok := mtest.CreateSuccessResponse()
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	tests := []struct {
+		name      string
+		actor     string
+		responses func(mt *mtest.T) []bson.D
+		wantErr   error
+		// wantVersions are the versions each delete attempt was guarded by
+		wantVersions []int64
+		wantEvent    int64
+	}{
+		{
+			name:  "owner deletes",
+			actor: "owner",
+			responses: func(mt *mtest.T) []bson.D {
+				return []bson.D{found(mt, chatDoc(3, true, owner)), deleted(1), ok, ok}
+			},
+			wantVersions: []int64{3},
+			wantEvent:    4,
+		},
+		{
+			name:  "a concurrent change is retried on the new version",
+			actor: "owner",
+			responses: func(mt *mtest.T) []bson.D {
+				return []bson.D{
+					found(mt, chatDoc(3, true, owner)), deleted(0), count(mt, 1), ok,
+					found(mt, chatDoc(4, true, owner)), deleted(1), ok, ok,
+				}
+			},
+			wantVersions: []int64{3, 4},
+			wantEvent:    5,
+		},
+		{
+			name:  "ownership lost meanwhile",
+			actor: "owner",
+			responses: func(mt *mtest.T) []bson.D {
+				return []bson.D{
+					found(mt, chatDoc(3, true, owner)), deleted(0), count(mt, 1), ok,
+					found(mt, chatDoc(4, true, bson.D{{Key: "member", Value: "owner"}})),
+				}
+			},
+			wantErr:      ErrForbidden,
+			wantVersions: []int64{3},
+		},
+		{
+			name:  "deleted by someone else meanwhile",
+			actor: "owner",
+			responses: func(mt *mtest.T) []bson.D {
+				return []bson.D{found(mt, chatDoc(3, true, owner)), deleted(0), count(mt, 0), ok}
+			},
+			wantErr:      ErrNotFound,
+			wantVersions: []int64{3},
+		},
+		{
+			name:  "not the owner",
+			actor: "member",
+			responses: func(mt *mtest.T) []bson.D {
+				return []bson.D{found(mt, chatDoc(3, true, owner))}
+			},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:  "direct chat",
+			actor: "owner",
+			responses: func(mt *mtest.T) []bson.D {
+				return []bson.D{found(mt, chatDoc(3, false, nil))}
+			},
+			wantErr: ErrInvalid,
+		},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			svc := mockService(mt)
+			mt.AddMockResponses(tt.responses(mt)...)
+
+			chat, err := svc.DeleteChat(context.Background(), tt.actor, "c1")
+			if !errors.Is(err, tt.wantErr) {
+				mt.Fatalf("DeleteChat err = %v, want %v", err, tt.wantErr)
+			}
+
+			var versions []int64
+			var event *events.ChatEvent
+			for _, c := range started(mt) {
+				switch c.name {
+				case "delete":
+					versions = append(versions, c.first("deletes").Lookup("q", "version").AsInt64())
+				case "insert":
+					_, data := c.first("documents").Lookup("data").Binary()
+					event = &events.ChatEvent{}
+					if err := json.Unmarshal(data, event); err != nil {
+						mt.Fatal(err)
+					}
+				}
+			}
+			if len(versions) != len(tt.wantVersions) {
+				mt.Fatalf("deletes guarded by versions %v, want %v", versions, tt.wantVersions)
+			}
+			for i := range versions {
+				if versions[i] != tt.wantVersions[i] {
+					mt.Fatalf("deletes guarded by versions %v, want %v", versions, tt.wantVersions)
+				}
+			}
+			if tt.wantErr != nil {
+				if event != nil {
+					mt.Fatalf("chat.deleted recorded for a failed delete: %+v", event)
+				}
+				return
+			}
+			if chat.Version != tt.wantEvent || event == nil || event.Version != tt.wantEvent {
+				mt.Fatalf("chat version %d, event %+v; want both at %d", chat.Version, event, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestDeleteChatGivesUpOnChurn(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("churn", func(mt *mtest.T) {
+		svc := mockService(mt)
+		for i := 0; i < staleRetries; i++ {
+			mt.AddMockResponses(
+				found(mt, chatDoc(3+i, true, bson.D{{Key: "owner", Value: "owner"}})),
+				mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+				found(mt, bson.D{{Key: "n", Value: 1}}),
+				mtest.CreateSuccessResponse(),
+			)
+		}
+		if _, err := svc.DeleteChat(context.Background(), "owner", "c1"); !errors.Is(err, ErrConflict) {
+			mt.Fatalf("DeleteChat err = %v, want ErrConflict", err)
+		}
+	})
+}
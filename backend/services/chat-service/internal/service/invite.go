@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fathima-sithara/message-service/internal/models"
+	"github.com/fathima-sithara/message-service/internal/repository"
+)
+
+// InviteOptions configures a new invite link. Zero values mean no expiry, no use
+// limit and no approval step.
+type InviteOptions struct {
+	TTL             time.Duration
+	MaxUses         int
+	RequireApproval bool
+}
+
+// CreateInvite lets a group owner or admin create an invite link.
+func (s *ChatService) CreateInvite(ctx context.Context, actor, chatID string, opts InviteOptions) (*models.Invite, error) {
+	if opts.TTL < 0 || opts.MaxUses < 0 {
+		return nil, fmt.Errorf("%w: expiry and max uses must not be negative", ErrInvalid)
+	}
+	chat, err := s.manageableGroup(ctx, actor, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if chat.IsChannel {
+		return nil, fmt.Errorf("%w: readers subscribe to a channel instead", ErrInvalid)
+	}
+	token, err := newInviteToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	inv := &models.Invite{
+		Token:           token,
+		ChatID:          chatID,
+		CreatedBy:       actor,
+		MaxUses:         opts.MaxUses,
+		RequireApproval: opts.RequireApproval,
+		CreatedAt:       now,
+	}
+	if opts.TTL > 0 {
+		exp := now.Add(opts.TTL)
+		inv.ExpiresAt = &exp
+	}
+	if err := s.invites.CreateInvite(ctx, inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// ListInvites returns a group's links that have not been revoked.
+func (s *ChatService) ListInvites(ctx context.Context, actor, chatID string) ([]*models.Invite, error) {
+	if _, err := s.manageableGroup(ctx, actor, chatID); err != nil {
+		return nil, err
+	}
+	return s.invites.ListInvites(ctx, chatID)
+}
+
+func (s *ChatService) RevokeInvite(ctx context.Context, actor, chatID, token string) error {
+	if _, err := s.manageableGroup(ctx, actor, chatID); err != nil {
+		return err
+	}
+	return s.invites.RevokeInvite(ctx, chatID, token)
+}
+
+// JoinByInvite adds userID to the invite's group, or queues a join request when
+// the link requires approval, in which case pending is true. A request takes its
+// use when it is approved. Joining a group you are already in, or repeating a
+// pending request, is a no-op and takes no use.
+func (s *ChatService) JoinByInvite(ctx context.Context, userID, token string) (chat *models.Chat, pending bool, err error) {
+	err = retryStale(func() error {
+		inv, err := s.invites.GetInvite(ctx, token)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		if !inv.Usable(now) {
+			return ErrExpired
+		}
+		chat, err = s.repo.GetChat(ctx, inv.ChatID)
+		if err != nil {
+			return err
+		}
+		if chat.IsMember(userID) {
+			return nil
+		}
+
+		if inv.RequireApproval {
+			req := &models.JoinRequest{ChatID: chat.ID, UserID: userID, InviteToken: token, CreatedAt: now}
+			if err := s.invites.CreateJoinRequest(ctx, req); err != nil && !errors.Is(err, repository.ErrDuplicate) {
+				return err
+			}
+			chat, pending = nil, true
+			return nil
+		}
+
+		return s.outbox.Tx(ctx, func(ctx context.Context) error {
+			if err := s.useInvite(ctx, token, now); err != nil {
+				return err
+			}
+			// a request from an approval link is moot now
+			if err := s.invites.DeleteJoinRequest(ctx, chat.ID, userID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+			return s.addMember(ctx, userID, chat, userID)
+		})
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return chat, pending, nil
+}
+
+// useInvite takes a use, reporting a link that ran out in the meantime as ErrExpired.
+func (s *ChatService) useInvite(ctx context.Context, token string, now time.Time) error {
+	err := s.invites.UseInvite(ctx, token, now)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrExpired
+	}
+	return err
+}
+
+// ListJoinRequests returns a group's pending requests, oldest first.
+func (s *ChatService) ListJoinRequests(ctx context.Context, actor, chatID string) ([]*models.JoinRequest, error) {
+	if _, err := s.manageableGroup(ctx, actor, chatID); err != nil {
+		return nil, err
+	}
+	return s.invites.ListJoinRequests(ctx, chatID)
+}
+
+// ApproveJoinRequest adds a pending requester to the group, taking a use of the
+// link they asked through. A link that has since been revoked, expired or used
+// up admits no one, and the request stays pending until it is rejected.
+func (s *ChatService) ApproveJoinRequest(ctx context.Context, actor, chatID, userID string) error {
+	return retryStale(func() error {
+		chat, err := s.manageableGroup(ctx, actor, chatID)
+		if err != nil {
+			return err
+		}
+		req, err := s.invites.GetJoinRequest(ctx, chatID, userID)
+		if err != nil {
+			return err
+		}
+		if chat.IsMember(userID) {
+			return s.invites.DeleteJoinRequest(ctx, chatID, userID)
+		}
+		inv, err := s.invites.GetInvite(ctx, req.InviteToken)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrExpired
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		if !inv.Usable(now) {
+			return ErrExpired
+		}
+		return s.outbox.Tx(ctx, func(ctx context.Context) error {
+			if err := s.useInvite(ctx, req.InviteToken, now); err != nil {
+				return err
+			}
+			if err := s.invites.DeleteJoinRequest(ctx, chatID, userID); err != nil {
+				return err
+			}
+			return s.addMember(ctx, actor, chat, userID)
+		})
+	})
+}
+
+func (s *ChatService) RejectJoinRequest(ctx context.Context, actor, chatID, userID string) error {
+	if _, err := s.manageableGroup(ctx, actor, chatID); err != nil {
+		return err
+	}
+	return s.invites.DeleteJoinRequest(ctx, chatID, userID)
+}
+
+func newInviteToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
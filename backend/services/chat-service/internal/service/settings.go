@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fathima-sithara/message-service/internal/events"
+	"github.com/fathima-sithara/message-service/internal/models"
+)
+
+// SettingsPatch changes some of a member's settings; nil fields are left alone.
+// A zero MutedUntil unmutes.
+type SettingsPatch struct {
+	MutedUntil  *time.Time
+	Pinned      *bool
+	PinOrder    *int
+	Archived    *bool
+	NotifyLevel *models.NotifyLevel
+}
+
+// GetSettings returns userID's settings for a chat they are in.
+func (s *ChatService) GetSettings(ctx context.Context, userID, chatID string) (models.MemberSettings, error) {
+	chat, err := s.GetChatForUser(ctx, userID, chatID)
+	if err != nil {
+		return models.MemberSettings{}, err
+	}
+	return chat.SettingsFor(userID), nil
+}
+
+// UpdateSettings applies p to userID's settings for a chat and publishes the
+// result so notification-service can honour mutes and notification levels.
+func (s *ChatService) UpdateSettings(ctx context.Context, userID, chatID string, p SettingsPatch) (models.MemberSettings, error) {
+	chat, err := s.GetChatForUser(ctx, userID, chatID)
+	if err != nil {
+		return models.MemberSettings{}, err
+	}
+	now := time.Now().UTC()
+	st := chat.SettingsFor(userID)
+	if p.MutedUntil != nil {
+		st.MutedUntil = nil
+		if p.MutedUntil.After(now) {
+			until := p.MutedUntil.UTC()
+			st.MutedUntil = &until
+		}
+	}
+	if p.Pinned != nil {
+		st.Pinned = *p.Pinned
+	}
+	if p.PinOrder != nil {
+		if *p.PinOrder < 0 {
+			return st, fmt.Errorf("%w: pin_order must not be negative", ErrInvalid)
+		}
+		st.PinOrder = *p.PinOrder
+	}
+	if !st.Pinned {
+		st.PinOrder = 0
+	}
+	if p.Archived != nil {
+		st.Archived = *p.Archived
+	}
+	if p.NotifyLevel != nil {
+		if !p.NotifyLevel.Valid() {
+			return st, fmt.Errorf("%w: notification_level must be all, mentions or none", ErrInvalid)
+		}
+		st.NotifyLevel = *p.NotifyLevel
+	}
+	st.UpdatedAt = now
+
+	err = s.outbox.Tx(ctx, func(ctx context.Context) error {
+		if err := s.repo.SetMemberSettings(ctx, chatID, userID, &st); err != nil {
+			return err
+		}
+		return s.outbox.Add(ctx, events.SubjectChatSettingsUpdated, events.SettingsEvent{
+			ChatID:      chatID,
+			UserID:      userID,
+			Version:     st.Version,
+			MutedUntil:  st.MutedUntil,
+			NotifyLevel: string(st.NotifyLevel),
+			Pinned:      st.Pinned,
+			PinOrder:    st.PinOrder,
+			Archived:    st.Archived,
+			At:          now,
+		})
+	})
+	return st, err
+}
@@ -1,18 +1,29 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"time"
 
+	"github.com/fathima-sithara/message-service/internal/models"
+	"github.com/fathima-sithara/message-service/internal/pb/messagev1"
+	"github.com/fathima-sithara/message-service/internal/service"
 	"github.com/gofiber/websocket/v2"
 )
 
+// storeTimeout bounds the message-service write for one incoming frame.
+const storeTimeout = 5 * time.Second
+
 type Connection struct {
 	ws   *websocket.Conn
-	send chan interface{}
+	send chan interface{} // frames for writePump; only the hub writes to or closes it
 	chat string
 	uid  string
 	hub  *Hub
+	svc  *service.ChatService
+	msgs messagev1.MessageServiceClient
 }
 
 func (c *Connection) readPump() {
@@ -32,18 +43,65 @@ func (c *Connection) readPump() {
 		if err != nil {
 			return
 		}
-		var ev map[string]interface{}
-		if err := json.Unmarshal(data, &ev); err != nil {
-			continue
-		}
-		msg := map[string]interface{}{
-			"type": "message",
-			"from": c.uid,
-			"data": ev,
-			"time": time.Now().Unix(),
+		c.handle(data)
+	}
+}
+
+// handle stores and broadcasts one frame from the client. Frames may carry only
+// the message types users send; system messages are message-service's own.
+func (c *Connection) handle(data []byte) {
+	var ev map[string]interface{}
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return
+	}
+	raw, _ := ev["msg_type"].(string)
+	msgType, ok := models.UserMsgType(raw)
+	if !ok {
+		c.hub.Send(c.chat, c, map[string]interface{}{"type": "error", "error": "unsupported msg_type"})
+		return
+	}
+	msg := map[string]interface{}{
+		"type": "message",
+		"from": c.uid,
+		"data": ev,
+		"time": time.Now().Unix(),
+	}
+	if content, _ := ev["content"].(string); content != "" && c.msgs != nil {
+		stored, err := c.store(content, msgType)
+		if err != nil {
+			// only stored messages are broadcast, so history and live views agree
+			reason := "message not stored"
+			if errors.Is(err, service.ErrForbidden) {
+				reason = "only admins can send messages"
+			} else {
+				log.Printf("ws: store message chat=%s user=%s: %v", c.chat, c.uid, err)
+			}
+			c.hub.Send(c.chat, c, map[string]interface{}{"type": "error", "error": reason})
+			return
 		}
-		c.hub.Broadcast(c.chat, msg)
+		msg["id"] = stored.GetId()
+		msg["time"] = stored.GetCreatedAt().AsTime().Unix()
+	}
+	c.hub.Broadcast(c.chat, msg)
+}
+
+func (c *Connection) store(content, msgType string) (*messagev1.Message, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	// posting rights can change while the connection is open
+	if err := c.svc.CheckCanPost(ctx, c.chat, c.uid); err != nil {
+		return nil, err
+	}
+	resp, err := c.msgs.CreateMessage(ctx, &messagev1.CreateMessageRequest{
+		ChatId:   c.chat,
+		SenderId: c.uid,
+		Content:  content,
+		MsgType:  msgType,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return resp.GetMessage(), nil
 }
 
 func (c *Connection) writePump() {
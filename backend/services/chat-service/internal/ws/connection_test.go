@@ -0,0 +1,96 @@
+package ws
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fathima-sithara/message-service/internal/pb/messagev1"
+	"github.com/fathima-sithara/message-service/internal/repository"
+	"github.com/fathima-sithara/message-service/internal/service"
+	"github.com/fathima-sithara/platform/outbox"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeMessages stores nothing and records what it was asked to create.
+type fakeMessages struct {
+	messagev1.MessageServiceClient
+	created []*messagev1.CreateMessageRequest
+}
+
+func (f *fakeMessages) CreateMessage(_ context.Context, in *messagev1.CreateMessageRequest, _ ...grpc.CallOption) (*messagev1.CreateMessageResponse, error) {
+	f.created = append(f.created, in)
+	return &messagev1.CreateMessageResponse{Message: &messagev1.Message{Id: "m1", CreatedAt: timestamppb.New(time.Unix(100, 0))}}, nil
+}
+
+func TestConnectionHandle(t *testing.T) {
+	group := func(onlyAdmins bool) bson.D {
+		return bson.D{
+			{Key: "_id", Value: "c1"},
+			{Key: "is_group", Value: true},
+			{Key: "members", Value: bson.A{"owner", "member"}},
+			{Key: "roles", Value: bson.D{{Key: "owner", Value: "owner"}}},
+			{Key: "only_admins_post", Value: onlyAdmins},
+		}
+	}
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	tests := []struct {
+		name  string
+		uid   string
+		frame string
+		// chat is what the posting check reads; nil when no check is expected
+		chat      bson.D
+		wantType  string // msg_type stored; empty when nothing is
+		wantFrame string // type of the frame sent back
+		wantError string
+	}{
+		{name: "text", uid: "member", frame: `{"content":"hi","msg_type":"text"}`, chat: group(false), wantType: "text", wantFrame: "message"},
+		{name: "no type is text", uid: "member", frame: `{"content":"hi"}`, chat: group(false), wantType: "text", wantFrame: "message"},
+		{name: "image", uid: "member", frame: `{"content":"k","msg_type":"image"}`, chat: group(false), wantType: "image", wantFrame: "message"},
+		{name: "forged system message", uid: "owner", frame: `{"content":"hi","msg_type":"system"}`, wantFrame: "error", wantError: "unsupported msg_type"},
+		{name: "forged system frame without content", uid: "member", frame: `{"typing":true,"msg_type":"system"}`, wantFrame: "error", wantError: "unsupported msg_type"},
+		{name: "unknown type", uid: "member", frame: `{"content":"hi","msg_type":"sticker"}`, wantFrame: "error", wantError: "unsupported msg_type"},
+		{name: "member where only admins post", uid: "member", frame: `{"content":"hi"}`, chat: group(true), wantFrame: "error", wantError: "only admins can send messages"},
+		{name: "owner where only admins post", uid: "owner", frame: `{"content":"hi"}`, chat: group(true), wantType: "text", wantFrame: "message"},
+		{name: "typing", uid: "member", frame: `{"typing":true}`, wantFrame: "message"},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			svc := service.NewChatService(repository.NewMongoRepository(mt.Coll), repository.NewInviteRepository(mt.DB),
+				repository.NewSubscriberRepository(mt.DB), outbox.NewStore(mt.DB, time.Hour), nil)
+			mt.ClearEvents()
+			if tt.chat != nil {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, "test."+mt.Coll.Name(), mtest.FirstBatch, tt.chat))
+			}
+			msgs := &fakeMessages{}
+			h := NewHub()
+			c := &Connection{send: make(chan interface{}, 1), chat: "c1", uid: tt.uid, hub: h, svc: svc, msgs: msgs}
+			h.Register("c1", c)
+
+			c.handle([]byte(tt.frame))
+
+			if tt.chat == nil && mt.GetStartedEvent() != nil {
+				mt.Fatalf("posting rights checked for a frame that is not stored")
+			}
+			if tt.wantType == "" && len(msgs.created) != 0 {
+				mt.Fatalf("stored %v, want nothing", msgs.created)
+			}
+			if tt.wantType != "" && (len(msgs.created) != 1 || msgs.created[0].GetMsgType() != tt.wantType) {
+				mt.Fatalf("stored %v, want one %s message", msgs.created, tt.wantType)
+			}
+			var frame map[string]interface{}
+			select {
+			case f := <-c.send:
+				frame = f.(map[string]interface{})
+			default:
+				mt.Fatalf("no frame sent back")
+			}
+			if frame["type"] != tt.wantFrame || (tt.wantError != "" && frame["error"] != tt.wantError) {
+				mt.Fatalf("frame = %v, want a %s frame %q", frame, tt.wantFrame, tt.wantError)
+			}
+		})
+	}
+}
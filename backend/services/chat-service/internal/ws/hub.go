@@ -5,6 +5,14 @@ import (
 	"time"
 )
 
+// sendTimeout is how long a broadcast waits on a connection whose queue is full
+// before dropping it.
+const sendTimeout = 200 * time.Millisecond
+
+// Hub keeps the connections of each chat room. A connection's send queue is only
+// written to or closed with mu held and while the connection is in its room, and
+// it leaves the room when the queue is closed, so it is never closed twice or
+// written to after closing.
 type Hub struct {
 	rooms map[string]map[*Connection]bool
 	mu    sync.RWMutex
@@ -32,19 +40,56 @@ func (h *Hub) Unregister(chatID string, c *Connection) {
 	}
 }
 
+// Broadcast queues msg for every connection in chatID. Connections that do not
+// make room within sendTimeout are evicted.
 func (h *Hub) Broadcast(chatID string, msg interface{}) {
+	var slow map[*Connection]bool
 	h.mu.RLock()
-	conns := h.rooms[chatID]
+	for c := range h.rooms[chatID] {
+		select {
+		case c.send <- msg:
+		case <-time.After(sendTimeout):
+			if slow == nil {
+				slow = make(map[*Connection]bool)
+			}
+			slow[c] = true
+		}
+	}
 	h.mu.RUnlock()
-	if conns == nil {
-		return
+	if slow != nil {
+		h.Evict(chatID, func(c *Connection) bool { return slow[c] })
+	}
+}
+
+// Send queues msg for c alone if it is still in chatID and has room, and reports
+// whether it did.
+func (h *Hub) Send(chatID string, c *Connection, msg interface{}) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if !h.rooms[chatID][c] {
+		return false
 	}
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// Evict drops the connections in chatID that match and closes them once their
+// queued frames are written.
+func (h *Hub) Evict(chatID string, match func(*Connection) bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	conns := h.rooms[chatID]
 	for c := range conns {
-		select {
-		case c.send <- msg:
-		case <-time.After(200 * time.Millisecond):
-			h.Unregister(chatID, c)
+		if match(c) {
+			delete(conns, c)
 			close(c.send)
 		}
 	}
+	if len(conns) == 0 {
+		delete(h.rooms, chatID)
+	}
 }
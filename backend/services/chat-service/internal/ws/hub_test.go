@@ -0,0 +1,150 @@
+package ws
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fathima-sithara/message-service/internal/events"
+)
+
+func newConn(uid string, buf int) *Connection {
+	return &Connection{uid: uid, send: make(chan interface{}, buf)}
+}
+
+// drain reads c's queue until the hub closes it and reports the frames seen.
+func drain(c *Connection) <-chan []interface{} {
+	out := make(chan []interface{}, 1)
+	go func() {
+		var got []interface{}
+		for msg := range c.send {
+			got = append(got, msg)
+		}
+		out <- got
+	}()
+	return out
+}
+
+func TestHubEvictDuringBroadcast(t *testing.T) {
+	h := NewHub()
+	var conns []*Connection
+	var done []<-chan []interface{}
+	for i := 0; i < 20; i++ {
+		c := newConn("u", 1)
+		h.Register("c1", c)
+		conns = append(conns, c)
+		done = append(done, drain(c))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				h.Broadcast("c1", j)
+				h.Send("c1", conns[j%len(conns)], "direct")
+			}
+		}()
+	}
+	for _, c := range conns {
+		c := c
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.Evict("c1", func(x *Connection) bool { return x == c })
+			// a second eviction finds nothing left to close
+			h.Evict("c1", func(x *Connection) bool { return x == c })
+		}()
+	}
+	wg.Wait()
+
+	for i, d := range done {
+		select {
+		case <-d:
+		case <-time.After(time.Second):
+			t.Fatalf("connection %d was never closed", i)
+		}
+	}
+	if len(h.rooms) != 0 {
+		t.Fatalf("rooms = %v, want none once every connection is evicted", h.rooms)
+	}
+}
+
+func TestHubDropsSlowConnections(t *testing.T) {
+	h := NewHub()
+	slow, fast := newConn("slow", 0), newConn("fast", 1)
+	h.Register("c1", slow)
+	h.Register("c1", fast)
+
+	h.Broadcast("c1", "hello")
+
+	if got := <-fast.send; got != "hello" {
+		t.Fatalf("fast connection got %v, want hello", got)
+	}
+	if _, open := <-slow.send; open {
+		t.Fatal("slow connection's queue is still open")
+	}
+	if h.rooms["c1"][slow] || !h.rooms["c1"][fast] {
+		t.Fatalf("room = %v, want only the fast connection", h.rooms["c1"])
+	}
+	if h.Send("c1", slow, "again") {
+		t.Fatal("Send queued a frame for an evicted connection")
+	}
+}
+
+func TestApplyChatEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		userID  string
+		// wantOpen lists which of the owner's and member's connections stay
+		wantOpen  map[string]bool
+		wantFrame bool
+	}{
+		{name: "update", subject: events.SubjectChatUpdated, wantOpen: map[string]bool{"owner": true, "member": true}, wantFrame: true},
+		{name: "member removed", subject: events.SubjectChatMemberRemoved, userID: "member", wantOpen: map[string]bool{"owner": true, "member": false}, wantFrame: true},
+		{name: "chat deleted", subject: events.SubjectChatDeleted, wantOpen: map[string]bool{"owner": false, "member": false}, wantFrame: true},
+		{name: "created", subject: events.SubjectChatCreated, wantOpen: map[string]bool{"owner": true, "member": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{hub: NewHub()}
+			conns := map[string]*Connection{"owner": newConn("owner", 4), "member": newConn("member", 4)}
+			for _, c := range conns {
+				s.hub.Register("c1", c)
+			}
+
+			s.ApplyChatEvent(context.Background(), tt.subject, events.ChatEvent{ChatID: "c1", UserID: tt.userID, Version: 7})
+
+			for uid, c := range conns {
+				var frames []interface{}
+				open := true
+			read:
+				for {
+					select {
+					case msg, ok := <-c.send:
+						if !ok {
+							open = false
+							break read
+						}
+						frames = append(frames, msg)
+					default:
+						break read
+					}
+				}
+				if open != tt.wantOpen[uid] {
+					t.Errorf("%s connection open = %v, want %v", uid, open, tt.wantOpen[uid])
+				}
+				// evicted members still get the frame telling them why
+				if tt.wantFrame != (len(frames) == 1) {
+					t.Fatalf("%s got frames %v, want one: %v", uid, frames, tt.wantFrame)
+				}
+				if tt.wantFrame && frames[0].(map[string]interface{})["type"] != tt.subject {
+					t.Errorf("%s frame = %v, want type %s", uid, frames[0], tt.subject)
+				}
+			}
+		})
+	}
+}
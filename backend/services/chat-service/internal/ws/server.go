@@ -1,18 +1,26 @@
 package ws
 
 import (
+	"context"
+
 	"github.com/fathima-sithara/message-service/internal/auth"
+	"github.com/fathima-sithara/message-service/internal/events"
+	"github.com/fathima-sithara/message-service/internal/pb/messagev1"
+	"github.com/fathima-sithara/message-service/internal/service"
 	"github.com/gofiber/websocket/v2"
 )
 
 type Server struct {
-	hub *Hub
-	svc interface{}
-	jv  *auth.JWTValidator
+	hub  *Hub
+	svc  *service.ChatService
+	jv   *auth.JWTValidator
+	msgs messagev1.MessageServiceClient
 }
 
-func NewServer(svc interface{}, jv *auth.JWTValidator) *Server {
-	return &Server{hub: NewHub(), svc: svc, jv: jv}
+// NewServer builds the websocket endpoint. When msgs is set, chat messages are
+// stored through message-service before they are broadcast.
+func NewServer(svc *service.ChatService, jv *auth.JWTValidator, msgs messagev1.MessageServiceClient) *Server {
+	return &Server{hub: NewHub(), svc: svc, jv: jv, msgs: msgs}
 }
 
 func (s *Server) HandleWS() func(*websocket.Conn) {
@@ -32,9 +40,44 @@ func (s *Server) HandleWS() func(*websocket.Conn) {
 			_ = conn.Close()
 			return
 		}
-		c := &Connection{ws: conn, send: make(chan interface{}, 256), chat: chatID, uid: uid, hub: s.hub}
+		// only members may join a chat room; their frames become stored messages
+		chat, err := s.svc.GetChat(context.Background(), chatID)
+		if err != nil || !chat.IsMember(uid) {
+			_ = conn.Close()
+			return
+		}
+		c := &Connection{ws: conn, send: make(chan interface{}, 256), chat: chatID, uid: uid, hub: s.hub, svc: s.svc, msgs: s.msgs}
 		s.hub.Register(chatID, c)
 		go c.writePump()
 		c.readPump()
 	}
 }
+
+// ApplyChatEvent keeps the rooms in step with chat lifecycle events: members see
+// the change as a frame, removed members are disconnected and a deleted chat's
+// room is closed.
+func (s *Server) ApplyChatEvent(_ context.Context, subject string, ev events.ChatEvent) {
+	if subject == events.SubjectChatCreated {
+		return
+	}
+	s.hub.Broadcast(ev.ChatID, map[string]interface{}{
+		"type":                  subject,
+		"chat_id":               ev.ChatID,
+		"version":               ev.Version,
+		"name":                  ev.Name,
+		"description":           ev.Description,
+		"avatar_url":            ev.AvatarURL,
+		"only_admins_post":      ev.OnlyAdminsPost,
+		"only_admins_edit_info": ev.OnlyAdminsEditInfo,
+		"members":               ev.Members,
+		"roles":                 ev.Roles,
+		"user_id":               ev.UserID,
+		"time":                  ev.At.Unix(),
+	})
+	switch subject {
+	case events.SubjectChatMemberRemoved:
+		s.hub.Evict(ev.ChatID, func(c *Connection) bool { return c.uid == ev.UserID })
+	case events.SubjectChatDeleted:
+		s.hub.Evict(ev.ChatID, func(*Connection) bool { return true })
+	}
+}
@@ -3,21 +3,29 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/fathima-sithara/message-service/internal/api"
-	"github.com/fathima-sithara/message-service/internal/auth"
 	"github.com/fathima-sithara/message-service/internal/config"
 	"github.com/fathima-sithara/message-service/internal/events"
+	"github.com/fathima-sithara/message-service/internal/pb/chatv1"
+	"github.com/fathima-sithara/message-service/internal/pb/messagev1"
 	"github.com/fathima-sithara/message-service/internal/repository"
+	rpcsrv "github.com/fathima-sithara/message-service/internal/rpc"
 	"github.com/fathima-sithara/message-service/internal/service"
+	"github.com/fathima-sithara/platform/health"
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/outbox"
+	"github.com/fathima-sithara/platform/rpc"
 
 	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -26,26 +34,23 @@ func main() {
 		log.Fatal("config:", err)
 	}
 
+	shutdownTracing, err := observability.InitTracing(context.Background(), "message-service")
+	if err != nil {
+		log.Fatal("tracing:", err)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.Mongo.URI))
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.Mongo.URI).SetMonitor(observability.MongoMonitor()))
 	if err != nil {
 		log.Fatal("mongo connect:", err)
 	}
 	db := client.Database(cfg.Mongo.DB)
 	repo := repository.NewMongoRepository(db)
+	ob := outbox.NewStore(db, cfg.Outbox.Retention)
 
 	rdb := redis.NewClient(&redis.Options{Addr: cfg.Redis.Addr, DB: cfg.Redis.DB})
-
-	var jv *auth.JWTValidator
-	if cfg.JWT.Alg == "RS256" {
-		jv, err = auth.NewJWTValidatorRS256(cfg.JWT.PublicKeyPath)
-	} else {
-		jv, err = auth.NewJWTValidatorHS256(cfg.JWT.HSSecret)
-	}
-	if err != nil {
-		log.Fatal("jwt:", err)
-	}
+	observability.InstrumentRedis(rdb)
 
 	pub, err := events.NewPublisher(cfg.NATS.URL)
 	if err != nil {
@@ -53,20 +58,55 @@ func main() {
 		pub = nil
 	}
 
-	sub, err := events.NewSubscriber(cfg.NATS.URL, repo)
+	sub, err := events.NewSubscriber(cfg.NATS.URL, repo, ob)
 	if err != nil {
 		log.Println("nats subscriber warn:", err)
 	} else {
 		go sub.Start("message-service")
 	}
 
-	msgSvc := service.NewMessageService(repo, rdb)
-	app := api.NewServer(cfg, msgSvc, jv, pub)
+	var chats chatv1.ChatServiceClient
+	if cfg.GRPC.ChatAddr != "" {
+		conn, err := rpc.Dial(cfg.GRPC.ChatAddr)
+		if err != nil {
+			log.Fatal("chat-service grpc:", err)
+		}
+		defer conn.Close()
+		chats = chatv1.NewChatServiceClient(conn)
+	}
 
-	errs := make(chan error, 1)
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	if pub != nil {
+		go outbox.NewRelay(ob, pub, cfg.Outbox.PollInterval).Run(relayCtx)
+	}
+
+	msgSvc := service.NewMessageService(repo, rdb, chats, ob)
+	app := api.NewServer(cfg, msgSvc)
+
+	hc := health.New()
+	hc.Add("mongo", func(ctx context.Context) error { return client.Ping(ctx, nil) })
+	hc.Add("redis", func(ctx context.Context) error { return rdb.Ping(ctx).Err() })
+	hc.Add("nats", pub.Ready)
+	hc.Add("nats_subscriber", sub.Ready)
+	hc.Register(app)
+
+	errs := make(chan error, 2)
 	go func() { errs <- app.Listen(":" + cfg.App.PortString()) }()
 	log.Printf("message-service started on :%s", cfg.App.PortString())
 
+	var grpcSrv *grpc.Server
+	if cfg.GRPC.Port != 0 {
+		lis, err := net.Listen("tcp", ":"+cfg.GRPC.PortString())
+		if err != nil {
+			log.Fatal("grpc listen:", err)
+		}
+		grpcSrv = rpc.NewServer()
+		messagev1.RegisterMessageServiceServer(grpcSrv, rpcsrv.NewMessageServer(msgSvc))
+		go func() { errs <- grpcSrv.Serve(lis) }()
+		log.Printf("grpc on :%s", cfg.GRPC.PortString())
+	}
+
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 	select {
@@ -81,7 +121,14 @@ func main() {
 	if err := app.Shutdown(); err != nil {
 		log.Println("fiber shutdown:", err)
 	}
+	if grpcSrv != nil {
+		grpcSrv.GracefulStop()
+	}
+	stopRelay()
 	_ = client.Disconnect(shutdownCtx)
 	_ = rdb.Close()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Println("tracing shutdown:", err)
+	}
 	log.Println("shutdown complete")
 }
@@ -0,0 +1,53 @@
+// Command replay-events publishes stored outbox events again, oldest first, so
+// consumers can rebuild state they lost or derived wrongly. Consumers apply events
+// idempotently, so replaying ones they already have is harmless. Only events
+// still inside OUTBOX_RETENTION can be replayed.
+//
+//	replay-events -since 24h -subject message.created -apply
+//
+// Without -apply it only counts what it would send.
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/fathima-sithara/message-service/internal/config"
+	"github.com/fathima-sithara/message-service/internal/events"
+	"github.com/fathima-sithara/platform/outbox"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	opts, err := outbox.ParseReplayFlags()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("config:", err)
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.Mongo.URI))
+	if err != nil {
+		log.Fatal("mongo connect:", err)
+	}
+	defer client.Disconnect(ctx)
+	store := outbox.NewStore(client.Database(cfg.Mongo.DB), cfg.Outbox.Retention)
+
+	var sender outbox.Sender
+	if opts.Apply {
+		pub, err := events.NewPublisher(cfg.NATS.URL)
+		if err != nil {
+			log.Fatal("nats:", err)
+		}
+		sender = pub
+	}
+	if err := outbox.Replay(ctx, store, sender, opts); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,14 @@
+package api
+
+import "github.com/gofiber/fiber/v2"
+
+// headerCachePurge is read by the api-gateway response cache, which evicts every
+// cached response carrying one of the listed tags.
+const headerCachePurge = "X-Cache-Purge"
+
+// purgeChat evicts cached chat-service responses that include the chat, whose last
+// message and unread counts a send or read changes. chat-service applies the
+// change from the event shortly after and purges the chat again then.
+func purgeChat(c *fiber.Ctx, chatID string) {
+	c.Set(headerCachePurge, "chat:"+chatID)
+}
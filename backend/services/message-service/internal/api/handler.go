@@ -2,20 +2,28 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
-	"github.com/fathima-sithara/message-service/internal/events"
+	"github.com/fathima-sithara/message-service/internal/repository"
 	"github.com/fathima-sithara/message-service/internal/service"
+	"github.com/fathima-sithara/platform/rpc"
 	"github.com/gofiber/fiber/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// maxLastMessages caps the chats one last-messages call may ask for.
+const maxLastMessages = 100
+
 type Handlers struct {
 	svc *service.MessageService
-	pub *events.Publisher
 }
 
-func NewHandlers(svc *service.MessageService, pub *events.Publisher) *Handlers {
-	return &Handlers{svc: svc, pub: pub}
+func NewHandlers(svc *service.MessageService) *Handlers {
+	return &Handlers{svc: svc}
 }
 
 func (h *Handlers) sendMessage(c *fiber.Ctx) error {
@@ -23,40 +31,89 @@ func (h *Handlers) sendMessage(c *fiber.Ctx) error {
 		ChatID  string `json:"chat_id"`
 		Content string `json:"content"`
 		MsgType string `json:"msg_type"`
+		ReplyTo string `json:"reply_to"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
 	}
+	if req.ChatID == "" || req.Content == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "chat_id and content are required"})
+	}
 	user := c.Locals("user_id").(string)
-	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c.UserContext(), 5*time.Second)
 	defer cancel()
-	msg, err := h.svc.SendMessage(ctx, req.ChatID, user, req.Content, req.MsgType)
+	if err := h.svc.CheckMember(ctx, req.ChatID, user); err != nil {
+		return membershipFailed(c, err)
+	}
+	msg, err := h.svc.SendMessage(ctx, req.ChatID, user, req.Content, req.MsgType, req.ReplyTo)
+	if errors.Is(err, service.ErrInvalidReply) || errors.Is(err, service.ErrBadMsgType) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if _, isStatus := status.FromError(err); err != nil && isStatus {
+		return membershipFailed(c, err)
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	if h.pub != nil {
-		h.pub.PublishMessageCreated(req.ChatID, msg)
-	}
+	purgeChat(c, req.ChatID)
 	return c.Status(201).JSON(fiber.Map{"status": "ok", "data": msg})
 }
 
+// membershipFailed answers for a failed CheckMember or CheckReader.
+func membershipFailed(c *fiber.Ctx, err error) error {
+	if errors.Is(err, service.ErrNotMember) || errors.Is(err, service.ErrAdminsOnly) {
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	}
+	if status.Code(err) == codes.NotFound {
+		return c.Status(404).JSON(fiber.Map{"error": "chat not found"})
+	}
+	return c.Status(rpc.HTTPStatus(err)).JSON(fiber.Map{"error": "membership check failed"})
+}
+
 func (h *Handlers) listMessages(c *fiber.Ctx) error {
 	chatID := c.Params("chat_id")
 	limit := int64(50)
-	msgs, err := h.svc.ListMessages(c.Context(), chatID, limit, time.Now())
+	msgs, err := h.svc.ListMessages(c.UserContext(), chatID, limit, time.Now())
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 	return c.JSON(fiber.Map{"status": "ok", "data": msgs})
 }
 
+// getThread returns a thread's root message and a page of its replies, oldest
+// first. Query: limit and cursor (next_cursor of the previous page).
+func (h *Handlers) getThread(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 100 {
+		return c.Status(400).JSON(fiber.Map{"error": "limit must be between 1 and 100"})
+	}
+	user := c.Locals("user_id").(string)
+	ctx, cancel := context.WithTimeout(c.UserContext(), 5*time.Second)
+	defer cancel()
+	root, replies, next, err := h.svc.GetThread(ctx, user, c.Params("msg_id"), c.Query("cursor"), int64(limit))
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "thread not found"})
+	}
+	if errors.Is(err, service.ErrBadCursor) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if _, isStatus := status.FromError(err); err != nil && (isStatus || errors.Is(err, service.ErrNotMember)) {
+		return membershipFailed(c, err)
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"status": "ok", "data": fiber.Map{"root": root, "replies": replies}, "next_cursor": next})
+}
+
 func (h *Handlers) markRead(c *fiber.Ctx) error {
 	msgID := c.Params("msg_id")
 	user := c.Locals("user_id").(string)
-	chatID, err := h.svc.MarkRead(c.Context(), msgID, user)
+	chatID, err := h.svc.MarkRead(c.UserContext(), msgID, user)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	purgeChat(c, chatID)
 	return c.JSON(fiber.Map{"status": "ok", "chat_id": chatID})
 }
 
@@ -65,11 +122,11 @@ func (h *Handlers) editMessage(c *fiber.Ctx) error {
 	var body struct {
 		Content string `json:"content"`
 	}
-	if err := c.BodyParser(&body); err != nil {
+	if err := c.BodyParser(&body); err != nil || body.Content == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid"})
 	}
 	user := c.Locals("user_id").(string)
-	chatID, err := h.svc.EditMessage(c.Context(), msgID, user, body.Content)
+	chatID, err := h.svc.EditMessage(c.UserContext(), msgID, user, body.Content)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -81,13 +138,13 @@ func (h *Handlers) deleteMessage(c *fiber.Ctx) error {
 	user := c.Locals("user_id").(string)
 	delType := c.Query("type", "user")
 	if delType == "all" {
-		chatID, err := h.svc.DeleteMessageForAll(c.Context(), msgID, user)
+		chatID, err := h.svc.DeleteMessageForAll(c.UserContext(), msgID, user)
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.JSON(fiber.Map{"status": "ok", "chat_id": chatID})
 	}
-	chatID, err := h.svc.DeleteMessageForUser(c.Context(), msgID, user)
+	chatID, err := h.svc.DeleteMessageForUser(c.UserContext(), msgID, user)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -99,7 +156,7 @@ func (h *Handlers) mediaUploadURL(c *fiber.Ctx) error {
 		Filename    string `json:"filename"`
 		ContentType string `json:"content_type"`
 	}
-	if err := c.BodyParser(&body); err != nil {
+	if err := c.BodyParser(&body); err != nil || body.Filename == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid"})
 	}
 	uploadURL := "https://fake-s3.local/upload/" + body.Filename + "?signature=stub"
@@ -109,9 +166,33 @@ func (h *Handlers) mediaUploadURL(c *fiber.Ctx) error {
 
 func (h *Handlers) lastMessage(c *fiber.Ctx) error {
 	chatID := c.Params("chat_id")
-	m, err := h.svc.GetLastMessage(c.Context(), chatID)
+	m, err := h.svc.GetLastMessage(c.UserContext(), chatID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "no messages"})
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 	return c.JSON(fiber.Map{"status": "ok", "data": m})
 }
+
+// lastMessages serves GET /v1/last-messages?chat_ids=a,b: the newest message of
+// each listed chat, keyed by chat id, leaving out chats without messages and
+// chats the caller is not a member of.
+func (h *Handlers) lastMessages(c *fiber.Ctx) error {
+	ids := strings.Split(c.Query("chat_ids"), ",")
+	if c.Query("chat_ids") == "" || len(ids) > maxLastMessages {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("chat_ids must list 1 to %d chats", maxLastMessages)})
+	}
+	user := c.Locals("user_id").(string)
+	ctx, cancel := context.WithTimeout(c.UserContext(), 5*time.Second)
+	defer cancel()
+	msgs, err := h.svc.GetLastMessages(ctx, user, ids)
+	if _, isStatus := status.FromError(err); err != nil && isStatus {
+		return membershipFailed(c, err)
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"status": "ok", "data": msgs})
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fathima-sithara/message-service/internal/pb/chatv1"
+	"github.com/fathima-sithara/message-service/internal/service"
+	"github.com/gofiber/fiber/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeChats answers membership from a fixed table: chats missing from it are
+// unknown, and a chat mapped to an error fails the lookup with it. Chats in
+// adminsOnly let only the admin they map to post.
+type fakeChats struct {
+	chatv1.ChatServiceClient
+	members    map[string][]string
+	adminsOnly map[string]string
+	errs       map[string]error
+}
+
+func (f fakeChats) GetMembership(_ context.Context, in *chatv1.GetMembershipRequest, _ ...grpc.CallOption) (*chatv1.GetMembershipResponse, error) {
+	if err := f.errs[in.ChatId]; err != nil {
+		return nil, err
+	}
+	members, ok := f.members[in.ChatId]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "chat not found")
+	}
+	for _, m := range members {
+		if m != in.UserId {
+			continue
+		}
+		admin, restricted := f.adminsOnly[in.ChatId]
+		role := "member"
+		if restricted && admin == m {
+			role = "admin"
+		}
+		return &chatv1.GetMembershipResponse{Member: true, IsGroup: true, Role: role, OnlyAdminsPost: restricted}, nil
+	}
+	return &chatv1.GetMembershipResponse{}, nil
+}
+
+func TestLastMessagesNonMember(t *testing.T) {
+	chats := fakeChats{
+		members: map[string][]string{"c1": {"u2"}, "c2": {"u2", "u3"}},
+		errs:    map[string]error{"down": status.Error(codes.Unavailable, "chat-service down")},
+	}
+	// no repository: every case must be settled before messages are looked up
+	h := NewHandlers(service.NewMessageService(nil, nil, chats, nil))
+	app := fiber.New()
+	app.Get("/v1/last-messages", func(c *fiber.Ctx) error {
+		c.Locals("user_id", "u1")
+		return c.Next()
+	}, h.lastMessages)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{name: "chats of other users", query: "c1,c2", wantStatus: 200},
+		{name: "unknown chat", query: "nope", wantStatus: 200},
+		{name: "membership lookup fails", query: "c1,down", wantStatus: 503},
+		{name: "no chats", query: "", wantStatus: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", "/v1/last-messages?chat_ids="+tt.query, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != 200 {
+				return
+			}
+			var body struct {
+				Data map[string]json.RawMessage `json:"data"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Data) != 0 {
+				t.Fatalf("data = %v, want no messages for chats u1 is not in", body.Data)
+			}
+		})
+	}
+}
+
+func TestSendMessageRejected(t *testing.T) {
+	chats := fakeChats{
+		members:    map[string][]string{"open": {"u1"}, "announce": {"u1", "boss"}},
+		adminsOnly: map[string]string{"announce": "boss"},
+		errs:       map[string]error{"down": status.Error(codes.Unavailable, "chat-service down")},
+	}
+	// no repository: every case must be refused before anything is stored
+	h := NewHandlers(service.NewMessageService(nil, nil, chats, nil))
+	app := fiber.New()
+	var sender string
+	app.Post("/v1/messages", func(c *fiber.Ctx) error {
+		c.Locals("user_id", sender)
+		return c.Next()
+	}, h.sendMessage)
+
+	tests := []struct {
+		name       string
+		sender     string
+		body       string
+		wantStatus int
+	}{
+		{name: "forged system message", sender: "u1", body: `{"chat_id":"open","content":"hi","msg_type":"system"}`, wantStatus: 400},
+		{name: "unknown type", sender: "u1", body: `{"chat_id":"open","content":"hi","msg_type":"sticker"}`, wantStatus: 400},
+		{name: "system message from an admin", sender: "boss", body: `{"chat_id":"announce","content":"hi","msg_type":"system"}`, wantStatus: 400},
+		{name: "member where only admins post", sender: "u1", body: `{"chat_id":"announce","content":"hi"}`, wantStatus: 403},
+		{name: "outsider", sender: "u2", body: `{"chat_id":"open","content":"hi"}`, wantStatus: 403},
+		{name: "unknown chat", sender: "u1", body: `{"chat_id":"nope","content":"hi"}`, wantStatus: 404},
+		{name: "membership lookup fails", sender: "u1", body: `{"chat_id":"down","content":"hi"}`, wantStatus: 503},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender = tt.sender
+			req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -1,46 +1,38 @@
 package api
 
 import (
-	"github.com/fathima-sithara/message-service/internal/auth"
 	"github.com/fathima-sithara/message-service/internal/config"
-	"github.com/fathima-sithara/message-service/internal/events"
 	"github.com/fathima-sithara/message-service/internal/service"
+	"github.com/fathima-sithara/platform/identity"
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/reqctx"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 )
 
-func NewServer(cfg *config.Config, svc *service.MessageService, jv *auth.JWTValidator, pub *events.Publisher) *fiber.App {
+func NewServer(cfg *config.Config, svc *service.MessageService) *fiber.App {
 	app := fiber.New()
-	app.Use(logger.New())
-	h := NewHandlers(svc, pub)
+	app.Use(reqctx.Middleware())
+	app.Use(observability.Middleware())
+	app.Use(logger.New(logger.Config{
+		Format: "[${time}] ${status} - ${latency} ${method} ${path} request_id=${locals:request_id} trace_id=${locals:trace_id}\n",
+	}))
+	h := NewHandlers(svc)
+	app.Get("/metrics", observability.MetricsHandler())
 
 	api := app.Group("/v1")
 
-	api.Use(func(c *fiber.Ctx) error {
-		hdr := c.Get("Authorization")
-		if hdr == "" {
-			return c.Status(401).JSON(fiber.Map{"error": "missing auth"})
-		}
-		const pref = "Bearer "
-		if len(hdr) <= len(pref) || hdr[:len(pref)] != pref {
-			return c.Status(401).JSON(fiber.Map{"error": "invalid auth"})
-		}
-		token := hdr[len(pref):]
-		sub, err := jv.Validate(token)
-		if err != nil {
-			return c.Status(401).JSON(fiber.Map{"error": err.Error()})
-		}
-		c.Locals("user_id", sub)
-		return c.Next()
-	})
+	api.Use(identity.TrustedIdentity(cfg.Identity.Secret))
 
 	api.Post("/messages", h.sendMessage)
 	api.Get("/chats/:chat_id/messages", h.listMessages)
+	api.Get("/messages/:msg_id/thread", h.getThread)
 	api.Post("/messages/:msg_id/read", h.markRead)
 	api.Patch("/messages/:msg_id", h.editMessage)
 	api.Delete("/messages/:msg_id", h.deleteMessage)
 	api.Post("/media/upload-url", h.mediaUploadURL)
 	api.Get("/chats/:chat_id/last-message", h.lastMessage)
+	api.Get("/last-messages", h.lastMessages)
 
 	return app
 }
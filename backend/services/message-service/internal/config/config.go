@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
@@ -41,19 +42,36 @@ type Kafka struct {
 	TopicOut string   `yaml:"topic_out"`
 }
 
-type JWT struct {
-	Alg           string `yaml:"alg"`
-	PublicKeyPath string `yaml:"public_key_path"`
-	HSSecret      string `yaml:"hs_secret"`
+type Identity struct {
+	// Secret verifies identity headers signed by the api-gateway; required.
+	Secret string `yaml:"secret"`
+}
+
+// GRPC configures the internal gRPC API. Port 0 leaves the server off; an empty
+// ChatAddr skips the membership check on POST /v1/messages.
+type GRPC struct {
+	Port     int    `yaml:"port"`
+	ChatAddr string `yaml:"chat_addr"`
+}
+
+func (g *GRPC) PortString() string { return fmt.Sprintf("%d", g.Port) }
+
+// Outbox configures event delivery: how often the relay looks for unsent events
+// and how long sent ones are kept for replays.
+type Outbox struct {
+	PollInterval time.Duration `yaml:"poll_interval"`
+	Retention    time.Duration `yaml:"retention"`
 }
 
 type Config struct {
-	App   App   `yaml:"app"`
-	Mongo Mongo `yaml:"mongo"`
-	Redis Redis `yaml:"redis"`
-	NATS  NATS  `yaml:"nats"`
-	Kafka Kafka `yaml:"kafka"`
-	JWT   JWT   `yaml:"jwt"`
+	App      App      `yaml:"app"`
+	GRPC     GRPC     `yaml:"grpc"`
+	Mongo    Mongo    `yaml:"mongo"`
+	Redis    Redis    `yaml:"redis"`
+	NATS     NATS     `yaml:"nats"`
+	Kafka    Kafka    `yaml:"kafka"`
+	Identity Identity `yaml:"identity"`
+	Outbox   Outbox   `yaml:"outbox"`
 }
 
 func Load() (*Config, error) {
@@ -85,6 +103,14 @@ func overrideFromEnv(cfg *Config) {
 		cfg.App.Port = n
 	}
 
+	if v := os.Getenv("GRPC_PORT"); v != "" {
+		n, _ := strconv.Atoi(v)
+		cfg.GRPC.Port = n
+	}
+	if v := os.Getenv("CHAT_GRPC_ADDR"); v != "" {
+		cfg.GRPC.ChatAddr = v
+	}
+
 	if v := os.Getenv("MONGODB_URI"); v != "" {
 		cfg.Mongo.URI = v
 	}
@@ -103,14 +129,26 @@ func overrideFromEnv(cfg *Config) {
 		cfg.Kafka.Brokers = strings.Split(v, ",")
 	}
 
+	if v := os.Getenv("IDENTITY_HMAC_SECRET"); v != "" {
+		cfg.Identity.Secret = v
+	}
 
-	if v := os.Getenv("JWT_PUBLIC_KEY_PATH"); v != "" {
-		cfg.JWT.PublicKeyPath = v
+	if v := os.Getenv("OUTBOX_POLL_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.Outbox.PollInterval = d
+		}
 	}
-	if v := os.Getenv("JWT_SECRET"); v != "" {
-		cfg.JWT.HSSecret = v
+	if v := os.Getenv("OUTBOX_RETENTION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.Outbox.Retention = d
+		}
+	}
+	if cfg.Outbox.PollInterval <= 0 {
+		cfg.Outbox.PollInterval = time.Second
+	}
+	if cfg.Outbox.Retention <= 0 {
+		cfg.Outbox.Retention = 7 * 24 * time.Hour
 	}
-
 }
 
 func validate(cfg *Config) error {
@@ -140,17 +178,8 @@ func validate(cfg *Config) error {
 		return errors.New("kafka topics missing")
 	}
 
-	switch strings.ToUpper(cfg.JWT.Alg) {
-	case "RS256":
-		if cfg.JWT.PublicKeyPath == "" {
-			return errors.New("jwt.public_key_path required for RS256")
-		}
-	case "HS256":
-		if cfg.JWT.HSSecret == "" {
-			return errors.New("jwt.hs_secret required for HS256")
-		}
-	default:
-		return errors.New("invalid jwt.alg (use RS256 or HS256)")
+	if cfg.Identity.Secret == "" {
+		return errors.New("identity.secret missing (set IDENTITY_HMAC_SECRET)")
 	}
 
 	return nil
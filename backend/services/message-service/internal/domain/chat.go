@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// Chat is message-service's copy of a chat-service chat, kept current from the
+// chat lifecycle events. A deleted chat stays behind as a tombstone so older
+// events cannot bring it back.
+type Chat struct {
+	ID        string    `bson:"_id" json:"id"`
+	Name      string    `bson:"name,omitempty" json:"name,omitempty"`
+	IsGroup   bool      `bson:"is_group" json:"is_group"`
+	Members   []string  `bson:"members" json:"members"`
+	Version   int64     `bson:"version" json:"version"`
+	Deleted   bool      `bson:"deleted,omitempty" json:"deleted,omitempty"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
@@ -2,6 +2,22 @@ package domain
 
 import "time"
 
+// MsgTypeSystem marks a message recorded for a chat change, such as a rename.
+// Only message-service records them; users cannot send one.
+const MsgTypeSystem = "system"
+
+// userMsgTypes are the types users may send.
+var userMsgTypes = map[string]bool{"text": true, "image": true, "video": true, "audio": true, "file": true}
+
+// UserMsgType reports whether users may send messages of type t, which defaults
+// to text when empty, and returns the type to store.
+func UserMsgType(t string) (string, bool) {
+	if t == "" {
+		return "text", true
+	}
+	return t, userMsgTypes[t]
+}
+
 type Message struct {
 	ID         string              `bson:"_id" json:"id"`
 	ChatID     string              `bson:"chat_id" json:"chat_id"`
@@ -13,10 +29,15 @@ type Message struct {
 	MediaURL   string              `json:"media_url,omitempty"`
 	Thumbnail  string              `json:"thumbnail,omitempty"`
 	ReplyTo    string              `bson:"reply_to,omitempty" json:"reply_to,omitempty"`
+	ThreadID   string              `bson:"thread_id,omitempty" json:"thread_id,omitempty"` // root message of the thread a reply is in
 	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
 	EditedAt   *time.Time          `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
 	Delivered  bool                `bson:"delivered" json:"delivered"`
 	ReadBy     []string            `bson:"read_by" json:"read_by"`
 	DeletedFor []string            `bson:"deleted_for" json:"deleted_for"`
 	Reactions  map[string][]string `bson:"reactions,omitempty" json:"reactions,omitempty"`
+	// Thread state, kept on a thread's root message.
+	ReplyCount   int64      `bson:"reply_count,omitempty" json:"reply_count,omitempty"`
+	LastReplyAt  *time.Time `bson:"last_reply_at,omitempty" json:"last_reply_at,omitempty"`
+	Participants []string   `bson:"thread_participants,omitempty" json:"thread_participants,omitempty"` // root sender and repliers
 }
@@ -0,0 +1,23 @@
+package domain
+
+import "testing"
+
+func TestUserMsgType(t *testing.T) {
+	tests := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"", "text", true},
+		{"text", "text", true},
+		{"image", "image", true},
+		{"file", "file", true},
+		{MsgTypeSystem, MsgTypeSystem, false},
+		{"System", "System", false},
+		{"sticker", "sticker", false},
+	}
+	for _, tt := range tests {
+		if got, ok := UserMsgType(tt.in); got != tt.want || ok != tt.ok {
+			t.Errorf("UserMsgType(%q) = (%q, %v), want (%q, %v)", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
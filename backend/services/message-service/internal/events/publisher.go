@@ -1,31 +1,107 @@
 package events
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"log"
+	"time"
 
+	"github.com/fathima-sithara/message-service/internal/domain"
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/reqctx"
 	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Message subjects.
+const (
+	// SubjectMessageCreated carries a MessageCreatedEvent for every stored message.
+	SubjectMessageCreated = "message.created"
+	// SubjectMessageRead carries a MessageReadEvent when a member reads a message.
+	SubjectMessageRead = "message.read"
+	// SubjectThreadReply carries a ThreadReplyEvent for every reply in a thread,
+	// alongside its message.created.
+	SubjectThreadReply = "message.thread_reply"
+)
+
+// MessageCreatedEvent carries the message with its content readable.
+type MessageCreatedEvent struct {
+	ChatID  string          `json:"chat_id"`
+	Message *domain.Message `json:"message"`
+}
+
+// MessageReadEvent moves UserID's read position in ChatID to MessageID, sent at
+// MessageAt. Unread is how many messages from others remain after it.
+type MessageReadEvent struct {
+	ChatID    string    `json:"chat_id"`
+	MessageID string    `json:"message_id"`
+	UserID    string    `json:"user_id"`
+	MessageAt time.Time `json:"message_at"`
+	Unread    int64     `json:"unread"`
+	ReadAt    time.Time `json:"read_at"`
+}
+
+// ThreadReplyEvent lets the thread's participants be notified of a reply apart
+// from the chat's other members. Participants includes the sender.
+type ThreadReplyEvent struct {
+	ChatID       string          `json:"chat_id"`
+	ThreadID     string          `json:"thread_id"`
+	Message      *domain.Message `json:"message"`
+	ReplyCount   int64           `json:"reply_count"`
+	Participants []string        `json:"participants"`
+}
+
 type Publisher struct {
 	nc *nats.Conn
 }
 
 func NewPublisher(natsURL string) (*Publisher, error) {
-	nc, err := nats.Connect(natsURL)
+	// keep trying in the background when NATS is down at startup; the outbox
+	// relay holds events back until the connection is up
+	nc, err := nats.Connect(natsURL, nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1))
 	if err != nil {
 		return nil, err
 	}
 	return &Publisher{nc: nc}, nil
 }
 
-func (p *Publisher) PublishMessageCreated(chatID string, message interface{}) {
-	ev := struct {
-		ChatID  string      `json:"chat_id"`
-		Message interface{} `json:"message"`
-	}{ChatID: chatID, Message: message}
-	b, _ := json.Marshal(ev)
-	if err := p.nc.Publish("message.created", b); err != nil {
-		log.Println("publish message.created:", err)
+// Ready reports whether the NATS connection is up, for the readiness probe.
+func (p *Publisher) Ready(context.Context) error {
+	if p == nil || p.nc == nil || !p.nc.IsConnected() {
+		return errors.New("nats not connected")
+	}
+	return nil
+}
+
+// Send publishes an outbox record and waits for the server to acknowledge it, so
+// the relay never marks a message sent that is still sitting in a client buffer.
+func (p *Publisher) Send(ctx context.Context, subject string, data []byte) error {
+	if !p.nc.IsConnected() {
+		return errors.New("nats not connected")
+	}
+	if err := p.publish(ctx, subject, data); err != nil {
+		return err
+	}
+	return p.nc.FlushTimeout(5 * time.Second)
+}
+
+// publish sends data on subject inside a producer span, carrying the correlation
+// headers so consumers can continue the trace.
+func (p *Publisher) publish(ctx context.Context, subject string, data []byte) error {
+	ctx, span := observability.StartSpan(ctx, subject+" publish", trace.SpanKindProducer,
+		attribute.String("messaging.system", "nats"),
+		attribute.String("messaging.destination.name", subject),
+	)
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	reqctx.Inject(ctx, msg.Header)
+	err := p.nc.PublishMsg(msg)
+	observability.RecordPublish("nats", subject, err)
+	observability.EndSpan(span, err)
+	if err != nil {
+		info := reqctx.FromContext(ctx)
+		log.Printf("publish %s: %v request_id=%s trace_id=%s", subject, err, info.RequestID, info.TraceID())
 	}
+	return err
 }
@@ -2,48 +2,242 @@ package events
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
+	"github.com/fathima-sithara/message-service/internal/domain"
 	"github.com/fathima-sithara/message-service/internal/repository"
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/outbox"
+	"github.com/fathima-sithara/platform/reqctx"
 	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type ChatCreatedEvent struct {
-	ChatID  string   `json:"chat_id"`
-	Members []string `json:"members"`
-	Name    string   `json:"name"`
-	IsGroup bool     `json:"is_group"`
+// chatLifecycleSubjects are the chat-service events that keep the local chat
+// copies current.
+var chatLifecycleSubjects = []string{
+	"chat.created", "chat.member_added", "chat.member_removed", "chat.updated", "chat.role_changed", "chat.deleted",
+	"chat.renamed", // no longer published; older records may still be replayed
+}
+
+// ChatEvent carries a chat's state after a change and the version of that state.
+// Notices are recorded in the chat as system messages.
+type ChatEvent struct {
+	ChatID  string    `json:"chat_id"`
+	Version int64     `json:"version"`
+	Name    string    `json:"name"`
+	IsGroup bool      `json:"is_group"`
+	Members []string  `json:"members"`
+	UserID  string    `json:"user_id,omitempty"`
+	ActorID string    `json:"actor_id,omitempty"`
+	Notices []string  `json:"notices,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// ChatMergedEvent is a request from chat-service to fold the duplicate direct
+// chats MergedIDs into ChatID. The reply is "ok", or the error text.
+type ChatMergedEvent struct {
+	ChatID    string   `json:"chat_id"`
+	MergedIDs []string `json:"merged_ids"`
 }
 
 type Subscriber struct {
-	nc   *nats.Conn
-	repo *repository.MongoRepository
+	nc     *nats.Conn
+	repo   *repository.MongoRepository
+	outbox *outbox.Store
 }
 
-func NewSubscriber(natsURL string, repo *repository.MongoRepository) (*Subscriber, error) {
+// NewSubscriber consumes chat-service events; system messages it records are
+// published through ob like any other message.
+func NewSubscriber(natsURL string, repo *repository.MongoRepository, ob *outbox.Store) (*Subscriber, error) {
 	nc, err := nats.Connect(natsURL)
-	if err != nil { return nil, err }
-	return &Subscriber{nc: nc, repo: repo}, nil
+	if err != nil {
+		return nil, err
+	}
+	return &Subscriber{nc: nc, repo: repo, outbox: ob}, nil
+}
+
+// Ready reports whether the NATS connection is up, for the readiness probe.
+func (s *Subscriber) Ready(context.Context) error {
+	if s == nil || s.nc == nil || !s.nc.IsConnected() {
+		return errors.New("nats not connected")
+	}
+	return nil
 }
 
 func (s *Subscriber) Start(queue string) {
-	_, err := s.nc.QueueSubscribe("chat.created", queue, func(m *nats.Msg) {
-		var ev ChatCreatedEvent
-		if err := json.Unmarshal(m.Data, &ev); err != nil {
-			log.Println("invalid chat.created event:", err); return
-		}
-		for i := 0; i < 3; i++ {
-			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-			err := s.repo.InitChat(ctx, ev.ChatID, ev.Members, ev.IsGroup)
-			cancel()
-			if err == nil {
-				log.Println("chat initialized:", ev.ChatID); break
+	for _, subject := range chatLifecycleSubjects {
+		if _, err := s.nc.QueueSubscribe(subject, queue, s.consume(s.handleChatEvent)); err != nil {
+			log.Fatal("nats subscribe error:", err)
+		}
+	}
+	_, err := s.nc.QueueSubscribe("chat.merged", queue, s.consume(s.handleChatMerged))
+	if err != nil {
+		log.Fatal("nats subscribe error:", err)
+	}
+}
+
+// consume restores the producer's correlation ids, wraps handle in a consumer span
+// and records the outcome.
+func (s *Subscriber) consume(handle func(context.Context, *nats.Msg) error) nats.MsgHandler {
+	return func(m *nats.Msg) {
+		ctx := context.Background()
+		if m.Header != nil {
+			ctx = reqctx.Extract(ctx, m.Header)
+		}
+		ctx, span := observability.StartSpan(ctx, m.Subject+" process", trace.SpanKindConsumer,
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", m.Subject),
+		)
+		err := handle(ctx, m)
+		observability.RecordConsume("nats", m.Subject, err)
+		observability.EndSpan(span, err)
+	}
+}
+
+// handleChatEvent applies a lifecycle event to the local chat copy. Events may
+// arrive twice or out of order; ApplyChat ignores any that are not newer.
+func (s *Subscriber) handleChatEvent(base context.Context, m *nats.Msg) error {
+	info := reqctx.FromContext(base)
+	var ev ChatEvent
+	if err := json.Unmarshal(m.Data, &ev); err != nil || ev.ChatID == "" {
+		if err == nil {
+			err = errors.New("chat_id missing")
+		}
+		log.Printf("invalid %s event: %v request_id=%s trace_id=%s", m.Subject, err, info.RequestID, info.TraceID())
+		return err
+	}
+	deleted := m.Subject == "chat.deleted"
+	chat := &domain.Chat{
+		ID:        ev.ChatID,
+		Name:      ev.Name,
+		IsGroup:   ev.IsGroup,
+		Members:   ev.Members,
+		Version:   ev.Version,
+		Deleted:   deleted,
+		UpdatedAt: ev.At,
+	}
+	if deleted {
+		chat.Members = []string{}
+	}
+
+	var err error
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(base, 3*time.Second)
+		var applied bool
+		applied, err = s.repo.ApplyChat(ctx, chat)
+		cancel()
+		if err == nil {
+			if !applied {
+				log.Printf("skip stale %s chat=%s version=%d request_id=%s trace_id=%s", m.Subject, ev.ChatID, ev.Version, info.RequestID, info.TraceID())
 			}
-			log.Println("init chat retry err:", err)
-			time.Sleep(time.Duration(i+1) * 200 * time.Millisecond)
+			break
 		}
-	})
-	if err != nil { log.Fatal("nats subscribe error:", err) }
+		log.Printf("apply %s retry err: %v request_id=%s trace_id=%s", m.Subject, err, info.RequestID, info.TraceID())
+		time.Sleep(time.Duration(i+1) * 200 * time.Millisecond)
+	}
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return s.recordNotices(base, ev)
+	}
+
+	// run on every delivery, so a failed cleanup is retried by a replay
+	ctx, cancel := context.WithTimeout(base, 30*time.Second)
+	defer cancel()
+	n, err := s.repo.DeleteChatMessages(ctx, ev.ChatID)
+	if err != nil {
+		log.Printf("delete messages of chat %s: %v request_id=%s trace_id=%s", ev.ChatID, err, info.RequestID, info.TraceID())
+		return err
+	}
+	log.Printf("chat %s deleted, %d messages removed request_id=%s trace_id=%s", ev.ChatID, n, info.RequestID, info.TraceID())
+	return nil
+}
+
+// recordNotices stores ev's notices as system messages from the actor. Their ids
+// derive from the event, so a redelivery adds nothing, while an event that lost
+// out to a newer version still records what happened.
+func (s *Subscriber) recordNotices(base context.Context, ev ChatEvent) error {
+	for i, text := range ev.Notices {
+		m := &domain.Message{
+			ID:        fmt.Sprintf("sys-%s-%d-%d", ev.ChatID, ev.Version, i),
+			ChatID:    ev.ChatID,
+			SenderID:  ev.ActorID,
+			Content:   base64.StdEncoding.EncodeToString([]byte(text)), // stored encoded, like every message
+			MsgType:   domain.MsgTypeSystem,
+			CreatedAt: ev.At,
+		}
+		readable := *m
+		readable.Content = text
+		err := retry(base, func(ctx context.Context) error {
+			return s.outbox.Tx(ctx, func(ctx context.Context) error {
+				created, err := s.repo.SaveMessageOnce(ctx, m)
+				if err != nil || !created {
+					return err
+				}
+				return s.outbox.Add(ctx, SubjectMessageCreated, MessageCreatedEvent{ChatID: ev.ChatID, Message: &readable})
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("record notice %s: %w", m.ID, err)
+		}
+	}
+	return nil
+}
+
+// retry runs fn up to three times with a short backoff.
+func retry(base context.Context, fn func(context.Context) error) error {
+	var err error
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(base, 3*time.Second)
+		err = fn(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		time.Sleep(time.Duration(i+1) * 200 * time.Millisecond)
+	}
+	return err
+}
+
+func (s *Subscriber) handleChatMerged(base context.Context, m *nats.Msg) error {
+	info := reqctx.FromContext(base)
+	var ev ChatMergedEvent
+	if err := json.Unmarshal(m.Data, &ev); err != nil || ev.ChatID == "" {
+		if err == nil {
+			err = errors.New("chat_id missing")
+		}
+		log.Printf("invalid chat.merged event: %v request_id=%s trace_id=%s", err, info.RequestID, info.TraceID())
+		respond(m, err)
+		return err
+	}
+	ctx, cancel := context.WithTimeout(base, 30*time.Second)
+	defer cancel()
+	n, err := s.repo.MergeChats(ctx, ev.ChatID, ev.MergedIDs)
+	if err != nil {
+		log.Printf("merge chats into %s: %v request_id=%s trace_id=%s", ev.ChatID, err, info.RequestID, info.TraceID())
+	} else {
+		log.Printf("merged %d chats into %s, %d messages moved request_id=%s trace_id=%s", len(ev.MergedIDs), ev.ChatID, n, info.RequestID, info.TraceID())
+	}
+	respond(m, err)
+	return err
+}
+
+// respond answers a request-reply message with "ok" or the error text.
+func respond(m *nats.Msg, err error) {
+	if m.Reply == "" {
+		return
+	}
+	reply := "ok"
+	if err != nil {
+		reply = err.Error()
+	}
+	_ = m.Respond([]byte(reply))
 }
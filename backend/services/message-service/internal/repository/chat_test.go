@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fathima-sithara/message-service/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestApplyChat(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	tests := []struct {
+		name     string
+		chat     domain.Chat
+		response bson.D
+		// wantOlder is the comparison that lets the event replace the copy
+		wantOlder   string
+		wantApplied bool
+		wantErr     bool
+	}{
+		{
+			name:        "newer change",
+			chat:        domain.Chat{ID: "c1", Version: 4, Members: []string{"a", "b"}},
+			response:    mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			wantOlder:   "$lt",
+			wantApplied: true,
+		},
+		{
+			name:        "tombstone of the same version",
+			chat:        domain.Chat{ID: "c1", Version: 4, Deleted: true, Members: []string{}},
+			response:    mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			wantOlder:   "$lte",
+			wantApplied: true,
+		},
+		{
+			name:      "copy already newer",
+			chat:      domain.Chat{ID: "c1", Version: 2},
+			response:  mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "E11000 duplicate key error"}),
+			wantOlder: "$lt",
+		},
+		{
+			name:      "store failure",
+			chat:      domain.Chat{ID: "c1", Version: 5},
+			response:  mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 2, Message: "bad value"}),
+			wantOlder: "$lt",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			r := &MongoRepository{chatCol: mt.Coll}
+			mt.AddMockResponses(tt.response)
+
+			applied, err := r.ApplyChat(context.Background(), &tt.chat)
+			if applied != tt.wantApplied || (err != nil) != tt.wantErr {
+				mt.Fatalf("ApplyChat = (%v, %v), want (%v, error %v)", applied, err, tt.wantApplied, tt.wantErr)
+			}
+
+			ev := mt.GetStartedEvent()
+			if ev == nil || ev.CommandName != "update" {
+				mt.Fatalf("command = %v, want an update", ev)
+			}
+			stmt := ev.Command.Lookup("updates").Array().Index(0).Value().Document()
+			if !stmt.Lookup("upsert").Boolean() {
+				mt.Fatal("ApplyChat does not upsert, so the first event for a chat is lost")
+			}
+			version, err := stmt.LookupErr("q", "$or", "0", "version", tt.wantOlder)
+			if err != nil || version.AsInt64() != tt.chat.Version {
+				mt.Fatalf("filter %v does not guard on version %s %d", stmt.Lookup("q"), tt.wantOlder, tt.chat.Version)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -11,7 +13,11 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var ErrNotFound = errors.New("not found")
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrBadCursor is returned for a page cursor GetThread did not issue.
+	ErrBadCursor = errors.New("invalid cursor")
+)
 
 type MongoRepository struct {
 	db      *mongo.Database
@@ -25,22 +31,83 @@ func NewMongoRepository(db *mongo.Database) *MongoRepository {
 		msgColl: db.Collection("messages"),
 		chatCol: db.Collection("chats"),
 	}
-	_, _ = r.msgColl.Indexes().CreateOne(context.Background(), mongo.IndexModel{
-		Keys:    bson.D{{Key: "chat_id", Value: 1}, {Key: "created_at", Value: -1}},
-		Options: options.Index().SetBackground(true),
+	_, _ = r.msgColl.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "chat_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetBackground(true),
+		},
+		{
+			// thread pages: a root's replies, oldest first; main-chat messages are not indexed
+			Keys: bson.D{{Key: "thread_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName("thread_idx").
+				SetPartialFilterExpression(bson.M{"thread_id": bson.M{"$type": "string"}}),
+		},
 	})
 	return r
 }
 
-func (r *MongoRepository) InitChat(ctx context.Context, chatID string, members []string, isGroup bool) error {
+// ApplyChat stores c unless the copy already holds a newer version, and reports
+// whether it did. A tombstone also replaces a copy of the same version, since
+// the delete was the last change made to it. Replaying an event is a no-op.
+func (r *MongoRepository) ApplyChat(ctx context.Context, c *domain.Chat) (bool, error) {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	doc := bson.M{"_id": chatID, "members": members, "is_group": isGroup, "created_at": time.Now().UTC()}
-	_, err := r.chatCol.UpdateByID(ctx, chatID, bson.M{"$setOnInsert": doc}, options.Update().SetUpsert(true))
-	return err
+	older := "$lt"
+	if c.Deleted {
+		older = "$lte"
+	}
+	filter := bson.M{"_id": c.ID, "$or": bson.A{
+		bson.M{"version": bson.M{older: c.Version}},
+		bson.M{"version": bson.M{"$exists": false}},
+	}}
+	set := bson.M{
+		"name":       c.Name,
+		"is_group":   c.IsGroup,
+		"members":    c.Members,
+		"version":    c.Version,
+		"deleted":    c.Deleted,
+		"updated_at": c.UpdatedAt,
+	}
+	_, err := r.chatCol.UpdateOne(ctx, filter, bson.M{"$set": set}, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// the filter missed an existing copy, so it is already as new or newer
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteChatMessages removes every message of a deleted chat.
+func (r *MongoRepository) DeleteChatMessages(ctx context.Context, chatID string) (int64, error) {
+	res, err := r.msgColl.DeleteMany(ctx, bson.M{"chat_id": chatID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// GetChat returns the local copy of a chat.
+func (r *MongoRepository) GetChat(ctx context.Context, id string) (*domain.Chat, error) {
+	var c domain.Chat
+	if err := r.chatCol.FindOne(ctx, bson.M{"_id": id}).Decode(&c); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
 }
 
 func (r *MongoRepository) SaveMessage(ctx context.Context, m *domain.Message) error {
+	_, err := r.SaveMessageOnce(ctx, m)
+	return err
+}
+
+// SaveMessageOnce stores m unless a message with its id exists, and reports
+// whether it did.
+func (r *MongoRepository) SaveMessageOnce(ctx context.Context, m *domain.Message) (bool, error) {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
@@ -56,15 +123,19 @@ func (r *MongoRepository) SaveMessage(ctx context.Context, m *domain.Message) er
 
 	filter := bson.M{"_id": m.ID}
 	update := bson.M{"$setOnInsert": m}
-	_, err := r.msgColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
-	return err
+	res, err := r.msgColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return false, err
+	}
+	return res.UpsertedCount > 0, nil
 }
 
 func (r *MongoRepository) GetMessages(ctx context.Context, chatID string, limit int64, before time.Time) ([]*domain.Message, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	filter := bson.M{"chat_id": chatID}
+	// thread replies are listed with their thread, not in the chat
+	filter := bson.M{"chat_id": chatID, "thread_id": bson.M{"$exists": false}}
 	if !before.IsZero() {
 		filter["created_at"] = bson.M{"$lt": before}
 	}
@@ -177,7 +248,7 @@ func (r *MongoRepository) DeleteMessageForAll(ctx context.Context, messageID str
 	return m.ChatID, nil
 }
 
-func (r *MongoRepository) MarkRead(ctx context.Context, messageID, userID string) (string, error) {
+func (r *MongoRepository) MarkRead(ctx context.Context, messageID, userID string) (*domain.Message, error) {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
@@ -204,10 +275,24 @@ func (r *MongoRepository) MarkRead(ctx context.Context, messageID, userID string
 
 	var m domain.Message
 	if err := res.Decode(&m); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return m.ChatID, nil
+	return &m, nil
+}
+
+// CountUnread counts the messages in chatID after the given time that userID did
+// not send and has not deleted. Thread replies and system messages do not count,
+// as in chat-service's live counter.
+func (r *MongoRepository) CountUnread(ctx context.Context, chatID, userID string, after time.Time) (int64, error) {
+	return r.msgColl.CountDocuments(ctx, bson.M{
+		"chat_id":     chatID,
+		"thread_id":   bson.M{"$exists": false},
+		"msg_type":    bson.M{"$ne": domain.MsgTypeSystem},
+		"created_at":  bson.M{"$gt": after},
+		"sender_id":   bson.M{"$ne": userID},
+		"deleted_for": bson.M{"$ne": userID},
+	})
 }
 
 func (r *MongoRepository) AddReaction(ctx context.Context, messageID, emoji, userID string) (string, error) {
@@ -238,7 +323,10 @@ func (r *MongoRepository) GetLastMessage(ctx context.Context, chatID string) (*d
 	defer cancel()
 	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
 	var m domain.Message
-	if err := r.msgColl.FindOne(ctx, bson.M{"chat_id": chatID}, opts).Decode(&m); err != nil {
+	if err := r.msgColl.FindOne(ctx, bson.M{"chat_id": chatID, "thread_id": bson.M{"$exists": false}}, opts).Decode(&m); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 	if m.ReadBy == nil {
@@ -252,3 +340,137 @@ func (r *MongoRepository) GetLastMessage(ctx context.Context, chatID string) (*d
 	}
 	return &m, nil
 }
+
+// GetLastMessages returns the newest top-level message of each of chatIDs, keyed
+// by chat id; chats without messages are left out.
+func (r *MongoRepository) GetLastMessages(ctx context.Context, chatIDs []string) (map[string]*domain.Message, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"chat_id": bson.M{"$in": chatIDs}, "thread_id": bson.M{"$exists": false}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$chat_id", "last": bson.M{"$first": "$$ROOT"}}}},
+	}
+	cur, err := r.msgColl.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Last domain.Message `bson:"last"`
+	}
+	if err := cur.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	out := make(map[string]*domain.Message, len(rows))
+	for i := range rows {
+		m := &rows[i].Last
+		if m.ReadBy == nil {
+			m.ReadBy = []string{}
+		}
+		if m.DeletedFor == nil {
+			m.DeletedFor = []string{}
+		}
+		if m.Reactions == nil {
+			m.Reactions = map[string][]string{}
+		}
+		out[m.ChatID] = m
+	}
+	return out, nil
+}
+
+// MergeChats moves every message of the from chats into chatID and drops their
+// chat records. Running it again after a partial failure is safe.
+func (r *MongoRepository) MergeChats(ctx context.Context, chatID string, from []string) (int64, error) {
+	res, err := r.msgColl.UpdateMany(ctx,
+		bson.M{"chat_id": bson.M{"$in": from}},
+		bson.M{"$set": bson.M{"chat_id": chatID}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.chatCol.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": from}}); err != nil {
+		return res.ModifiedCount, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// AddThreadReply counts a reply by replier, sent at, on the thread's root
+// message and returns the root afterwards. The root's sender is a participant
+// from the first reply on.
+func (r *MongoRepository) AddThreadReply(ctx context.Context, rootID, rootSender, replier string, at time.Time) (*domain.Message, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	res := r.msgColl.FindOneAndUpdate(ctx,
+		bson.M{"_id": rootID},
+		bson.M{
+			"$inc":      bson.M{"reply_count": 1},
+			"$max":      bson.M{"last_reply_at": at},
+			"$addToSet": bson.M{"thread_participants": bson.M{"$each": bson.A{rootSender, replier}}},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+	var m domain.Message
+	if err := res.Decode(&m); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// threadCursor is the position of the last reply on a thread page.
+type threadCursor struct {
+	At time.Time `json:"t"`
+	ID string    `json:"id"`
+}
+
+// GetThread returns up to limit replies in the thread rooted at rootID, oldest
+// first, starting after cursor ("" for the first page). next is the cursor of
+// the following page, or "" on the last one.
+func (r *MongoRepository) GetThread(ctx context.Context, rootID, cursor string, limit int64) (replies []*domain.Message, next string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	filter := bson.M{"thread_id": rootID}
+	if cursor != "" {
+		c, err := decodeThreadCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		filter["$or"] = bson.A{
+			bson.M{"created_at": bson.M{"$gt": c.At}},
+			bson.M{"created_at": c.At, "_id": bson.M{"$gt": c.ID}},
+		}
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit + 1)
+	cur, err := r.msgColl.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	replies = []*domain.Message{}
+	if err := cur.All(ctx, &replies); err != nil {
+		return nil, "", err
+	}
+	if int64(len(replies)) > limit {
+		replies = replies[:limit]
+		last := replies[len(replies)-1]
+		next = encodeThreadCursor(threadCursor{At: last.CreatedAt, ID: last.ID})
+	}
+	return replies, next, nil
+}
+
+func encodeThreadCursor(c threadCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeThreadCursor(s string) (threadCursor, error) {
+	var c threadCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &c) != nil || c.ID == "" {
+		return c, ErrBadCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestThreadCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		c    threadCursor
+	}{
+		{"utc", threadCursor{At: time.Date(2024, 5, 1, 10, 30, 0, 123_000_000, time.UTC), ID: "m1"}},
+		{"zero time", threadCursor{ID: "m2"}},
+		{"id with url characters", threadCursor{At: time.Unix(1_700_000_000, 0).UTC(), ID: "a/b+c?d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := encodeThreadCursor(tt.c)
+			got, err := decodeThreadCursor(s)
+			if err != nil {
+				t.Fatalf("decodeThreadCursor(%q) error = %v", s, err)
+			}
+			if !got.At.Equal(tt.c.At) || got.ID != tt.c.ID {
+				t.Fatalf("decodeThreadCursor = %+v, want %+v", got, tt.c)
+			}
+		})
+	}
+}
+
+func TestDecodeThreadCursorRejects(t *testing.T) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	tests := []struct {
+		name, cursor string
+	}{
+		{"empty", ""},
+		{"not base64", "%%%"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"id":"m1"}`))},
+		{"not json", b64([]byte("m1"))},
+		{"no id", b64([]byte(`{"t":"2024-05-01T10:30:00Z"}`))},
+		{"bad time", b64([]byte(`{"t":"yesterday","id":"m1"}`))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeThreadCursor(tt.cursor); !errors.Is(err, ErrBadCursor) {
+				t.Fatalf("decodeThreadCursor(%q) error = %v, want ErrBadCursor", tt.cursor, err)
+			}
+		})
+	}
+}
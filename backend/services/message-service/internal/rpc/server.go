@@ -0,0 +1,46 @@
+// Package rpc serves message.v1 over the message store. The plumbing every
+// internal API shares lives in platform/rpc.
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fathima-sithara/message-service/internal/pb/messagev1"
+	"github.com/fathima-sithara/message-service/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// MessageServer implements message.v1.MessageService with the same store and
+// events as the REST API.
+type MessageServer struct {
+	messagev1.UnimplementedMessageServiceServer
+	svc *service.MessageService
+}
+
+func NewMessageServer(svc *service.MessageService) *MessageServer {
+	return &MessageServer{svc: svc}
+}
+
+func (s *MessageServer) CreateMessage(ctx context.Context, req *messagev1.CreateMessageRequest) (*messagev1.CreateMessageResponse, error) {
+	if req.GetChatId() == "" || req.GetSenderId() == "" || req.GetContent() == "" {
+		return nil, status.Error(codes.InvalidArgument, "chat_id, sender_id and content are required")
+	}
+	m, err := s.svc.SendMessage(ctx, req.GetChatId(), req.GetSenderId(), req.GetContent(), req.GetMsgType(), "")
+	if errors.Is(err, service.ErrBadMsgType) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &messagev1.CreateMessageResponse{Message: &messagev1.Message{
+		Id:        m.ID,
+		ChatId:    m.ChatID,
+		SenderId:  m.SenderID,
+		Content:   req.GetContent(),
+		MsgType:   m.MsgType,
+		CreatedAt: timestamppb.New(m.CreatedAt),
+	}}, nil
+}
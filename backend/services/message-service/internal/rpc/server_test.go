@@ -0,0 +1,32 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fathima-sithara/message-service/internal/pb/messagev1"
+	"github.com/fathima-sithara/message-service/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateMessageRejected(t *testing.T) {
+	// no store: every case must be refused before anything is written
+	s := NewMessageServer(service.NewMessageService(nil, nil, nil, nil))
+	tests := []struct {
+		name string
+		req  *messagev1.CreateMessageRequest
+	}{
+		{"system message", &messagev1.CreateMessageRequest{ChatId: "c1", SenderId: "u1", Content: "hi", MsgType: "system"}},
+		{"unknown type", &messagev1.CreateMessageRequest{ChatId: "c1", SenderId: "u1", Content: "hi", MsgType: "sticker"}},
+		{"no content", &messagev1.CreateMessageRequest{ChatId: "c1", SenderId: "u1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.CreateMessage(context.Background(), tt.req)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("CreateMessage err = %v, want InvalidArgument", err)
+			}
+		})
+	}
+}
@@ -7,24 +7,109 @@ import (
 	"time"
 
 	"github.com/fathima-sithara/message-service/internal/domain"
+	"github.com/fathima-sithara/message-service/internal/events"
+	"github.com/fathima-sithara/message-service/internal/pb/chatv1"
 	"github.com/fathima-sithara/message-service/internal/repository"
 	"github.com/fathima-sithara/message-service/internal/util"
+	"github.com/fathima-sithara/platform/outbox"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrNotMember is returned when the sender does not belong to the chat.
+	ErrNotMember = errors.New("not a member of this chat")
+	// ErrAdminsOnly is returned when a plain member posts to a group where only
+	// admins may send messages.
+	ErrAdminsOnly = errors.New("only admins can send messages in this group")
+	// ErrInvalidReply is returned when reply_to is not a message a thread can
+	// hang off in the same group.
+	ErrInvalidReply = errors.New("reply_to must be a message in the same group")
+	// ErrBadMsgType is returned for a msg_type users may not send, including system.
+	ErrBadMsgType = errors.New("msg_type must be text, image, video, audio or file")
+	// ErrBadCursor is returned for a thread page cursor that was not issued.
+	ErrBadCursor = repository.ErrBadCursor
 )
 
 type MessageService struct {
-	repo  *repository.MongoRepository
-	cache *redis.Client 
+	repo   *repository.MongoRepository
+	cache  *redis.Client
+	chats  chatv1.ChatServiceClient
+	outbox *outbox.Store
+}
+
+// NewMessageService wires the message store; message.created events are written
+// to ob with the message. chats may be nil, in which case CheckMember allows
+// everyone.
+func NewMessageService(r *repository.MongoRepository, c *redis.Client, chats chatv1.ChatServiceClient, ob *outbox.Store) *MessageService {
+	return &MessageService{repo: r, cache: c, chats: chats, outbox: ob}
 }
 
-func NewMessageService(r *repository.MongoRepository, c *redis.Client) *MessageService {
-	return &MessageService{repo: r, cache: c}
+// CheckMember asks chat-service whether userID may post to chatID: they must be a
+// member, and an owner or admin where only those may post. Lookup failures are
+// returned as they came back from the call so callers can map the status; an
+// unknown chat is NotFound.
+func (s *MessageService) CheckMember(ctx context.Context, chatID, userID string) error {
+	if s.chats == nil {
+		return nil
+	}
+	resp, err := s.membership(ctx, chatID, userID)
+	if err != nil {
+		return err
+	}
+	if resp.GetOnlyAdminsPost() && resp.GetRole() != "owner" && resp.GetRole() != "admin" {
+		return ErrAdminsOnly
+	}
+	return nil
+}
+
+// CheckReader asks chat-service whether userID may read chatID, which any member
+// may, including in groups where only admins post. Errors are as for CheckMember.
+func (s *MessageService) CheckReader(ctx context.Context, chatID, userID string) error {
+	if s.chats == nil {
+		return nil
+	}
+	_, err := s.membership(ctx, chatID, userID)
+	return err
 }
 
-func (s *MessageService) SendMessage(ctx context.Context, chatID, senderID, content, msgType string) (*domain.Message, error) {
+// membership looks userID up in chatID and fails with ErrNotMember for outsiders.
+func (s *MessageService) membership(ctx context.Context, chatID, userID string) (*chatv1.GetMembershipResponse, error) {
+	resp, err := s.chats.GetMembership(ctx, &chatv1.GetMembershipRequest{ChatId: chatID, UserId: userID})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.GetMember() {
+		return nil, ErrNotMember
+	}
+	return resp, nil
+}
+
+// SendMessage stores a message and publishes message.created. With replyTo set
+// the message is a reply in the thread of that message: the root message counts
+// it and the thread's participants still in the chat are told through
+// message.thread_reply. Users send only the types domain.UserMsgType allows;
+// others fail with ErrBadMsgType.
+func (s *MessageService) SendMessage(ctx context.Context, chatID, senderID, content, msgType, replyTo string) (*domain.Message, error) {
 	if chatID == "" || senderID == "" {
 		return nil, errors.New("chat_id and sender_id required")
 	}
+	msgType, ok := domain.UserMsgType(msgType)
+	if !ok {
+		return nil, ErrBadMsgType
+	}
+	var root *domain.Message
+	var members map[string]bool
+	if replyTo != "" {
+		var err error
+		if root, err = s.threadRoot(ctx, chatID, replyTo); err != nil {
+			return nil, err
+		}
+		if members, err = s.threadMembers(ctx, chatID, senderID, root); err != nil {
+			return nil, err
+		}
+	}
 	id := util.NewID()
 	enc := base64.StdEncoding.EncodeToString([]byte(content))
 
@@ -39,13 +124,138 @@ func (s *MessageService) SendMessage(ctx context.Context, chatID, senderID, cont
 		DeletedFor: []string{},
 		Reactions:  map[string][]string{},
 	}
+	if root != nil {
+		m.ReplyTo = replyTo
+		m.ThreadID = root.ID
+	}
 
-	if err := s.repo.SaveMessage(ctx, m); err != nil {
+	err := s.outbox.Tx(ctx, func(ctx context.Context) error {
+		if err := s.repo.SaveMessage(ctx, m); err != nil {
+			return err
+		}
+		readable := *m
+		readable.Content = content
+		if err := s.outbox.Add(ctx, events.SubjectMessageCreated, events.MessageCreatedEvent{ChatID: chatID, Message: &readable}); err != nil {
+			return err
+		}
+		if root == nil {
+			return nil
+		}
+		updated, err := s.repo.AddThreadReply(ctx, root.ID, root.SenderID, senderID, m.CreatedAt)
+		if err != nil {
+			return err
+		}
+		participants := make([]string, 0, len(updated.Participants))
+		for _, id := range updated.Participants {
+			if members[id] {
+				participants = append(participants, id)
+			}
+		}
+		return s.outbox.Add(ctx, events.SubjectThreadReply, events.ThreadReplyEvent{
+			ChatID:       chatID,
+			ThreadID:     root.ID,
+			Message:      &readable,
+			ReplyCount:   updated.ReplyCount,
+			Participants: participants,
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 	return m, nil
 }
 
+// threadMembers reports which of root's thread participants are still in the
+// chat, so people who left stop hearing about its replies. The sender has just
+// passed CheckMember; anyone who joins the thread meanwhile is left out of this
+// reply's event and picked up by the next.
+func (s *MessageService) threadMembers(ctx context.Context, chatID, senderID string, root *domain.Message) (map[string]bool, error) {
+	members := map[string]bool{senderID: true}
+	for _, id := range append([]string{root.SenderID}, root.Participants...) {
+		if _, seen := members[id]; seen {
+			continue
+		}
+		err := s.CheckReader(ctx, chatID, id)
+		switch {
+		case err == nil:
+			members[id] = true
+		case errors.Is(err, ErrNotMember):
+			members[id] = false
+		default:
+			return nil, err
+		}
+	}
+	return members, nil
+}
+
+// threadRoot returns the root of the thread a reply to replyTo belongs in: the
+// message itself, or its root when it is a reply already. Threads are only kept
+// in groups; a chat whose copy has not arrived yet is given the benefit of the
+// doubt.
+func (s *MessageService) threadRoot(ctx context.Context, chatID, replyTo string) (*domain.Message, error) {
+	chat, err := s.repo.GetChat(ctx, chatID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	if chat != nil && !chat.IsGroup {
+		return nil, ErrInvalidReply
+	}
+	parent, err := s.repo.GetMessageByID(ctx, replyTo)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidReply
+	}
+	if err != nil {
+		return nil, err
+	}
+	if parent.ChatID != chatID || parent.MsgType == domain.MsgTypeSystem {
+		return nil, ErrInvalidReply
+	}
+	if parent.ThreadID == "" {
+		return parent, nil
+	}
+	root, err := s.repo.GetMessageByID(ctx, parent.ThreadID)
+	if errors.Is(err, repository.ErrNotFound) {
+		// the root was deleted for everyone
+		return nil, ErrInvalidReply
+	}
+	return root, err
+}
+
+// GetThread returns a thread's root message and a page of its replies, oldest
+// first, with the cursor of the next page. Only members of the root's chat may
+// read it; see CheckReader for the errors.
+func (s *MessageService) GetThread(ctx context.Context, userID, rootID, cursor string, limit int64) (*domain.Message, []*domain.Message, string, error) {
+	root, err := s.repo.GetMessageByID(ctx, rootID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if root.ThreadID != "" {
+		// a reply has no thread of its own
+		return nil, nil, "", repository.ErrNotFound
+	}
+	if err := s.CheckReader(ctx, root.ChatID, userID); err != nil {
+		return nil, nil, "", err
+	}
+	replies, next, err := s.repo.GetThread(ctx, rootID, cursor, limit)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	decodeContent(root)
+	for _, m := range replies {
+		decodeContent(m)
+	}
+	return root, replies, next, nil
+}
+
+// decodeContent turns stored content back into what the sender wrote.
+func decodeContent(m *domain.Message) {
+	if m.Content != "" {
+		if b, err := base64.StdEncoding.DecodeString(m.Content); err == nil {
+			m.Content = string(b)
+		}
+	}
+}
+
 func (s *MessageService) ListMessages(ctx context.Context, chatID string, limit int64, before time.Time) ([]*domain.Message, error) {
 	msgs, err := s.repo.GetMessages(ctx, chatID, limit, before)
 	if err != nil {
@@ -61,8 +271,34 @@ func (s *MessageService) ListMessages(ctx context.Context, chatID string, limit
 	return msgs, nil
 }
 
+// MarkRead records that userID read the message and everything before it, and
+// returns the message's chat. Reading a thread reply leaves the chat's read
+// position alone.
 func (s *MessageService) MarkRead(ctx context.Context, messageID, userID string) (string, error) {
-	return s.repo.MarkRead(ctx, messageID, userID)
+	var chatID string
+	err := s.outbox.Tx(ctx, func(ctx context.Context) error {
+		m, err := s.repo.MarkRead(ctx, messageID, userID)
+		if err != nil {
+			return err
+		}
+		chatID = m.ChatID
+		if m.ThreadID != "" {
+			return nil
+		}
+		unread, err := s.repo.CountUnread(ctx, m.ChatID, userID, m.CreatedAt)
+		if err != nil {
+			return err
+		}
+		return s.outbox.Add(ctx, events.SubjectMessageRead, events.MessageReadEvent{
+			ChatID:    m.ChatID,
+			MessageID: m.ID,
+			UserID:    userID,
+			MessageAt: m.CreatedAt,
+			Unread:    unread,
+			ReadAt:    time.Now().UTC(),
+		})
+	})
+	return chatID, err
 }
 
 func (s *MessageService) EditMessage(ctx context.Context, messageID, userID, newContent string) (string, error) {
@@ -94,3 +330,35 @@ func (s *MessageService) GetLastMessage(ctx context.Context, chatID string) (*do
 	}
 	return m, nil
 }
+
+// GetLastMessages is GetLastMessage for many chats at once, keyed by chat id.
+// Chats userID may not read, or that do not exist, are left out like chats
+// without messages; other lookup failures are returned as for CheckReader.
+func (s *MessageService) GetLastMessages(ctx context.Context, userID string, chatIDs []string) (map[string]*domain.Message, error) {
+	readable := make([]string, 0, len(chatIDs))
+	for _, id := range chatIDs {
+		err := s.CheckReader(ctx, id, userID)
+		switch {
+		case err == nil:
+			readable = append(readable, id)
+		case errors.Is(err, ErrNotMember), status.Code(err) == codes.NotFound:
+		default:
+			return nil, err
+		}
+	}
+	if len(readable) == 0 {
+		return map[string]*domain.Message{}, nil
+	}
+	msgs, err := s.repo.GetLastMessages(ctx, readable)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range msgs {
+		if m.Content != "" {
+			if b, err := base64.StdEncoding.DecodeString(m.Content); err == nil {
+				m.Content = string(b)
+			}
+		}
+	}
+	return msgs, nil
+}
@@ -1,20 +1,35 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"github.com/fathima-sithara/notification-service/internal/config"
 	"github.com/fathima-sithara/notification-service/internal/db"
+	"github.com/fathima-sithara/notification-service/internal/events"
 	"github.com/fathima-sithara/notification-service/internal/handler"
 	"github.com/fathima-sithara/notification-service/internal/kafka"
 	"github.com/fathima-sithara/notification-service/internal/repository"
 	route "github.com/fathima-sithara/notification-service/internal/routes"
 	"github.com/fathima-sithara/notification-service/internal/service"
+	"github.com/fathima-sithara/platform/health"
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/reqctx"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/logger"
 )
 
 func main() {
 	cfg := config.Load()
+	if cfg.IdentitySecret == "" {
+		log.Fatal("IDENTITY_HMAC_SECRET is required")
+	}
+
+	shutdownTracing, err := observability.InitTracing(context.Background(), "notification-service")
+	if err != nil {
+		log.Fatal("tracing error:", err)
+	}
+	defer shutdownTracing(context.Background())
 
 	client, err := db.Connect(cfg.MongoURI)
 	if err != nil {
@@ -23,11 +38,31 @@ func main() {
 
 	dbase := client.Database(cfg.MongoDB)
 	repo := repository.NewNotificationRepo(dbase)
-	svc := service.New(repo)
+	prefs := repository.NewPreferenceRepo(dbase)
+	svc := service.New(repo, prefs)
 	h := handler.New(svc)
 
+	sub, err := events.NewSubscriber(cfg.NatsURL, svc)
+	if err != nil {
+		log.Fatal("nats error:", err)
+	}
+	if err := sub.Start(); err != nil {
+		log.Fatal("nats subscribe error:", err)
+	}
+
 	app := fiber.New()
-	route.Register(app, h)
+	app.Use(reqctx.Middleware())
+	app.Use(observability.Middleware())
+	app.Use(logger.New(logger.Config{
+		Format: "[${time}] ${status} - ${latency} ${method} ${path} request_id=${locals:request_id} trace_id=${locals:trace_id}\n",
+	}))
+	app.Get("/metrics", observability.MetricsHandler())
+
+	hc := health.New()
+	hc.Add("mongo", func(ctx context.Context) error { return client.Ping(ctx, nil) })
+	hc.Add("nats", sub.Ready)
+	hc.Register(app)
+	route.Register(app, h, cfg.IdentitySecret)
 
 	go kafka.StartConsumer(cfg.KafkaBrokers, cfg.KafkaTopic, svc)
 
@@ -11,6 +11,9 @@ type Config struct {
 	MongoDB      string
 	KafkaBrokers string
 	KafkaTopic   string
+	NatsURL      string
+	// IdentitySecret verifies identity headers signed by the api-gateway; required.
+	IdentitySecret string
 }
 
 func Load() *Config {
@@ -20,6 +23,9 @@ func Load() *Config {
 		MongoDB:      getEnv("MONGO_DB", "chatapp"),
 		KafkaBrokers: getEnv("KAFKA_BROKERS", "localhost:9092"),
 		KafkaTopic:   getEnv("KAFKA_TOPIC", "notifications"),
+		NatsURL:      getEnv("NATS_URL", "nats://localhost:4222"),
+		// no default: a guessable secret would let anyone forge identities
+		IdentitySecret: os.Getenv("IDENTITY_HMAC_SECRET"),
 	}
 }
 
@@ -0,0 +1,201 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/fathima-sithara/notification-service/internal/model"
+	"github.com/fathima-sithara/notification-service/internal/service"
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/reqctx"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SubjectChatSettingsUpdated is published by chat-service whenever a member
+// changes their settings for a chat.
+const SubjectChatSettingsUpdated = "chat.settings.updated"
+
+// settingsEvent is chat-service's payload, trimmed to what notifications need.
+type settingsEvent struct {
+	ChatID      string     `json:"chat_id"`
+	UserID      string     `json:"user_id"`
+	Version     int64      `json:"version"`
+	MutedUntil  *time.Time `json:"muted_until,omitempty"`
+	NotifyLevel string     `json:"notification_level"`
+	At          time.Time  `json:"at"`
+}
+
+// SubjectChannelDelivery is published by chat-service for every batch of a
+// channel message's subscribers.
+const SubjectChannelDelivery = "channel.message.delivery"
+
+// TypeChannelPost marks notifications about a post in a subscribed channel.
+const TypeChannelPost = "channel_post"
+
+// deliveryEvent is chat-service's channel fan-out payload, trimmed to what
+// notifications need.
+type deliveryEvent struct {
+	ChatID      string `json:"chat_id"`
+	ChannelName string `json:"channel_name"`
+	Message     struct {
+		ID        string    `json:"id"`
+		Content   string    `json:"content"`
+		CreatedAt time.Time `json:"created_at"`
+	} `json:"message"`
+	UserIDs []string `json:"user_ids"`
+}
+
+// SubjectThreadReply is published by message-service for every reply in a
+// thread.
+const SubjectThreadReply = "message.thread_reply"
+
+// TypeThreadReply marks notifications about a reply in a thread the user is in.
+const TypeThreadReply = "thread_reply"
+
+// threadReplyEvent is message-service's payload, trimmed to what notifications
+// need.
+type threadReplyEvent struct {
+	ChatID   string `json:"chat_id"`
+	ThreadID string `json:"thread_id"`
+	Message  struct {
+		ID        string    `json:"id"`
+		SenderID  string    `json:"sender_id"`
+		Content   string    `json:"content"`
+		CreatedAt time.Time `json:"created_at"`
+	} `json:"message"`
+	Participants []string `json:"participants"`
+}
+
+// Subscriber keeps users' chat preferences in step with chat-service and
+// notifies channel subscribers of new posts and thread participants of replies.
+type Subscriber struct {
+	nc  *nats.Conn
+	svc *service.NotificationService
+}
+
+func NewSubscriber(url string, svc *service.NotificationService) (*Subscriber, error) {
+	nc, err := nats.Connect(url, nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	return &Subscriber{nc: nc, svc: svc}, nil
+}
+
+// Ready reports whether the NATS connection is up, for the readiness probe.
+func (s *Subscriber) Ready(context.Context) error {
+	if s == nil || s.nc == nil || !s.nc.IsConnected() {
+		return errors.New("nats not connected")
+	}
+	return nil
+}
+
+// Start subscribes in a queue group, so each event is stored by one replica.
+func (s *Subscriber) Start() error {
+	if _, err := s.nc.QueueSubscribe(SubjectChatSettingsUpdated, "notification-service", s.consume(s.handleSettings)); err != nil {
+		return err
+	}
+	if _, err := s.nc.QueueSubscribe(SubjectChannelDelivery, "notification-service", s.consume(s.handleDelivery)); err != nil {
+		return err
+	}
+	_, err := s.nc.QueueSubscribe(SubjectThreadReply, "notification-service", s.consume(s.handleThreadReply))
+	return err
+}
+
+// consume restores the producer's correlation ids, wraps handle in a consumer span
+// and records the outcome.
+func (s *Subscriber) consume(handle func(context.Context, []byte) error) nats.MsgHandler {
+	return func(m *nats.Msg) {
+		ctx := context.Background()
+		if m.Header != nil {
+			ctx = reqctx.Extract(ctx, m.Header)
+		}
+		ctx, span := observability.StartSpan(ctx, m.Subject+" process", trace.SpanKindConsumer,
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", m.Subject),
+		)
+		err := handle(ctx, m.Data)
+		if err != nil {
+			info := reqctx.FromContext(ctx)
+			log.Printf("%s: %v request_id=%s trace_id=%s", m.Subject, err, info.RequestID, info.TraceID())
+		}
+		observability.RecordConsume("nats", m.Subject, err)
+		observability.EndSpan(span, err)
+	}
+}
+
+func (s *Subscriber) handleSettings(ctx context.Context, data []byte) error {
+	var ev settingsEvent
+	if err := json.Unmarshal(data, &ev); err != nil || ev.ChatID == "" || ev.UserID == "" {
+		return errors.New("invalid settings event")
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return s.svc.SetPreference(ctx, &model.ChatPreference{
+		UserID:     ev.UserID,
+		ChatID:     ev.ChatID,
+		MutedUntil: ev.MutedUntil,
+		Level:      ev.NotifyLevel,
+		Version:    ev.Version,
+		UpdatedAt:  ev.At,
+	})
+}
+
+// handleDelivery notifies every subscriber in the batch, subject to their
+// preference for the channel. One failed user does not hold back the rest.
+func (s *Subscriber) handleDelivery(ctx context.Context, data []byte) error {
+	var ev deliveryEvent
+	if err := json.Unmarshal(data, &ev); err != nil || ev.ChatID == "" || ev.Message.ID == "" {
+		return errors.New("invalid channel delivery event")
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	var failed error
+	for _, userID := range ev.UserIDs {
+		err := s.svc.Send(ctx, &model.Notification{
+			UserID:    userID,
+			ChatID:    ev.ChatID,
+			Title:     ev.ChannelName,
+			Message:   ev.Message.Content,
+			Type:      TypeChannelPost,
+			CreatedAt: ev.Message.CreatedAt,
+		})
+		if err != nil && !errors.Is(err, service.ErrSuppressed) {
+			failed = err
+		}
+	}
+	return failed
+}
+
+// handleThreadReply notifies the thread's participants other than the sender,
+// subject to their preference for the chat.
+func (s *Subscriber) handleThreadReply(ctx context.Context, data []byte) error {
+	var ev threadReplyEvent
+	if err := json.Unmarshal(data, &ev); err != nil || ev.ChatID == "" || ev.ThreadID == "" {
+		return errors.New("invalid thread reply event")
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var failed error
+	for _, userID := range ev.Participants {
+		if userID == ev.Message.SenderID {
+			continue
+		}
+		err := s.svc.Send(ctx, &model.Notification{
+			UserID:    userID,
+			ChatID:    ev.ChatID,
+			Title:     "New reply in a thread",
+			Message:   ev.Message.Content,
+			Type:      TypeThreadReply,
+			CreatedAt: ev.Message.CreatedAt,
+		})
+		if err != nil && !errors.Is(err, service.ErrSuppressed) {
+			failed = err
+		}
+	}
+	return failed
+}
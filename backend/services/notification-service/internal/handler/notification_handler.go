@@ -2,36 +2,56 @@ package handler
 
 import (
 	"context"
+	"errors"
 
 	"github.com/fathima-sithara/notification-service/internal/model"
 	"github.com/fathima-sithara/notification-service/internal/service"
 	"github.com/gofiber/fiber/v2"
 )
 
+// Notifier is the part of service.NotificationService the handlers use.
+type Notifier interface {
+	Send(ctx context.Context, n *model.Notification) error
+	List(ctx context.Context, userID string) ([]model.Notification, error)
+}
+
 type Handler struct {
-	svc *service.NotificationService
+	svc Notifier
 }
 
-func New(s *service.NotificationService) *Handler {
+func New(s Notifier) *Handler {
 	return &Handler{s}
 }
 
+// SendNotification stores a notification for any user; it is only served on the
+// internal API.
 func (h *Handler) SendNotification(c *fiber.Ctx) error {
 	var n model.Notification
 	if err := c.BodyParser(&n); err != nil {
 		return fiber.ErrBadRequest
 	}
+	if n.UserID == "" || n.Title == "" || n.Message == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "user_id, title and message are required")
+	}
 
-	if err := h.svc.Send(context.Background(), &n); err != nil {
+	err := h.svc.Send(c.UserContext(), &n)
+	if errors.Is(err, service.ErrSuppressed) {
+		return c.JSON(fiber.Map{"message": "suppressed"})
+	}
+	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
 
 	return c.JSON(fiber.Map{"message": "sent"})
 }
 
-func (h *Handler) GetUserNotifications(c *fiber.Ctx) error {
-	userID := c.Params("userID")
-	notifs, err := h.svc.List(context.Background(), userID)
+// GetMyNotifications lists the notifications of the user the gateway signed for.
+func (h *Handler) GetMyNotifications(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(string)
+	if userID == "" {
+		return fiber.ErrUnauthorized
+	}
+	notifs, err := h.svc.List(c.UserContext(), userID)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
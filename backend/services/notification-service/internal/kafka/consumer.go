@@ -3,11 +3,16 @@ package kafka
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 
 	"github.com/fathima-sithara/notification-service/internal/model"
 	"github.com/fathima-sithara/notification-service/internal/service"
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/reqctx"
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func StartConsumer(broker, topic string, svc *service.NotificationService) {
@@ -25,12 +30,30 @@ func StartConsumer(broker, topic string, svc *service.NotificationService) {
 				continue
 			}
 
-			var n model.Notification
-			if err := json.Unmarshal(msg.Value, &n); err != nil {
-				continue
-			}
-
-			svc.Send(context.Background(), &n)
+			ctx := ContextFromMessage(context.Background(), msg)
+			ctx, span := observability.StartSpan(ctx, msg.Topic+" process", trace.SpanKindConsumer,
+				attribute.String("messaging.system", "kafka"),
+				attribute.String("messaging.destination.name", msg.Topic),
+			)
+			err = handleMessage(ctx, svc, msg)
+			observability.RecordConsume("kafka", msg.Topic, err)
+			observability.EndSpan(span, err)
 		}
 	}()
 }
+
+func handleMessage(ctx context.Context, svc *service.NotificationService, msg kafka.Message) error {
+	info := reqctx.FromContext(ctx)
+
+	var n model.Notification
+	if err := json.Unmarshal(msg.Value, &n); err != nil {
+		log.Printf("invalid notification event: %v request_id=%s trace_id=%s", err, info.RequestID, info.TraceID())
+		return err
+	}
+
+	if err := svc.Send(ctx, &n); err != nil && !errors.Is(err, service.ErrSuppressed) {
+		log.Printf("store notification: %v request_id=%s trace_id=%s", err, info.RequestID, info.TraceID())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/fathima-sithara/platform/reqctx"
+	"github.com/segmentio/kafka-go"
+)
+
+// headerCarrier adapts kafka message headers to reqctx.Carrier.
+type headerCarrier struct {
+	headers *[]kafka.Header
+}
+
+func (h headerCarrier) Get(key string) string {
+	for _, hd := range *h.headers {
+		if hd.Key == key {
+			return string(hd.Value)
+		}
+	}
+	return ""
+}
+
+func (h headerCarrier) Set(key, value string) {
+	for i, hd := range *h.headers {
+		if hd.Key == key {
+			(*h.headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*h.headers = append(*h.headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+// ContextFromMessage returns a context carrying the correlation ids of msg.
+func ContextFromMessage(ctx context.Context, msg kafka.Message) context.Context {
+	return reqctx.Extract(ctx, headerCarrier{headers: &msg.Headers})
+}
+
+// InjectHeaders adds the correlation ids in ctx to an outgoing message.
+func InjectHeaders(ctx context.Context, msg *kafka.Message) {
+	reqctx.Inject(ctx, headerCarrier{headers: &msg.Headers})
+}
@@ -5,6 +5,7 @@ import "time"
 type Notification struct {
 	ID        string    `json:"id" bson:"_id,omitempty"`
 	UserID    string    `json:"user_id" bson:"user_id"`
+	ChatID    string    `json:"chat_id,omitempty" bson:"chat_id,omitempty"` // set for chat activity; subject to the user's chat preference
 	Title     string    `json:"title" bson:"title"`
 	Message   string    `json:"message" bson:"message"`
 	Type      string    `json:"type" bson:"type"`
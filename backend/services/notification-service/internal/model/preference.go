@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// Notification types with special handling.
+const (
+	// TypeMention is a notification about a message that mentions the user; it is
+	// the only kind a chat set to LevelMentions lets through.
+	TypeMention = "mention"
+)
+
+// Notification levels a user can choose per chat in chat-service.
+const (
+	LevelAll      = "all"
+	LevelMentions = "mentions"
+	LevelNone     = "none"
+)
+
+// ChatPreference is a user's mute and notification level for one chat, as last
+// published by chat-service.
+type ChatPreference struct {
+	ID         string     `json:"-" bson:"_id"` // chat_id:user_id
+	UserID     string     `json:"user_id" bson:"user_id"`
+	ChatID     string     `json:"chat_id" bson:"chat_id"`
+	MutedUntil *time.Time `json:"muted_until,omitempty" bson:"muted_until,omitempty"`
+	Level      string     `json:"notification_level" bson:"level"`
+	Version    int64      `json:"-" bson:"version"`
+	UpdatedAt  time.Time  `json:"updated_at" bson:"updated_at"`
+}
+
+func PreferenceID(chatID, userID string) string { return chatID + ":" + userID }
+
+// Allows reports whether n may be delivered at now. A nil preference allows all.
+func (p *ChatPreference) Allows(n *Notification, now time.Time) bool {
+	if p == nil {
+		return true
+	}
+	if p.MutedUntil != nil && p.MutedUntil.After(now) {
+		return false
+	}
+	switch p.Level {
+	case LevelNone:
+		return false
+	case LevelMentions:
+		return n.Type == TypeMention
+	}
+	return true
+}
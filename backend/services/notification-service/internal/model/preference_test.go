@@ -0,0 +1,37 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChatPreferenceAllows(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+	message := &Notification{Type: "message"}
+	mention := &Notification{Type: TypeMention}
+	tests := []struct {
+		name string
+		pref *ChatPreference
+		n    *Notification
+		want bool
+	}{
+		{"no preference", nil, message, true},
+		{"default level", &ChatPreference{}, message, true},
+		{"level all", &ChatPreference{Level: LevelAll}, message, true},
+		{"level none", &ChatPreference{Level: LevelNone}, mention, false},
+		{"mentions only lets mentions through", &ChatPreference{Level: LevelMentions}, mention, true},
+		{"mentions only blocks messages", &ChatPreference{Level: LevelMentions}, message, false},
+		{"muted", &ChatPreference{Level: LevelAll, MutedUntil: at(time.Hour)}, message, false},
+		{"mute blocks mentions too", &ChatPreference{Level: LevelMentions, MutedUntil: at(time.Hour)}, mention, false},
+		{"mute ended", &ChatPreference{Level: LevelAll, MutedUntil: at(-time.Second)}, message, true},
+		{"mute ends at now", &ChatPreference{MutedUntil: at(0)}, message, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pref.Allows(tt.n, now); got != tt.want {
+				t.Fatalf("Allows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
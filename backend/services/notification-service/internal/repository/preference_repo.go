@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/fathima-sithara/notification-service/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PreferenceRepo struct {
+	col *mongo.Collection
+}
+
+func NewPreferenceRepo(db *mongo.Database) *PreferenceRepo {
+	return &PreferenceRepo{
+		col: db.Collection("chat_preferences"),
+	}
+}
+
+// Upsert stores p unless a preference with the same or a higher version is
+// already stored, so redelivered and reordered events are harmless.
+func (r *PreferenceRepo) Upsert(ctx context.Context, p *model.ChatPreference) error {
+	p.ID = model.PreferenceID(p.ChatID, p.UserID)
+	filter := bson.M{"_id": p.ID, "version": bson.M{"$lt": p.Version}}
+	_, err := r.col.ReplaceOne(ctx, filter, p, options.Replace().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// a newer version is stored
+		return nil
+	}
+	return err
+}
+
+// Get returns the user's preference for the chat, or nil if they never set one.
+func (r *PreferenceRepo) Get(ctx context.Context, userID, chatID string) (*model.ChatPreference, error) {
+	var p model.ChatPreference
+	err := r.col.FindOne(ctx, bson.M{"_id": model.PreferenceID(chatID, userID)}).Decode(&p)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
@@ -2,12 +2,17 @@ package route
 
 import (
 	"github.com/fathima-sithara/notification-service/internal/handler"
+	"github.com/fathima-sithara/platform/identity"
 	"github.com/gofiber/fiber/v2"
 )
 
-func Register(app *fiber.App, h *handler.Handler) {
-	api := app.Group("/api/v1/notifications")
+// Register mounts the public API, which the gateway forwards with a signed
+// identity, and the internal API other services call directly. The gateway only
+// forwards /api/v1/notifications, so /internal stays unreachable from outside.
+func Register(app *fiber.App, h *handler.Handler, identitySecret string) {
+	api := app.Group("/api/v1/notifications", identity.TrustedIdentity(identitySecret))
+	api.Get("/", h.GetMyNotifications)
 
-	api.Post("/", h.SendNotification)
-	api.Get("/:userID", h.GetUserNotifications)
+	internal := app.Group("/internal/notifications")
+	internal.Post("/", h.SendNotification)
 }
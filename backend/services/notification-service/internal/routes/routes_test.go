@@ -0,0 +1,90 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fathima-sithara/notification-service/internal/handler"
+	"github.com/fathima-sithara/notification-service/internal/model"
+	"github.com/fathima-sithara/platform/identity"
+	"github.com/gofiber/fiber/v2"
+)
+
+type fakeNotifier struct {
+	sent   []*model.Notification
+	listed []string
+}
+
+func (f *fakeNotifier) Send(_ context.Context, n *model.Notification) error {
+	f.sent = append(f.sent, n)
+	return nil
+}
+
+func (f *fakeNotifier) List(_ context.Context, userID string) ([]model.Notification, error) {
+	f.listed = append(f.listed, userID)
+	return []model.Notification{{UserID: userID, Title: "hi"}}, nil
+}
+
+func TestRoutes(t *testing.T) {
+	const secret = "s3cret"
+	const body = `{"user_id":"victim","title":"t","message":"m"}`
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		signedAs   string
+		secret     string
+		wantStatus int
+		wantListed string
+		wantSent   bool
+	}{
+		{name: "own notifications", method: "GET", path: "/api/v1/notifications", signedAs: "u1", secret: secret, wantStatus: 200, wantListed: "u1"},
+		{name: "unsigned", method: "GET", path: "/api/v1/notifications", wantStatus: 401},
+		{name: "forged signature", method: "GET", path: "/api/v1/notifications", signedAs: "u1", secret: "guess", wantStatus: 401},
+		{name: "another user's path", method: "GET", path: "/api/v1/notifications/victim", signedAs: "u1", secret: secret, wantStatus: 404},
+		{name: "public send", method: "POST", path: "/api/v1/notifications", body: body, signedAs: "u1", secret: secret, wantStatus: 405},
+		{name: "internal send", method: "POST", path: "/internal/notifications", body: body, wantStatus: 200, wantSent: true},
+		{name: "internal send needs fields", method: "POST", path: "/internal/notifications", body: `{"user_id":"u1"}`, wantStatus: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeNotifier{}
+			app := fiber.New()
+			Register(app, handler.New(svc), secret)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.signedAs != "" {
+				for k, v := range identity.Sign(tt.secret, identity.Identity{UserID: tt.signedAs}, time.Now()) {
+					req.Header.Set(k, v)
+				}
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantListed != "" {
+				var got []model.Notification
+				if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+					t.Fatal(err)
+				}
+				if len(svc.listed) != 1 || svc.listed[0] != tt.wantListed || got[0].UserID != tt.wantListed {
+					t.Fatalf("listed %q, want only %q", svc.listed, tt.wantListed)
+				}
+			} else if len(svc.listed) != 0 {
+				t.Fatalf("listed %q, want nothing", svc.listed)
+			}
+			if (len(svc.sent) == 1) != tt.wantSent {
+				t.Fatalf("sent %d notifications, want sent %v", len(svc.sent), tt.wantSent)
+			}
+		})
+	}
+}
@@ -2,23 +2,46 @@ package service
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/fathima-sithara/notification-service/internal/model"
 	"github.com/fathima-sithara/notification-service/internal/repository"
 )
 
+// ErrSuppressed is returned by Send for a notification the user's chat
+// preference holds back. It is not a failure.
+var ErrSuppressed = errors.New("suppressed by chat preference")
+
 type NotificationService struct {
-	repo *repository.NotificationRepo
+	repo  *repository.NotificationRepo
+	prefs *repository.PreferenceRepo
 }
 
-func New(repo *repository.NotificationRepo) *NotificationService {
-	return &NotificationService{repo}
+func New(repo *repository.NotificationRepo, prefs *repository.PreferenceRepo) *NotificationService {
+	return &NotificationService{repo: repo, prefs: prefs}
 }
 
+// Send stores n, unless it is about a chat the user has muted or set to a
+// notification level that excludes it.
 func (s *NotificationService) Send(ctx context.Context, n *model.Notification) error {
+	if n.ChatID != "" {
+		pref, err := s.prefs.Get(ctx, n.UserID, n.ChatID)
+		if err != nil {
+			return err
+		}
+		if !pref.Allows(n, time.Now()) {
+			return ErrSuppressed
+		}
+	}
 	return s.repo.Create(ctx, n)
 }
 
 func (s *NotificationService) List(ctx context.Context, userID string) ([]model.Notification, error) {
 	return s.repo.GetUserNotifications(ctx, userID)
 }
+
+// SetPreference records a user's chat preference published by chat-service.
+func (s *NotificationService) SetPreference(ctx context.Context, p *model.ChatPreference) error {
+	return s.prefs.Upsert(ctx, p)
+}
@@ -4,21 +4,29 @@ import (
 	"context"
 	"fmt"
 	stdlog "log"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/fathima-sithara/platform/health"
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/reqctx"
+	"github.com/fathima-sithara/platform/rpc"
 	"github.com/fathima-sithara/user-service/internal/config"
 	"github.com/fathima-sithara/user-service/internal/database"
 	handlers "github.com/fathima-sithara/user-service/internal/handler"
+	"github.com/fathima-sithara/user-service/internal/middleware"
+	"github.com/fathima-sithara/user-service/internal/pb/userv1"
 	"github.com/fathima-sithara/user-service/internal/repository"
 	"github.com/fathima-sithara/user-service/internal/routes"
+	rpcsrv "github.com/fathima-sithara/user-service/internal/rpc"
 	"github.com/fathima-sithara/user-service/internal/service"
 	"github.com/fathima-sithara/user-service/internal/utils"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -35,6 +43,11 @@ func main() {
 	sugar := logger.Sugar()
 	sugar.Infof("starting user-service in %s mode", cfg.App.Env)
 
+	shutdownTracing, err := observability.InitTracing(context.Background(), "user-service")
+	if err != nil {
+		sugar.Fatalf("tracing init failed: %v", err)
+	}
+
 	mongoURI := cfg.Mongo.URI
 	if v := os.Getenv("MONGO_URI"); v != "" {
 		mongoURI = v
@@ -55,9 +68,17 @@ func main() {
 		WriteTimeout: cfg.App.WriteTimeout,
 		IdleTimeout:  cfg.App.IdleTimeout,
 	})
-	app.Use(cors.New())
+	app.Use(reqctx.Middleware())
+	app.Use(observability.Middleware())
+	app.Use(middleware.RequestLogger(logger))
 
-	routes.RegisterUserRoutes(app, h)
+	app.Get("/metrics", observability.MetricsHandler())
+
+	hc := health.New()
+	hc.Add("mongo", func(ctx context.Context) error { return client.Ping(ctx, nil) })
+	hc.Register(app)
+
+	routes.RegisterUserRoutes(app, h, cfg.Identity.Secret)
 
 	go func() {
 		addr := fmt.Sprintf(":%d", cfg.App.Port)
@@ -67,6 +88,22 @@ func main() {
 		}
 	}()
 
+	var grpcSrv *grpc.Server
+	if cfg.GRPC.Port != 0 {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+		if err != nil {
+			sugar.Fatalf("grpc listen failed: %v", err)
+		}
+		grpcSrv = rpc.NewServer()
+		userv1.RegisterUserServiceServer(grpcSrv, rpcsrv.NewUserServer(userSvc))
+		go func() {
+			sugar.Infof("grpc listening on :%d", cfg.GRPC.Port)
+			if err := grpcSrv.Serve(lis); err != nil {
+				sugar.Fatalf("grpc serve failed: %v", err)
+			}
+		}()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
@@ -78,10 +115,17 @@ func main() {
 	if err := app.ShutdownWithContext(ctx); err != nil {
 		sugar.Errorf("fiber shutdown error: %v", err)
 	}
+	if grpcSrv != nil {
+		grpcSrv.GracefulStop()
+	}
 
 	if err := client.Disconnect(ctx); err != nil {
 		sugar.Errorf("mongo disconnect error: %v", err)
 	}
 
+	if err := shutdownTracing(ctx); err != nil {
+		sugar.Errorf("tracing shutdown error: %v", err)
+	}
+
 	sugar.Info("graceful shutdown complete")
 }
@@ -41,11 +41,23 @@ type JWTConfig struct {
 	RefreshTTLDays int    `yaml:"refresh_ttl_days"`
 }
 
+type IdentityConfig struct {
+	// Secret verifies identity headers signed by the api-gateway; required.
+	Secret string `yaml:"secret"`
+}
+
+// GRPCConfig configures the internal gRPC API; port 0 leaves it off.
+type GRPCConfig struct {
+	Port int `yaml:"port"`
+}
+
 type Config struct {
-	App   AppConfig   `yaml:"app"`
-	Mongo MongoConfig `yaml:"mongo"`
-	Redis RedisConfig `yaml:"redis"`
-	JWT   JWTConfig   `yaml:"jwt"`
+	App      AppConfig      `yaml:"app"`
+	GRPC     GRPCConfig     `yaml:"grpc"`
+	Mongo    MongoConfig    `yaml:"mongo"`
+	Redis    RedisConfig    `yaml:"redis"`
+	JWT      JWTConfig      `yaml:"jwt"`
+	Identity IdentityConfig `yaml:"identity"`
 }
 
 func Load() (*Config, error) {
@@ -90,11 +102,18 @@ func overrideFromEnv(cfg *Config) {
 	if v := os.Getenv("JWT_SECRET"); v != "" {
 		cfg.JWT.HSSecret = v
 	}
+	if v := os.Getenv("IDENTITY_HMAC_SECRET"); v != "" {
+		cfg.Identity.Secret = v
+	}
 
 	if v := os.Getenv("SERVICE_PORT"); v != "" {
 		p, _ := strconv.Atoi(v)
 		cfg.App.Port = p
 	}
+	if v := os.Getenv("GRPC_PORT"); v != "" {
+		p, _ := strconv.Atoi(v)
+		cfg.GRPC.Port = p
+	}
 }
 
 func validate(cfg *Config) error {
@@ -126,6 +145,10 @@ func validate(cfg *Config) error {
 		return errors.New("jwt.algorithm must be RS256 or HS256")
 	}
 
+	if cfg.Identity.Secret == "" {
+		return errors.New("identity.secret missing (set IDENTITY_HMAC_SECRET)")
+	}
+
 	if cfg.JWT.AccessTTLMin <= 0 || cfg.JWT.RefreshTTLDays <= 0 {
 		log.Println("[WARN] Using default JWT TTL values")
 	}
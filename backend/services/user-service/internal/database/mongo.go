@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/fathima-sithara/platform/observability"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -12,7 +13,7 @@ func ConnectMongo(uri, dbName string) (*mongo.Database, *mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetMonitor(observability.MongoMonitor()))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Headers read by the api-gateway response cache: Surrogate-Key tags a cacheable
+// response and X-Cache-Purge evicts every cached response carrying a tag.
+const (
+	headerSurrogateKey = "Surrogate-Key"
+	headerCachePurge   = "X-Cache-Purge"
+)
+
+func userTag(id string) string { return "user:" + id }
+
+func cacheTags(c *fiber.Ctx, tags ...string) {
+	c.Set(headerSurrogateKey, strings.Join(tags, " "))
+}
+
+func purgeCache(c *fiber.Ctx, tags ...string) {
+	c.Set(headerCachePurge, strings.Join(tags, " "))
+}
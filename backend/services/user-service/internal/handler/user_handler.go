@@ -1,15 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
+	"github.com/fathima-sithara/platform/reqctx"
+	models "github.com/fathima-sithara/user-service/internal/model"
 	"github.com/fathima-sithara/user-service/internal/repository"
 	"github.com/fathima-sithara/user-service/internal/service"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 )
 
+// maxUsers caps the ids one GetUsers call may ask for.
+const maxUsers = 100
+
 type Handler struct {
 	svc *service.UserService
 	log *zap.Logger
@@ -19,6 +27,11 @@ func NewHandler(svc *service.UserService, log *zap.Logger) *Handler {
 	return &Handler{svc: svc, log: log}
 }
 
+// logger returns the handler logger tagged with the request's correlation ids.
+func (h *Handler) logger(c *fiber.Ctx) *zap.Logger {
+	return h.log.With(reqctx.Fields(c.UserContext())...)
+}
+
 type updateProfileReq struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
@@ -36,14 +49,15 @@ func (h *Handler) GetProfile(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthenticated"})
 	}
 	uid := userID.(string)
-	u, err := h.svc.GetProfile(c.Context(), uid)
+	u, err := h.svc.GetProfile(c.UserContext(), uid)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
 		}
-		h.log.Error("get profile failed", zap.Error(err))
+		h.logger(c).Error("get profile failed", zap.Error(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "internal error"})
 	}
+	cacheTags(c, userTag(uid))
 	return c.JSON(u)
 }
 
@@ -60,17 +74,18 @@ func (h *Handler) UpdateProfile(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
 	}
 
-	u, err := h.svc.UpdateProfile(c.Context(), uid, req.Username, req.Email, req.Phone)
+	u, err := h.svc.UpdateProfile(c.UserContext(), uid, req.Username, req.Email, req.Phone)
 	if err != nil {
 
 		if strings.Contains(err.Error(), "exists") {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
 
-		h.log.Error("update profile failed", zap.Error(err))
+		h.logger(c).Error("update profile failed", zap.Error(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update profile"})
 	}
 
+	purgeCache(c, userTag(uid))
 	return c.JSON(u)
 }
 
@@ -83,30 +98,69 @@ func (h *Handler) ChangePassword(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
 	}
-	if err := h.svc.ChangePassword(c.Context(), token, req.OldPassword, req.NewPassword); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	ctx, cancel := context.WithTimeout(c.UserContext(), 5*time.Second)
+	defer cancel()
+	err := h.svc.ChangePassword(ctx, token, req.OldPassword, req.NewPassword)
+	var authErr *service.AuthError
+	switch {
+	case err == nil:
+		return c.JSON(fiber.Map{"message": "password changed"})
+	case errors.As(err, &authErr):
+		return c.Status(authErr.Status).JSON(fiber.Map{"error": authErr.Message})
+	case errors.Is(err, service.ErrAuthUnavailable):
+		h.logger(c).Warn("change password failed", zap.Error(err))
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "auth-service unavailable"})
+	case errors.Is(err, service.ErrAuthTimeout):
+		h.logger(c).Warn("change password failed", zap.Error(err))
+		return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{"error": "auth-service timed out"})
+	default:
+		h.logger(c).Error("change password failed", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to change password"})
 	}
-	return c.JSON(fiber.Map{"message": "password changed"})
 }
 
 func (h *Handler) GetUserByID(c *fiber.Ctx) error {
 	id := c.Params("id")
-	u, err := h.svc.GetByIDAdmin(c.Context(), id)
+	u, err := h.svc.GetByIDAdmin(c.UserContext(), id)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
 		}
-		h.log.Error("get user by id failed", zap.Error(err))
+		h.logger(c).Error("get user by id failed", zap.Error(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "internal error"})
 	}
+	cacheTags(c, userTag(id))
 	return c.JSON(u)
 }
 
+// GetUsers serves GET /api/v1/users?ids=a,b: the live users among ids, keyed by
+// id. Unknown ids are left out.
+func (h *Handler) GetUsers(c *fiber.Ctx) error {
+	ids := strings.Split(c.Query("ids"), ",")
+	if c.Query("ids") == "" || len(ids) > maxUsers {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("ids must list 1 to %d users", maxUsers)})
+	}
+	users, err := h.svc.GetProfiles(c.UserContext(), ids)
+	if err != nil {
+		h.logger(c).Error("get users failed", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "internal error"})
+	}
+	out := make(map[string]*models.User, len(users))
+	tags := make([]string, 0, len(users))
+	for _, u := range users {
+		out[u.ID.Hex()] = u
+		tags = append(tags, userTag(u.ID.Hex()))
+	}
+	cacheTags(c, tags...)
+	return c.JSON(fiber.Map{"data": out})
+}
+
 func (h *Handler) DeleteUser(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if err := h.svc.DeleteUser(c.Context(), id); err != nil {
-		h.log.Error("delete user failed", zap.Error(err))
+	if err := h.svc.DeleteUser(c.UserContext(), id); err != nil {
+		h.logger(c).Error("delete user failed", zap.Error(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete user"})
 	}
+	purgeCache(c, userTag(id))
 	return c.JSON(fiber.Map{"message": "user deleted"})
 }
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fathima-sithara/user-service/internal/service"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name string
+		// authStatus and authBody are what the auth-service stub answers
+		authStatus int
+		authBody   string
+		down       bool
+		wantStatus int
+		wantError  string
+	}{
+		{name: "changed", authStatus: 200, wantStatus: 200},
+		{name: "wrong old password", authStatus: 401, authBody: `{"error":"invalid credentials"}`, wantStatus: 401, wantError: "invalid credentials"},
+		{name: "refusal without a body", authStatus: 404, wantStatus: 404, wantError: "Not Found"},
+		{name: "auth-service overloaded", authStatus: 503, wantStatus: 503, wantError: "auth-service unavailable"},
+		{name: "auth-service timed out", authStatus: 504, wantStatus: 504, wantError: "auth-service timed out"},
+		{name: "auth-service failed", authStatus: 500, wantStatus: 500, wantError: "failed to change password"},
+		{name: "auth-service unreachable", down: true, wantStatus: 503, wantError: "auth-service unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/auth/change-password" || r.Header.Get("Authorization") != "Bearer tok" {
+					t.Errorf("auth-service got %s with Authorization %q", r.URL.Path, r.Header.Get("Authorization"))
+				}
+				w.WriteHeader(tt.authStatus)
+				_, _ = w.Write([]byte(tt.authBody))
+			}))
+			if tt.down {
+				auth.Close()
+			} else {
+				defer auth.Close()
+			}
+
+			h := NewHandler(service.NewUserService(nil, auth.URL, zap.NewNop()), zap.NewNop())
+			app := fiber.New()
+			app.Put("/change-password", h.ChangePassword)
+			req := httptest.NewRequest("PUT", "/change-password", strings.NewReader(`{"old_password":"a","new_password":"b"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer tok")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			var body struct {
+				Error string `json:"error"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&body)
+			if body.Error != tt.wantError {
+				t.Fatalf("error = %q, want %q", body.Error, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/fathima-sithara/platform/reqctx"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// RequestLogger logs every request with its correlation ids.
+func RequestLogger(logger *zap.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		fields := append(reqctx.Fields(c.UserContext()),
+			zap.String("method", c.Method()),
+			zap.String("path", c.Path()),
+			zap.String("ip", c.IP()),
+			zap.Int("status", c.Response().StatusCode()),
+			zap.Duration("latency", time.Since(start)),
+		)
+		if err != nil {
+			logger.Error("HTTP Request Error", append(fields, zap.Error(err))...)
+			return err
+		}
+		logger.Info("HTTP Request", fields...)
+		return nil
+	}
+}
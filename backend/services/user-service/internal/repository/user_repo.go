@@ -19,6 +19,7 @@ var ErrDuplicateKey = errors.New("duplicate key error")
 type UserRepository interface {
 	GetByID(ctx context.Context, id string) (*models.User, error)
 	GetByIDAdmin(ctx context.Context, id string) (*models.User, error)
+	GetByIDs(ctx context.Context, ids []string) ([]*models.User, error)
 	Update(ctx context.Context, u *models.User) (*models.User, error)
 	SoftDelete(ctx context.Context, id string) error
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
@@ -92,6 +93,32 @@ func (r *mongoUserRepo) GetByIDAdmin(ctx context.Context, id string) (*models.Us
 	return &u, nil
 }
 
+// GetByIDs returns the live users among ids; malformed and unknown ids are skipped.
+func (r *mongoUserRepo) GetByIDs(ctx context.Context, ids []string) ([]*models.User, error) {
+	objIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if objID, err := primitive.ObjectIDFromHex(id); err == nil {
+			objIDs = append(objIDs, objID)
+		}
+	}
+	if len(objIDs) == 0 {
+		return nil, nil
+	}
+
+	cur, err := r.col.Find(ctx, bson.M{
+		"_id":        bson.M{"$in": objIDs},
+		"deleted_at": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var users []*models.User
+	if err := cur.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (r *mongoUserRepo) Update(ctx context.Context, u *models.User) (*models.User, error) {
 	if u.ID.IsZero() {
 		return nil, errors.New("invalid user ID")
@@ -1,18 +1,22 @@
 package routes
 
 import (
+	"github.com/fathima-sithara/platform/identity"
 	handlers "github.com/fathima-sithara/user-service/internal/handler"
-	"github.com/fathima-sithara/user-service/internal/middleware"
 	"github.com/gofiber/fiber/v2"
 )
 
-func RegisterUserRoutes(app *fiber.App, h *handlers.Handler) {
+func RegisterUserRoutes(app *fiber.App, h *handlers.Handler, identitySecret string) {
 	api := app.Group("/api/v1/users")
 
-	api.Get("/me", middleware.JWT(), h.GetProfile)
-	api.Put("/me", middleware.JWT(), h.UpdateProfile)
-	api.Put("/change-password", middleware.JWT(), h.ChangePassword)
+	// every route trusts the identity the gateway signed
+	auth := identity.TrustedIdentity(identitySecret)
 
-	api.Get("/:id", middleware.JWT(), h.GetUserByID)
-	api.Delete("/:id", middleware.JWT(), h.DeleteUser)
+	api.Get("/me", auth, h.GetProfile)
+	api.Put("/me", auth, h.UpdateProfile)
+	api.Put("/change-password", auth, h.ChangePassword)
+
+	api.Get("/", auth, h.GetUsers)
+	api.Get("/:id", auth, h.GetUserByID)
+	api.Delete("/:id", auth, h.DeleteUser)
 }
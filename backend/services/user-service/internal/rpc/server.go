@@ -0,0 +1,60 @@
+// Package rpc serves user.v1 over the user store. The plumbing every internal
+// API shares lives in platform/rpc.
+package rpc
+
+import (
+	"context"
+
+	models "github.com/fathima-sithara/user-service/internal/model"
+	"github.com/fathima-sithara/user-service/internal/pb/userv1"
+	"github.com/fathima-sithara/user-service/internal/repository"
+	"github.com/fathima-sithara/user-service/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxBatch caps BatchGetProfiles so one call cannot ask for the whole collection.
+const maxBatch = 500
+
+// UserServer implements user.v1.UserService over the user store.
+type UserServer struct {
+	userv1.UnimplementedUserServiceServer
+	svc *service.UserService
+}
+
+func NewUserServer(svc *service.UserService) *UserServer {
+	return &UserServer{svc: svc}
+}
+
+func (s *UserServer) GetProfile(ctx context.Context, req *userv1.GetProfileRequest) (*userv1.GetProfileResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	users, err := s.svc.GetProfiles(ctx, []string{req.GetUserId()})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, status.Error(codes.NotFound, repository.ErrUserNotFound.Error())
+	}
+	return &userv1.GetProfileResponse{Profile: toProfile(users[0])}, nil
+}
+
+func (s *UserServer) BatchGetProfiles(ctx context.Context, req *userv1.BatchGetProfilesRequest) (*userv1.BatchGetProfilesResponse, error) {
+	if len(req.GetUserIds()) > maxBatch {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d user_ids per call", maxBatch)
+	}
+	users, err := s.svc.GetProfiles(ctx, req.GetUserIds())
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]*userv1.Profile, len(users))
+	for _, u := range users {
+		out[u.ID.Hex()] = toProfile(u)
+	}
+	return &userv1.BatchGetProfilesResponse{Profiles: out}, nil
+}
+
+func toProfile(u *models.User) *userv1.Profile {
+	return &userv1.Profile{Id: u.ID.Hex(), Username: u.Username, Email: u.Email, Phone: u.Phone}
+}
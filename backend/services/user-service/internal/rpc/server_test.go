@@ -0,0 +1,117 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	platformrpc "github.com/fathima-sithara/platform/rpc"
+	models "github.com/fathima-sithara/user-service/internal/model"
+	"github.com/fathima-sithara/user-service/internal/pb/userv1"
+	"github.com/fathima-sithara/user-service/internal/repository"
+	"github.com/fathima-sithara/user-service/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeRepo serves GetByIDs from users, or fails every call with err.
+type fakeRepo struct {
+	repository.UserRepository
+	users map[string]*models.User
+	err   error
+}
+
+func (f fakeRepo) GetByIDs(_ context.Context, ids []string) ([]*models.User, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var out []*models.User
+	for _, id := range ids {
+		if u, ok := f.users[id]; ok {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+// dialUserServer serves repo over gRPC on a loopback port, with the interceptors
+// the service runs in production, and returns a client for it.
+func dialUserServer(t *testing.T, repo repository.UserRepository) userv1.UserServiceClient {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := platformrpc.NewServer()
+	userv1.RegisterUserServiceServer(srv, NewUserServer(service.NewUserService(repo, "", zap.NewNop())))
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := platformrpc.Dial(lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return userv1.NewUserServiceClient(conn)
+}
+
+func TestUserServer(t *testing.T) {
+	id := primitive.NewObjectID()
+	alice := &models.User{ID: id, Username: "alice", Email: "alice@example.com"}
+	client := dialUserServer(t, fakeRepo{users: map[string]*models.User{id.Hex(): alice}})
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		call     func() (any, error)
+		wantCode codes.Code
+	}{
+		{name: "profile", call: func() (any, error) {
+			return client.GetProfile(ctx, &userv1.GetProfileRequest{UserId: id.Hex()})
+		}, wantCode: codes.OK},
+		{name: "profile without id", call: func() (any, error) {
+			return client.GetProfile(ctx, &userv1.GetProfileRequest{})
+		}, wantCode: codes.InvalidArgument},
+		{name: "unknown profile", call: func() (any, error) {
+			return client.GetProfile(ctx, &userv1.GetProfileRequest{UserId: primitive.NewObjectID().Hex()})
+		}, wantCode: codes.NotFound},
+		{name: "batch", call: func() (any, error) {
+			return client.BatchGetProfiles(ctx, &userv1.BatchGetProfilesRequest{UserIds: []string{id.Hex(), "gone"}})
+		}, wantCode: codes.OK},
+		{name: "batch too large", call: func() (any, error) {
+			return client.BatchGetProfiles(ctx, &userv1.BatchGetProfilesRequest{UserIds: make([]string, maxBatch+1)})
+		}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.call()
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %v (%v), want %v", code, err, tt.wantCode)
+			}
+			switch r := resp.(type) {
+			case *userv1.GetProfileResponse:
+				if err == nil && (r.GetProfile().GetId() != id.Hex() || r.GetProfile().GetUsername() != "alice") {
+					t.Fatalf("profile = %v", r.GetProfile())
+				}
+			case *userv1.BatchGetProfilesResponse:
+				if err == nil && (len(r.GetProfiles()) != 1 || r.GetProfiles()[id.Hex()].GetEmail() != "alice@example.com") {
+					t.Fatalf("profiles = %v, want only alice keyed by id", r.GetProfiles())
+				}
+			}
+		})
+	}
+}
+
+func TestUserServerStoreFailure(t *testing.T) {
+	client := dialUserServer(t, fakeRepo{err: errors.New("mongo down")})
+	_, err := client.GetProfile(context.Background(), &userv1.GetProfileRequest{UserId: "u1"})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("code = %v, want Internal for a plain store error", status.Code(err))
+	}
+	if got := platformrpc.HTTPStatus(err); got != 500 {
+		t.Fatalf("HTTPStatus = %d, want 500", got)
+	}
+}
@@ -1,14 +1,17 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/fathima-sithara/platform/reqctx"
 	models "github.com/fathima-sithara/user-service/internal/model"
 	"github.com/fathima-sithara/user-service/internal/repository"
 	"go.uber.org/zap"
@@ -17,6 +20,24 @@ import (
 var ErrNotFound = errors.New("not found")
 var ErrInvalidCredentials = errors.New("invalid credentials")
 
+// ErrAuthUnavailable and ErrAuthTimeout report an auth-service that could not
+// be reached or did not answer in time.
+var (
+	ErrAuthUnavailable = errors.New("auth-service unavailable")
+	ErrAuthTimeout     = errors.New("auth-service timed out")
+)
+
+// AuthError is auth-service turning a request down with a 4xx Status, which
+// callers pass on to the client together with Message.
+type AuthError struct {
+	Status  int
+	Message string
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("auth-service: %d %s", e.Status, e.Message)
+}
+
 type UserService struct {
 	repo       repository.UserRepository
 	authSvcURL string
@@ -60,29 +81,62 @@ func (s *UserService) UpdateProfile(ctx context.Context, userID string, username
 	return s.repo.Update(ctx, u)
 }
 
+// changePasswordRequest is auth-service's ChangePasswordRequest body.
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+// ChangePassword asks auth-service to change the password of the token's user,
+// within ctx's deadline. Refusals come back as *AuthError; an unreachable or slow
+// auth-service as ErrAuthUnavailable or ErrAuthTimeout.
 func (s *UserService) ChangePassword(ctx context.Context, token, oldPass, newPass string) error {
-	body, _ := json.Marshal(map[string]string{
-		"old_password": newPass,
-		"new_password": newPass,
-	})
-	req, err := http.NewRequestWithContext(ctx, "POST", s.authSvcURL+"/api/v1/auth/change-password", strings.NewReader(string(body)))
+	body, err := json.Marshal(changePasswordRequest{OldPassword: oldPass, NewPassword: newPass})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", s.authSvcURL+"/api/v1/auth/change-password", bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", token)
+	reqctx.Inject(ctx, req.Header)
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return err
+		var ne net.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout()) {
+			return fmt.Errorf("%w: %v", ErrAuthTimeout, err)
+		}
+		return fmt.Errorf("%w: %v", ErrAuthUnavailable, err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("auth-service rejected change-password")
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		var e struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		if e.Error == "" {
+			e.Error = http.StatusText(resp.StatusCode)
+		}
+		return &AuthError{Status: resp.StatusCode, Message: e.Error}
+	case resp.StatusCode == http.StatusServiceUnavailable:
+		return ErrAuthUnavailable
+	case resp.StatusCode == http.StatusGatewayTimeout:
+		return ErrAuthTimeout
+	default:
+		return fmt.Errorf("auth-service change-password: status %d", resp.StatusCode)
 	}
-	return nil
+}
+
+func (s *UserService) GetProfiles(ctx context.Context, ids []string) ([]*models.User, error) {
+	return s.repo.GetByIDs(ctx, ids)
 }
 
 func (s *UserService) GetByIDAdmin(ctx context.Context, id string) (*models.User, error) {
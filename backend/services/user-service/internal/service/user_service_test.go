@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestChangePasswordDeadline(t *testing.T) {
+	release := make(chan struct{})
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer auth.Close()
+	defer close(release)
+
+	svc := NewUserService(nil, auth.URL, zap.NewNop())
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := svc.ChangePassword(ctx, "Bearer tok", "a", "b"); !errors.Is(err, ErrAuthTimeout) {
+		t.Fatalf("ChangePassword err = %v, want ErrAuthTimeout", err)
+	}
+}
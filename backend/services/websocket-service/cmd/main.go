@@ -8,6 +8,8 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/fathima-sithara/platform/health"
+	"github.com/fathima-sithara/platform/observability"
 	"github.com/fathima-sithara/websocket-service/internal/api"
 	"github.com/fathima-sithara/websocket-service/internal/auth"
 	"github.com/fathima-sithara/websocket-service/internal/config"
@@ -21,6 +23,11 @@ func main() {
 		log.Fatalf("config load: %v", err)
 	}
 
+	shutdownTracing, err := observability.InitTracing(context.Background(), "websocket-service")
+	if err != nil {
+		log.Fatalf("tracing init: %v", err)
+	}
+
 	var jv *auth.JWTValidator
 	if cfg.JWT.Algorithm == "RS256" {
 		jv, err = auth.NewJWTValidatorRS256(cfg.JWT.PublicKeyPath)
@@ -35,6 +42,9 @@ func main() {
 	wsSrv := ws.NewServer(jv)
 	app := api.NewServer(cfg, wsSrv, st, jv)
 
+	// no external dependencies; readiness only reflects that the process is serving
+	health.New().Register(app)
+
 	errs := make(chan error, 1)
 	go func() {
 		addr := ":" + cfg.App.PortString()
@@ -56,6 +66,8 @@ func main() {
 	if err := app.Shutdown(); err != nil {
 		log.Printf("fiber shutdown err: %v", err)
 	}
-	_ = shutdownCtx
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("tracing shutdown err: %v", err)
+	}
 	log.Println("shutting down")
 }
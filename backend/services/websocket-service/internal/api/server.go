@@ -1,6 +1,8 @@
 package api
 
 import (
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/reqctx"
 	"github.com/fathima-sithara/websocket-service/internal/auth"
 	"github.com/fathima-sithara/websocket-service/internal/config"
 	"github.com/fathima-sithara/websocket-service/internal/store"
@@ -20,9 +22,15 @@ type Server struct {
 
 func NewServer(cfg *config.Config, wsrv *ws.Server, st store.Store, jv *auth.JWTValidator) *fiber.App {
 	app := fiber.New()
-	app.Use(logger.New())
+	app.Use(reqctx.Middleware())
+	app.Use(observability.Middleware())
+	app.Use(logger.New(logger.Config{
+		Format: "[${time}] ${status} - ${latency} ${method} ${path} request_id=${locals:request_id} trace_id=${locals:trace_id}\n",
+	}))
 	s := &Server{app: app, wsrv: wsrv, store: st, jv: jv, cfg: cfg}
 
+	app.Get("/metrics", observability.MetricsHandler())
+
 	api := app.Group("/v1")
 
 	api.Get("/health", func(c *fiber.Ctx) error { return c.JSON(fiber.Map{"status": "ok"}) })
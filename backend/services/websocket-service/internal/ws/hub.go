@@ -3,6 +3,8 @@ package ws
 import (
 	"sync"
 	"time"
+
+	"github.com/fathima-sithara/platform/observability"
 )
 
 type Hub struct {
@@ -22,18 +24,26 @@ func (h *Hub) Register(room string, c *Connection) {
 	if h.rooms[room] == nil {
 		h.rooms[room] = make(map[*Connection]bool)
 	}
-	h.rooms[room][c] = true
+	if !h.rooms[room][c] {
+		h.rooms[room][c] = true
+		observability.WSConnected()
+	}
+	observability.SetWSRooms(len(h.rooms))
 }
 
 func (h *Hub) Unregister(room string, c *Connection) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	if conns, ok := h.rooms[room]; ok {
-		delete(conns, c)
+		if conns[c] {
+			delete(conns, c)
+			observability.WSDisconnected()
+		}
 		if len(conns) == 0 {
 			delete(h.rooms, room)
 		}
 	}
+	observability.SetWSRooms(len(h.rooms))
 }
 
 func (h *Hub) Broadcast(room string, msg interface{}) {
@@ -47,6 +57,7 @@ func (h *Hub) Broadcast(room string, msg interface{}) {
 		select {
 		case c.send <- msg:
 		case <-time.After(200 * time.Millisecond):
+			observability.WSDropped()
 			h.Unregister(room, c)
 			close(c.send)
 		}
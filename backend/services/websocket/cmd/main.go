@@ -8,9 +8,10 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/fathima-sithara/platform/health"
+	"github.com/fathima-sithara/platform/observability"
 	"github.com/fathima-sithara/websocket/internal/auth"
 	"github.com/fathima-sithara/websocket/internal/config"
-	metrics "github.com/fathima-sithara/websocket/internal/metric"
 	redisclient "github.com/fathima-sithara/websocket/internal/redis"
 	"github.com/fathima-sithara/websocket/internal/ws"
 )
@@ -18,8 +19,9 @@ import (
 func main() {
 	cfg := config.Load()
 
-	if cfg.EnablePrometheus {
-		metrics.Init()
+	shutdownTracing, err := observability.InitTracing(context.Background(), "websocket")
+	if err != nil {
+		log.Fatalf("failed to init tracing: %v", err)
 	}
 
 	redisclient.Init(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
@@ -34,6 +36,10 @@ func main() {
 
 	srv := ws.NewServer(hub, jv, cfg)
 
+	hc := health.New()
+	hc.Add("redis", func(ctx context.Context) error { return redisclient.Client().Ping(ctx).Err() })
+	hc.Register(srv)
+
 	errChan := make(chan error, 1)
 	go func() {
 		addr := ":" + cfg.PortString()
@@ -60,5 +66,9 @@ func main() {
 
 	_ = redisclient.Close()
 
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("error shutting down tracing: %v", err)
+	}
+
 	log.Println("shutdown complete")
 }
@@ -1,7 +0,0 @@
-package metric
-
-import "fmt"
-
-func Init() {
-	fmt.Println("metrics init (stub) - integrate prometheus client as needed")
-}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/fathima-sithara/platform/observability"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -25,6 +26,7 @@ func Init(addr, password string, db int) {
 		WriteTimeout:    5 * time.Second,
 		ConnMaxIdleTime: 5 * time.Minute,
 	})
+	observability.InstrumentRedis(rdb)
 
 	if err := rdb.Ping(context.Background()).Err(); err != nil {
 		fmt.Printf("warning: redis ping failed: %v\n", err)
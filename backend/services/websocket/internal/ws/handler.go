@@ -1,16 +1,29 @@
 package ws
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/fathima-sithara/platform/observability"
+	"github.com/fathima-sithara/platform/reqctx"
 	"github.com/fathima-sithara/websocket/internal/auth"
 	"github.com/fathima-sithara/websocket/internal/config"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/websocket/v2"
 )
 
+// maxPresences caps the users one batch presence call may ask about.
+const maxPresences = 100
+
 func NewServer(hub *Hub, jv *auth.JWTValidator, cfg *config.Config) *fiber.App {
 	app := fiber.New()
+	app.Use(reqctx.Middleware())
+	app.Use(observability.Middleware())
+
+	if cfg.EnablePrometheus {
+		app.Get("/metrics", observability.MetricsHandler())
+	}
 
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"status": "ok"})
@@ -47,6 +60,14 @@ func NewServer(hub *Hub, jv *auth.JWTValidator, cfg *config.Config) *fiber.App {
 		client.readPump()
 	}))
 
+	app.Get("/presence", func(c *fiber.Ctx) error {
+		uids := strings.Split(c.Query("user_ids"), ",")
+		if c.Query("user_ids") == "" || len(uids) > maxPresences {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("user_ids must list 1 to %d users", maxPresences)})
+		}
+		return c.JSON(fiber.Map{"online": hub.CheckPresences(uids)})
+	})
+
 	app.Get("/presence/:user_id", func(c *fiber.Ctx) error {
 		uid := c.Params("user_id")
 		if uid == "" {
@@ -6,6 +6,7 @@ import (
 	"log"
 	"sync"
 
+	"github.com/fathima-sithara/platform/observability"
 	"github.com/fathima-sithara/websocket/internal/config"
 	"github.com/redis/go-redis/v9"
 )
@@ -85,7 +86,11 @@ func (h *Hub) Register(c *Client) {
 		if h.rooms[c.chat] == nil {
 			h.rooms[c.chat] = make(map[*Client]bool)
 		}
-		h.rooms[c.chat][c] = true
+		if !h.rooms[c.chat][c] {
+			h.rooms[c.chat][c] = true
+			observability.WSConnected()
+		}
+		observability.SetWSRooms(len(h.rooms))
 	}
 	_ = h.rdb.Set(context.Background(), "presence:"+c.uid, "online", 60).Err()
 }
@@ -98,11 +103,15 @@ func (h *Hub) Unregister(chatID string, c *Client) {
 	}
 	if chatID != "" {
 		if conns, ok := h.rooms[chatID]; ok {
-			delete(conns, c)
+			if conns[c] {
+				delete(conns, c)
+				observability.WSDisconnected()
+			}
 			if len(conns) == 0 {
 				delete(h.rooms, chatID)
 			}
 		}
+		observability.SetWSRooms(len(h.rooms))
 	}
 	_ = h.rdb.Del(context.Background(), "presence:"+c.uid).Err()
 }
@@ -119,6 +128,7 @@ func (h *Hub) BroadcastLocal(chatID string, env *Envelope) {
 		select {
 		case c.send <- b:
 		default:
+			observability.WSDropped()
 			h.Unregister(chatID, c)
 			c.close()
 		}
@@ -143,6 +153,24 @@ func (h *Hub) CheckPresence(uid string) bool {
 	return true
 }
 
+// CheckPresences is CheckPresence for many users in one round trip.
+func (h *Hub) CheckPresences(uids []string) map[string]bool {
+	out := make(map[string]bool, len(uids))
+	keys := make([]string, len(uids))
+	for i, uid := range uids {
+		keys[i] = "presence:" + uid
+		out[uid] = false
+	}
+	vals, err := h.rdb.MGet(context.Background(), keys...).Result()
+	if err != nil {
+		return out
+	}
+	for i, v := range vals {
+		out[uids[i]] = v == "online"
+	}
+	return out
+}
+
 func (h *Hub) Shutdown() {
 	h.cancel()
 	close(h.localBroadcast)